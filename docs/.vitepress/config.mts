@@ -66,7 +66,9 @@ export default defineConfig({
             { text: "OAuth Setup", link: "/config/oauth-setup" },
             { text: "RBAC Configuration", link: "/config/rbac-config" },
             { text: "Prometheus Setup", link: "/config/prometheus-setup" },
+            { text: "Cost Allocation", link: "/config/cost-allocation" },
             { text: "Managed K8s Auth", link: "/config/managed-k8s-auth" },
+            { text: "Declarative Config", link: "/config/declarative-config" },
             { text: "Environment Variables", link: "/config/env" },
             { text: "Chart Values", link: "/config/chart-values" },
           ],
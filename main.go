@@ -20,6 +20,7 @@ import (
 	"github.com/pixelvide/kube-sentinel/pkg/auth"
 	"github.com/pixelvide/kube-sentinel/pkg/cluster"
 	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/gitops"
 	"github.com/pixelvide/kube-sentinel/pkg/handlers"
 	"github.com/pixelvide/kube-sentinel/pkg/handlers/resources"
 	"github.com/pixelvide/kube-sentinel/pkg/mcp"
@@ -109,6 +110,10 @@ func setupAPIRouter(r *gin.RouterGroup, cm *cluster.ClusterManager, authHandler
 	adminAPI.Use(authHandler.RequireAuth(), authHandler.RequireAdmin())
 	{
 		adminAPI.GET("/audit-logs", handlers.ListAuditLogs)
+		adminAPI.GET("/config/status", gitops.GetStatus)
+		adminAPI.POST("/config/reconcile", gitops.Reconcile)
+		adminAPI.GET("/config/export", handlers.ExportConfig)
+		adminAPI.POST("/config/import", handlers.ImportConfig)
 		oauthProviderAPI := adminAPI.Group("/oauth-providers")
 		{
 			oauthProviderAPI.GET("/", authHandler.ListOAuthProviders)
@@ -132,6 +137,14 @@ func setupAPIRouter(r *gin.RouterGroup, cm *cluster.ClusterManager, authHandler
 			clusterAPI.DELETE("/:id/knowledge/:knn_id", handlers.DeleteKnowledge)
 		}
 
+		clusterGroupAPI := adminAPI.Group("/cluster-groups")
+		{
+			clusterGroupAPI.GET("/", handlers.ListClusterGroups)
+			clusterGroupAPI.POST("/", handlers.CreateClusterGroup)
+			clusterGroupAPI.PUT("/:id", handlers.UpdateClusterGroup)
+			clusterGroupAPI.DELETE("/:id", handlers.DeleteClusterGroup)
+		}
+
 		rbacAPI := adminAPI.Group("/roles")
 		{
 			rbacAPI.GET("/", rbac.ListRoles)
@@ -176,6 +189,12 @@ func setupAPIRouter(r *gin.RouterGroup, cm *cluster.ClusterManager, authHandler
 	api.Use(authHandler.RequireAuth())
 	{
 		api.GET("/clusters", cm.GetClusters)
+
+		federatedHandler := handlers.NewFederatedHandler(cm)
+		api.GET("/federated/:resource", federatedHandler.List)
+
+		diffHandler := handlers.NewDiffHandler(cm)
+		api.POST("/diff", diffHandler.Diff)
 		api.GET("/templates", handlers.ListTemplates)
 
 		apiKeyAPI := api.Group("/settings/api-keys")
@@ -199,8 +218,39 @@ func setupAPIRouter(r *gin.RouterGroup, cm *cluster.ClusterManager, authHandler
 			awsConfigAPI.POST("/", handlers.UpdateUserAWSConfig)
 		}
 
+		gcpConfigAPI := api.Group("/settings/gcp-config")
+		{
+			gcpConfigAPI.GET("/", handlers.GetUserGCPConfig)
+			gcpConfigAPI.POST("/", handlers.UpdateUserGCPConfig)
+			gcpConfigAPI.DELETE("/", handlers.DeleteUserGCPConfig)
+		}
+
+		azureConfigAPI := api.Group("/settings/azure-config")
+		{
+			azureConfigAPI.GET("/", handlers.GetUserAzureConfig)
+			azureConfigAPI.POST("/", handlers.UpdateUserAzureConfig)
+			azureConfigAPI.DELETE("/", handlers.DeleteUserAzureConfig)
+			azureConfigAPI.POST("/device-login", handlers.AzureDeviceLogin)
+		}
+
+		oidcConfigAPI := api.Group("/settings/oidc-config")
+		{
+			oidcConfigAPI.GET("/", handlers.GetUserOIDCConfig)
+			oidcConfigAPI.POST("/", handlers.UpdateUserOIDCConfig)
+			oidcConfigAPI.DELETE("/", handlers.DeleteUserOIDCConfig)
+		}
+
 		api.GET("/settings/gitlab-hosts", handlers.ListGitlabHosts)
 
+		dashboardAPI := api.Group("/dashboards")
+		{
+			dashboardAPI.GET("/", handlers.ListDashboards)
+			dashboardAPI.POST("/", handlers.CreateDashboard)
+			dashboardAPI.GET("/:id", handlers.GetDashboard)
+			dashboardAPI.PUT("/:id", handlers.UpdateDashboard)
+			dashboardAPI.DELETE("/:id", handlers.DeleteDashboard)
+		}
+
 		aiGroup := api.Group("/ai")
 		{
 			aiGroup.GET("/profiles", handlers.ListAIProfiles)
@@ -235,6 +285,34 @@ func setupAPIRouter(r *gin.RouterGroup, cm *cluster.ClusterManager, authHandler
 		promHandler := handlers.NewPromHandler()
 		api.GET("/prometheus/resource-usage-history", promHandler.GetResourceUsageHistory)
 		api.GET("/prometheus/pods/:namespace/:podName/metrics", promHandler.GetPodMetrics)
+		api.GET("/prometheus/workloads/:kind/:namespace/:name/metrics", promHandler.GetWorkloadMetrics)
+		api.GET("/prometheus/namespaces/:namespace/metrics", promHandler.GetNamespaceMetrics)
+		api.GET("/prometheus/volumes", promHandler.GetVolumeReport)
+		api.GET("/prometheus/volumes/:namespace/:name/metrics", promHandler.GetVolumeMetrics)
+		api.GET("/prometheus/query", promHandler.Query)
+		api.POST("/prometheus/query", promHandler.Query)
+		api.GET("/prometheus/query_range", promHandler.QueryRange)
+		api.POST("/prometheus/query_range", promHandler.QueryRange)
+
+		api.GET("/dashboards/:id/render", handlers.RenderDashboard)
+		api.GET("/cost/report", handlers.GetCostReport)
+
+		sloAPI := api.Group("/slos")
+		{
+			sloAPI.GET("", handlers.ListSLOs)
+			sloAPI.POST("", handlers.CreateSLO)
+			sloAPI.GET("/:id", handlers.GetSLO)
+			sloAPI.PUT("/:id", handlers.UpdateSLO)
+			sloAPI.DELETE("/:id", handlers.DeleteSLO)
+		}
+
+		alertmanagerAPI := api.Group("/alertmanager")
+		{
+			alertmanagerAPI.GET("/alerts", handlers.ListAlerts)
+			alertmanagerAPI.GET("/silences", handlers.ListSilences)
+			alertmanagerAPI.POST("/silences", handlers.CreateSilence)
+			alertmanagerAPI.DELETE("/silences/:id", handlers.ExpireSilence)
+		}
 
 		logsHandler := handlers.NewLogsHandler()
 		api.GET("/logs/:namespace/:podName/ws", logsHandler.HandleLogsWebSocket)
@@ -250,6 +328,9 @@ func setupAPIRouter(r *gin.RouterGroup, cm *cluster.ClusterManager, authHandler
 
 		resourceApplyHandler := handlers.NewResourceApplyHandler()
 		api.POST("/resources/apply", resourceApplyHandler.ApplyResource)
+		api.POST("/workloads/restart", handlers.BulkRestart)
+		api.POST("/resources/bulk", handlers.BulkOperation)
+		api.POST("/resources/restore", handlers.RestoreResource)
 
 		api.GET("/image/tags", handlers.GetImageTags)
 
@@ -286,9 +367,11 @@ func main() {
 	model.StartAppConfigRefresher()
 	rbac.InitRBAC()
 	handlers.InitTemplates()
+	gitops.Start(common.ConfigFile)
 	internal.LoadConfigFromEnv()
 	handlers.RestoreGitlabConfigs()
 	handlers.RestoreAWSConfigs()
+	handlers.RestoreGCPConfigs()
 
 	cm, err := cluster.NewClusterManager()
 	if err != nil {
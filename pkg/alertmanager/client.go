@@ -0,0 +1,177 @@
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client talks to the Alertmanager v2 API.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Alert is a firing, silenced or inhibited alert.
+type Alert struct {
+	Fingerprint  string            `json:"fingerprint"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	UpdatedAt    time.Time         `json:"updatedAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+	Status       AlertStatus       `json:"status"`
+	Receivers    []Receiver        `json:"receivers,omitempty"`
+}
+
+type AlertStatus struct {
+	// State is "active", "suppressed" or "unprocessed"
+	State       string   `json:"state"`
+	SilencedBy  []string `json:"silencedBy"`
+	InhibitedBy []string `json:"inhibitedBy"`
+}
+
+type Receiver struct {
+	Name string `json:"name"`
+}
+
+type Matcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	// IsEqual is false for != and !~ matchers
+	IsEqual *bool `json:"isEqual,omitempty"`
+}
+
+// Equal reports whether m is a name="value" matcher.
+func (m Matcher) Equal() bool {
+	return !m.IsRegex && (m.IsEqual == nil || *m.IsEqual)
+}
+
+type Silence struct {
+	ID        string         `json:"id,omitempty"`
+	Matchers  []Matcher      `json:"matchers"`
+	StartsAt  time.Time      `json:"startsAt"`
+	EndsAt    time.Time      `json:"endsAt"`
+	UpdatedAt *time.Time     `json:"updatedAt,omitempty"`
+	CreatedBy string         `json:"createdBy"`
+	Comment   string         `json:"comment"`
+	Status    *SilenceStatus `json:"status,omitempty"`
+}
+
+type SilenceStatus struct {
+	// State is "active", "pending" or "expired"
+	State string `json:"state"`
+}
+
+func NewClientWithRoundTripper(alertmanagerURL string, rt http.RoundTripper) (*Client, error) {
+	if alertmanagerURL == "" {
+		return nil, fmt.Errorf("alertmanager URL cannot be empty")
+	}
+	if _, err := url.Parse(alertmanagerURL); err != nil {
+		return nil, fmt.Errorf("invalid alertmanager URL: %w", err)
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(alertmanagerURL, "/"),
+		httpClient: &http.Client{Transport: rt, Timeout: 30 * time.Second},
+	}, nil
+}
+
+// ListAlerts returns the alerts matching all filters, e.g. `namespace="default"`.
+func (c *Client) ListAlerts(ctx context.Context, filters ...string) ([]Alert, error) {
+	query := url.Values{"filter": filters}
+	var alerts []Alert
+	if err := c.do(ctx, http.MethodGet, "/api/v2/alerts?"+query.Encode(), nil, &alerts); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// ListSilences returns the silences matching all filters.
+func (c *Client) ListSilences(ctx context.Context, filters ...string) ([]Silence, error) {
+	query := url.Values{"filter": filters}
+	var silences []Silence
+	if err := c.do(ctx, http.MethodGet, "/api/v2/silences?"+query.Encode(), nil, &silences); err != nil {
+		return nil, err
+	}
+	return silences, nil
+}
+
+func (c *Client) GetSilence(ctx context.Context, id string) (*Silence, error) {
+	var silence Silence
+	if err := c.do(ctx, http.MethodGet, "/api/v2/silence/"+url.PathEscape(id), nil, &silence); err != nil {
+		return nil, err
+	}
+	return &silence, nil
+}
+
+// CreateSilence creates a silence, or updates it when silence.ID is set, and
+// returns its ID.
+func (c *Client) CreateSilence(ctx context.Context, silence Silence) (string, error) {
+	silence.Status = nil
+	silence.UpdatedAt = nil
+	var resp struct {
+		SilenceID string `json:"silenceID"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v2/silences", silence, &resp); err != nil {
+		return "", err
+	}
+	return resp.SilenceID, nil
+}
+
+func (c *Client) ExpireSilence(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v2/silence/"+url.PathEscape(id), nil, nil)
+}
+
+// APIError is a non-2xx response of Alertmanager.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("alertmanager returned %d: %s", e.StatusCode, e.Message)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,60 @@
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	var created Silence
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v2/alerts", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, []string{`namespace="default"`}, r.URL.Query()["filter"])
+		_, _ = w.Write([]byte(`[{"fingerprint":"abc","labels":{"alertname":"KubePodCrashLooping","namespace":"default"},"status":{"state":"active"}}]`))
+	})
+	mux.HandleFunc("POST /api/v2/silences", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+		_, _ = w.Write([]byte(`{"silenceID":"s1"}`))
+	})
+	mux.HandleFunc("DELETE /api/v2/silence/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "s1" {
+			http.Error(w, "silence not found", http.StatusNotFound)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client, err := NewClientWithRoundTripper(srv.URL+"/", http.DefaultTransport)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	alerts, err := client.ListAlerts(ctx, `namespace="default"`)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "KubePodCrashLooping", alerts[0].Labels["alertname"])
+	assert.Equal(t, "active", alerts[0].Status.State)
+
+	id, err := client.CreateSilence(ctx, Silence{
+		Matchers:  []Matcher{{Name: "namespace", Value: "default"}},
+		StartsAt:  time.Now(),
+		EndsAt:    time.Now().Add(time.Hour),
+		CreatedBy: "alice",
+		Comment:   "maintenance",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "s1", id)
+	assert.Equal(t, "alice", created.CreatedBy)
+
+	require.NoError(t, client.ExpireSilence(ctx, "s1"))
+	err = client.ExpireSilence(ctx, "missing")
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
@@ -0,0 +1,83 @@
+package alertmanager
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// NamespaceLabel is the alert label that scopes an alert to a namespace.
+const NamespaceLabel = "namespace"
+
+// resourceMatcher finds the alerts of one resource type. label is the alert
+// label holding the resource name, as set by kube-state-metrics, and
+// podPattern matches the names of pods owned by the resource.
+type resourceMatcher struct {
+	label      string
+	podPattern string
+	// childLabel and childPattern match the names of owned objects other
+	// than pods, e.g. the jobs of a cronjob.
+	childLabel   string
+	childPattern string
+}
+
+var resourceMatchers = map[string]resourceMatcher{
+	"pods":                     {label: "pod"},
+	"deployments":              {label: "deployment", podPattern: `-[a-z0-9]+-[a-z0-9]{5}`, childLabel: "replicaset", childPattern: `-[a-z0-9]+`},
+	"replicasets":              {label: "replicaset", podPattern: `-[a-z0-9]{5}`},
+	"statefulsets":             {label: "statefulset", podPattern: `-[0-9]+`},
+	"daemonsets":               {label: "daemonset", podPattern: `-[a-z0-9]{5}`},
+	"jobs":                     {label: "job_name", podPattern: `-[a-z0-9]{5}`},
+	"cronjobs":                 {label: "cronjob", childLabel: "job_name", childPattern: `-[0-9]+`},
+	"services":                 {label: "service"},
+	"persistentvolumeclaims":   {label: "persistentvolumeclaim"},
+	"horizontalpodautoscalers": {label: "horizontalpodautoscaler"},
+	"ingresses":                {label: "ingress"},
+}
+
+// SupportedResources returns the resource types that alerts can be matched to.
+func SupportedResources() []string {
+	resources := make([]string, 0, len(resourceMatchers))
+	for resource := range resourceMatchers {
+		resources = append(resources, resource)
+	}
+	slices.Sort(resources)
+	return resources
+}
+
+// MatchResource reports whether an alert with labels is about the resource
+// (e.g. "deployments") namespace/name or one of its pods.
+func MatchResource(labels map[string]string, resource, namespace, name string) bool {
+	m, ok := resourceMatchers[resource]
+	if !ok || labels[NamespaceLabel] != namespace {
+		return false
+	}
+	if labels[m.label] == name {
+		return true
+	}
+	if m.podPattern != "" && matchOwned(labels["pod"], name, m.podPattern) {
+		return true
+	}
+	return m.childLabel != "" && matchOwned(labels[m.childLabel], name, m.childPattern)
+}
+
+// matchOwned reports whether value is name followed by a generated suffix.
+func matchOwned(value, name, suffixPattern string) bool {
+	if value == "" {
+		return false
+	}
+	re, err := regexp.Compile("^" + regexp.QuoteMeta(name) + suffixPattern + "$")
+	return err == nil && re.MatchString(value)
+}
+
+// ValidateFilter checks that filter is a single Alertmanager matcher, such
+// as `severity="critical"` or `team=~"web|api"`.
+func ValidateFilter(filter string) error {
+	matchers, err := parser.ParseMetricSelector("{" + filter + "}")
+	if err != nil || len(matchers) != 1 {
+		return fmt.Errorf("invalid alert filter %q: must be a single matcher like severity=\"critical\"", filter)
+	}
+	return nil
+}
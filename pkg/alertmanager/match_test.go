@@ -0,0 +1,38 @@
+package alertmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchResource(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		resource string
+		expected bool
+	}{
+		{"deployment label", map[string]string{"namespace": "default", "deployment": "api"}, "deployments", true},
+		{"deployment pod", map[string]string{"namespace": "default", "pod": "api-7d9f8b6c5-x2k4p"}, "deployments", true},
+		{"deployment replicaset", map[string]string{"namespace": "default", "replicaset": "api-7d9f8b6c5"}, "deployments", true},
+		{"other deployment pod", map[string]string{"namespace": "default", "pod": "api-gateway-7d9f8b6c5-x2k4p"}, "deployments", false},
+		{"other namespace", map[string]string{"namespace": "prod", "deployment": "api"}, "deployments", false},
+		{"statefulset pod", map[string]string{"namespace": "default", "pod": "api-0"}, "statefulsets", true},
+		{"cronjob job", map[string]string{"namespace": "default", "job_name": "api-28312345"}, "cronjobs", true},
+		{"pod", map[string]string{"namespace": "default", "pod": "api"}, "pods", true},
+		{"unsupported resource", map[string]string{"namespace": "default", "configmap": "api"}, "configmaps", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, MatchResource(tc.labels, tc.resource, "default", "api"))
+		})
+	}
+}
+
+func TestValidateFilter(t *testing.T) {
+	assert.NoError(t, ValidateFilter(`severity="critical"`))
+	assert.NoError(t, ValidateFilter(`team=~"web|api"`))
+	assert.Error(t, ValidateFilter(`severity`))
+	assert.Error(t, ValidateFilter(`severity="critical",team="web"`))
+}
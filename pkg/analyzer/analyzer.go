@@ -3,6 +3,7 @@ package analyzer
 import (
 	"context"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -10,3 +11,10 @@ type Analyzer interface {
 	Name() string
 	Analyze(ctx context.Context, client client.Client, obj client.Object) ([]Anomaly, error)
 }
+
+// TargetedAnalyzer is an Analyzer that only runs for objects of the kinds it
+// targets, which lets it analyze custom resources as unstructured objects.
+type TargetedAnalyzer interface {
+	Analyzer
+	Targets() []schema.GroupKind
+}
@@ -0,0 +1,155 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CertificateExpiryWarningDays is how far ahead cert-manager certificates
+// that expire are reported.
+const CertificateExpiryWarningDays = 14
+
+// healthyConditions are the condition types that are False when a custom
+// resource is unhealthy, and unhealthyConditions the ones that are True.
+var (
+	healthyConditions   = []string{"Ready", "Available", "Synced", "Healthy"}
+	unhealthyConditions = []string{"Stalled", "Degraded", "Failed"}
+)
+
+// StatusConditionAnalyzer reports the failing status conditions of custom
+// resources, following the Ready/Stalled conventions of most operators.
+type StatusConditionAnalyzer struct{}
+
+func (a *StatusConditionAnalyzer) Name() string { return "StatusConditions" }
+
+func (a *StatusConditionAnalyzer) Analyze(ctx context.Context, c client.Client, obj client.Object) ([]Anomaly, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, nil
+	}
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+
+	var anomalies []Anomaly
+	for _, item := range conditions {
+		condition, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		conditionType, _, _ := unstructured.NestedString(condition, "type")
+		status, _, _ := unstructured.NestedString(condition, "status")
+		failing := (status == "False" && slices.Contains(healthyConditions, conditionType)) ||
+			(status == "True" && slices.Contains(unhealthyConditions, conditionType))
+		if !failing {
+			continue
+		}
+		message := fmt.Sprintf("%s %s has condition %s=%s", u.GetKind(), u.GetName(), conditionType, status)
+		if reason, _, _ := unstructured.NestedString(condition, "reason"); reason != "" {
+			message += " (" + reason + ")"
+		}
+		if detail, _, _ := unstructured.NestedString(condition, "message"); detail != "" {
+			message += ": " + detail
+		}
+		anomalies = append(anomalies, Anomaly{
+			Severity:    SeverityHigh,
+			Title:       fmt.Sprintf("%s Condition Is %s", conditionType, status),
+			Message:     message + ".",
+			Remediation: "Check the events of the resource and the logs of the operator that manages it.",
+			RuleID:      "CR-001",
+		})
+	}
+	return anomalies, nil
+}
+
+// ArgoApplicationAnalyzer reports Argo CD applications that are unhealthy or
+// out of sync.
+type ArgoApplicationAnalyzer struct{}
+
+func (a *ArgoApplicationAnalyzer) Name() string { return "ArgoApplication" }
+
+func (a *ArgoApplicationAnalyzer) Targets() []schema.GroupKind {
+	return []schema.GroupKind{{Group: "argoproj.io", Kind: "Application"}}
+}
+
+func (a *ArgoApplicationAnalyzer) Analyze(ctx context.Context, c client.Client, obj client.Object) ([]Anomaly, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, nil
+	}
+
+	var anomalies []Anomaly
+	health, _, _ := unstructured.NestedString(u.Object, "status", "health", "status")
+	if health == "Degraded" || health == "Missing" {
+		anomalies = append(anomalies, Anomaly{
+			Severity:    SeverityHigh,
+			Title:       "Application " + health,
+			Message:     fmt.Sprintf("Argo CD reports the health of application %s as %s.", u.GetName(), health),
+			Remediation: "Open the application in Argo CD to find the resources that are not healthy.",
+			RuleID:      "CR-002",
+		})
+	}
+	if sync, _, _ := unstructured.NestedString(u.Object, "status", "sync", "status"); sync == "OutOfSync" {
+		anomalies = append(anomalies, Anomaly{
+			Severity:    SeverityMedium,
+			Title:       "Application Out Of Sync",
+			Message:     fmt.Sprintf("The live state of application %s differs from its target revision.", u.GetName()),
+			Remediation: "Sync the application, or enable automated sync if the drift is unexpected.",
+			RuleID:      "CR-003",
+		})
+	}
+	return anomalies, nil
+}
+
+// CertificateExpiryAnalyzer reports cert-manager certificates that expired or
+// expire soon, which usually means their renewal is failing.
+type CertificateExpiryAnalyzer struct{}
+
+func (a *CertificateExpiryAnalyzer) Name() string { return "CertificateExpiry" }
+
+func (a *CertificateExpiryAnalyzer) Targets() []schema.GroupKind {
+	return []schema.GroupKind{{Group: "cert-manager.io", Kind: "Certificate"}}
+}
+
+func (a *CertificateExpiryAnalyzer) Analyze(ctx context.Context, c client.Client, obj client.Object) ([]Anomaly, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, nil
+	}
+	value, _, _ := unstructured.NestedString(u.Object, "status", "notAfter")
+	notAfter, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, nil
+	}
+
+	left := time.Until(notAfter)
+	switch {
+	case left <= 0:
+		return []Anomaly{{
+			Severity:    SeverityCritical,
+			Title:       "Certificate Expired",
+			Message:     fmt.Sprintf("Certificate %s expired on %s.", u.GetName(), notAfter.Format(time.RFC1123)),
+			Remediation: "Check the CertificateRequests and the Issuer of the certificate to find why it was not renewed.",
+			RuleID:      "CR-004",
+		}}, nil
+	case left <= CertificateExpiryWarningDays*24*time.Hour:
+		return []Anomaly{{
+			Severity:    SeverityHigh,
+			Title:       "Certificate Expiring Soon",
+			Message:     fmt.Sprintf("Certificate %s expires in %.0f days.", u.GetName(), left.Hours()/24),
+			Remediation: "cert-manager renews certificates well before they expire; check the CertificateRequests and the Issuer of the certificate.",
+			RuleID:      "CR-005",
+		}}, nil
+	}
+	return nil, nil
+}
+
+func init() {
+	Register(&StatusConditionAnalyzer{})
+	Register(&ArgoApplicationAnalyzer{})
+	Register(&CertificateExpiryAnalyzer{})
+}
@@ -0,0 +1,62 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func ruleIDs(analysis *ResourceAnalysis) []string {
+	var ids []string
+	for _, anomaly := range analysis.Anomalies {
+		ids = append(ids, anomaly.RuleID)
+	}
+	return ids
+}
+
+func TestAnalyzeUnstructured(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+	ctx := context.Background()
+
+	app := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+		"metadata":   map[string]interface{}{"name": "shop", "namespace": "argocd"},
+		"status": map[string]interface{}{
+			"health": map[string]interface{}{"status": "Degraded"},
+			"sync":   map[string]interface{}{"status": "OutOfSync"},
+		},
+	}}
+	assert.ElementsMatch(t, []string{"CR-002", "CR-003"}, ruleIDs(Analyze(ctx, c, app)))
+
+	// the Argo CD analyzer only targets applications
+	rollout := app.DeepCopy()
+	rollout.SetKind("Rollout")
+	assert.Empty(t, ruleIDs(Analyze(ctx, c, rollout)))
+
+	certificate := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"metadata":   map[string]interface{}{"name": "shop-tls", "namespace": "shop"},
+		"status": map[string]interface{}{
+			"notAfter":   time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339),
+			"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "False", "reason": "Failed"}},
+		},
+	}}
+	assert.ElementsMatch(t, []string{"CR-001", "CR-005"}, ruleIDs(Analyze(ctx, c, certificate)))
+	assert.ElementsMatch(t, []string{"CR-001"}, ruleIDs(Analyze(ctx, c, certificate, "CertificateExpiry")))
+
+	// built-in kinds are analyzed as their typed objects
+	deployment := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "api", "namespace": "shop"},
+		"spec":       map[string]interface{}{"replicas": int64(1)},
+	}}
+	assert.Contains(t, ruleIDs(Analyze(ctx, c, deployment)), "REL-001")
+}
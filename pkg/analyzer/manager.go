@@ -2,10 +2,15 @@ package analyzer
 
 import (
 	"context"
+	"slices"
 	"sync"
 
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
 var (
@@ -19,12 +24,21 @@ func Register(a Analyzer) {
 	analyzers = append(analyzers, a)
 }
 
-func Analyze(ctx context.Context, k8sClient client.Client, obj client.Object) *ResourceAnalysis {
+// Analyze runs every registered analyzer against obj, except the ones named in disabled.
+// Unstructured objects of built-in kinds are converted to their typed objects first.
+func Analyze(ctx context.Context, k8sClient client.Client, obj client.Object, disabled ...string) *ResourceAnalysis {
 	mu.RLock()
 	defer mu.RUnlock()
 
+	obj, gk := typedObject(k8sClient.Scheme(), obj)
 	var anomalies []Anomaly
 	for _, a := range analyzers {
+		if slices.Contains(disabled, a.Name()) {
+			continue
+		}
+		if t, ok := a.(TargetedAnalyzer); ok && !slices.Contains(t.Targets(), gk) {
+			continue
+		}
 		results, err := a.Analyze(ctx, k8sClient, obj)
 		if err != nil {
 			klog.Errorf("Analyzer %s failed: %v", a.Name(), err)
@@ -62,3 +76,35 @@ func Analyze(ctx context.Context, k8sClient client.Client, obj client.Object) *R
 
 	return analysis
 }
+
+// typedObject returns obj as the typed object of its kind when it is an
+// unstructured object known to scheme, and the group kind of obj.
+func typedObject(scheme *runtime.Scheme, obj client.Object) (client.Object, schema.GroupKind) {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Empty() {
+		if scheme == nil {
+			return obj, schema.GroupKind{}
+		}
+		var err error
+		if gvk, err = apiutil.GVKForObject(obj, scheme); err != nil {
+			return obj, schema.GroupKind{}
+		}
+	}
+
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok || scheme == nil || !scheme.Recognizes(gvk) {
+		return obj, gvk.GroupKind()
+	}
+	typed, err := scheme.New(gvk)
+	if err != nil {
+		return obj, gvk.GroupKind()
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+		klog.Warningf("Failed to convert %s %s to its typed object: %v", gvk.Kind, u.GetName(), err)
+		return obj, gvk.GroupKind()
+	}
+	if typedObj, ok := typed.(client.Object); ok {
+		return typedObj, gvk.GroupKind()
+	}
+	return obj, gvk.GroupKind()
+}
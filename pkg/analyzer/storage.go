@@ -0,0 +1,94 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pixelvide/kube-sentinel/pkg/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// VolumeFillWarningDays is how far ahead volumes predicted to fill up are
+// reported.
+const VolumeFillWarningDays = 7
+
+// VolumeForecaster forecasts when persistent volume claims fill up.
+type VolumeForecaster interface {
+	GetVolumeUsages(ctx context.Context, namespace string, window time.Duration) ([]prometheus.VolumeUsage, error)
+}
+
+type volumeForecasterKey struct{}
+
+// WithVolumeForecaster returns a context that makes f available to the
+// analyzers. Without it, volume usage is not analyzed.
+func WithVolumeForecaster(ctx context.Context, f VolumeForecaster) context.Context {
+	return context.WithValue(ctx, volumeForecasterKey{}, f)
+}
+
+// VolumeAtRisk returns the anomaly of a volume that is almost full or
+// predicted to fill up within days, or nil.
+func VolumeAtRisk(usage prometheus.VolumeUsage, days float64) *Anomaly {
+	name := usage.Namespace + "/" + usage.Name
+	switch {
+	case usage.UsedPercent >= 95 || (usage.DaysUntilFull != nil && *usage.DaysUntilFull <= 1):
+		return &Anomaly{
+			Severity:    SeverityCritical,
+			Title:       "Volume Almost Full",
+			Message:     fmt.Sprintf("PersistentVolumeClaim %s is %.0f%% full%s.", name, usage.UsedPercent, fullIn(usage)),
+			Remediation: "Expand the volume or free up space now to avoid a disk-full outage.",
+			RuleID:      "STO-001",
+		}
+	case usage.DaysUntilFull != nil && *usage.DaysUntilFull <= days:
+		return &Anomaly{
+			Severity:    SeverityHigh,
+			Title:       "Volume Predicted To Fill Up",
+			Message:     fmt.Sprintf("PersistentVolumeClaim %s is %.0f%% full%s.", name, usage.UsedPercent, fullIn(usage)),
+			Remediation: "Plan a volume expansion or data cleanup before the volume fills up.",
+			RuleID:      "STO-002",
+		}
+	}
+	return nil
+}
+
+func fullIn(usage prometheus.VolumeUsage) string {
+	if usage.DaysUntilFull == nil {
+		return ""
+	}
+	return fmt.Sprintf(" and predicted to fill up in %.1f days at %.1f MiB per day", *usage.DaysUntilFull, usage.GrowthBytesPerDay/1024/1024)
+}
+
+type VolumeUsageAnalyzer struct{}
+
+func (a *VolumeUsageAnalyzer) Name() string {
+	return "VolumeUsage"
+}
+
+func (a *VolumeUsageAnalyzer) Analyze(ctx context.Context, c client.Client, obj client.Object) ([]Anomaly, error) {
+	pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return nil, nil
+	}
+	forecaster, ok := ctx.Value(volumeForecasterKey{}).(VolumeForecaster)
+	if !ok {
+		return nil, nil
+	}
+	usages, err := forecaster.GetVolumeUsages(ctx, pvc.Namespace, prometheus.DefaultForecastWindow)
+	if err != nil {
+		return nil, err
+	}
+	for _, usage := range usages {
+		if usage.Name != pvc.Name {
+			continue
+		}
+		if anomaly := VolumeAtRisk(usage, VolumeFillWarningDays); anomaly != nil {
+			return []Anomaly{*anomaly}, nil
+		}
+	}
+	return nil, nil
+}
+
+func init() {
+	Register(&VolumeUsageAnalyzer{})
+}
@@ -482,6 +482,8 @@ func (h *AuthHandler) CreateOAuthProvider(c *gin.Context) {
 		return
 	}
 
+	provider.ManagedBy = ""
+
 	// Validate required fields
 	if provider.Name == "" || provider.ClientID == "" || string(provider.ClientSecret) == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -526,6 +528,13 @@ func (h *AuthHandler) UpdateOAuthProvider(c *gin.Context) {
 	}
 	provider.ID = uint(dbID)
 
+	if isConfigManagedProvider(provider.ID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": model.ErrConfigManaged.Error(),
+		})
+		return
+	}
+
 	// Validate required fields
 	if provider.Name == "" || provider.ClientID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -571,6 +580,13 @@ func (h *AuthHandler) DeleteOAuthProvider(c *gin.Context) {
 		return
 	}
 
+	if isConfigManagedProvider(uint(dbID)) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": model.ErrConfigManaged.Error(),
+		})
+		return
+	}
+
 	if err := model.DeleteOAuthProvider(uint(dbID)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to delete OAuth provider: " + err.Error(),
@@ -583,6 +599,16 @@ func (h *AuthHandler) DeleteOAuthProvider(c *gin.Context) {
 	})
 }
 
+// isConfigManagedProvider reports whether the provider is owned by the
+// configuration file.
+func isConfigManagedProvider(id uint) bool {
+	var provider model.OAuthProvider
+	if err := model.DB.Select("managed_by").First(&provider, id).Error; err != nil {
+		return false
+	}
+	return provider.ManagedBy == model.ManagedByConfig
+}
+
 func (h *AuthHandler) GetOAuthProvider(c *gin.Context) {
 	id := c.Param("id")
 	dbID, err := strconv.ParseUint(id, 10, 32)
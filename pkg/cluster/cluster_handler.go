@@ -4,8 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,6 +15,8 @@ import (
 	"github.com/pixelvide/kube-sentinel/pkg/model"
 	"github.com/pixelvide/kube-sentinel/pkg/rbac"
 	"gorm.io/gorm"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
 )
@@ -22,6 +26,16 @@ func (cm *ClusterManager) GetClusters(c *gin.Context) {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 
+	selector := labels.Everything()
+	if ls := c.Query("labelSelector"); ls != "" {
+		var err error
+		selector, err = labels.Parse(ls)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid labelSelector parameter: " + err.Error()})
+			return
+		}
+	}
+
 	result := make([]common.ClusterInfo, 0)
 
 	// We should show clusters that are either in shared map OR in user map for this user
@@ -36,11 +50,15 @@ func (cm *ClusterManager) GetClusters(c *gin.Context) {
 		if !cluster.Enable || !rbac.CanAccessCluster(user, cluster.Name) {
 			continue
 		}
+		if !selector.Matches(labels.Set(cluster.Labels)) {
+			continue
+		}
 
 		info := common.ClusterInfo{
 			Name:           cluster.Name,
 			IsDefault:      cluster.Name == cm.defaultContext,
 			SkipSystemSync: cluster.SkipSystemSync,
+			Labels:         cluster.Labels,
 		}
 
 		// Check shared client
@@ -89,20 +107,28 @@ func (cm *ClusterManager) GetClusterList(c *gin.Context) {
 	result := make([]gin.H, 0, len(clusters))
 	for _, cluster := range clusters {
 		config := ""
+		var promConfig *model.PrometheusConfig
 		if isAdmin {
 			config = string(cluster.Config)
+			promConfig = cluster.PrometheusConfig
 		}
 
 		clusterInfo := gin.H{
-			"id":             cluster.ID,
-			"name":           cluster.Name,
-			"description":    cluster.Description,
-			"enabled":        cluster.Enable,
-			"inCluster":      cluster.InCluster,
-			"isDefault":      cluster.IsDefault,
-			"prometheusURL":  cluster.PrometheusURL,
-			"config":         config,
-			"skipSystemSync": cluster.SkipSystemSync,
+			"id":               cluster.ID,
+			"name":             cluster.Name,
+			"description":      cluster.Description,
+			"enabled":          cluster.Enable,
+			"inCluster":        cluster.InCluster,
+			"isDefault":        cluster.IsDefault,
+			"prometheusURL":    cluster.PrometheusURL,
+			"prometheusConfig": promConfig,
+			"alertmanagerURL":  cluster.AlertmanagerURL,
+			"config":           config,
+			"skipSystemSync":   cluster.SkipSystemSync,
+			"labels":           cluster.Labels,
+			"cachePolicy":      cluster.CachePolicy,
+			"pricing":          cluster.Pricing,
+			"managedBy":        cluster.ManagedBy,
 		}
 
 		if clientSet, exists := cm.clusters[cluster.Name]; exists {
@@ -132,19 +158,41 @@ func (cm *ClusterManager) GetClusterList(c *gin.Context) {
 
 func (cm *ClusterManager) CreateCluster(c *gin.Context) {
 	var req struct {
-		Name           string `json:"name" binding:"required"`
-		Description    string `json:"description"`
-		Config         string `json:"config"`
-		PrometheusURL  string `json:"prometheusURL"`
-		InCluster      bool   `json:"inCluster"`
-		IsDefault      bool   `json:"isDefault"`
-		SkipSystemSync bool   `json:"skipSystemSync"`
+		Name           string              `json:"name" binding:"required"`
+		Description    string              `json:"description"`
+		Config         string              `json:"config"`
+		PrometheusURL  string              `json:"prometheusURL"`
+		InCluster      bool                `json:"inCluster"`
+		IsDefault      bool                `json:"isDefault"`
+		SkipSystemSync bool                `json:"skipSystemSync"`
+		Labels         map[string]string   `json:"labels"`
+		CachePolicy    *common.CachePolicy `json:"cachePolicy"`
+
+		PrometheusConfig *model.PrometheusConfig `json:"prometheusConfig"`
+		AlertmanagerURL  string                  `json:"alertmanagerURL"`
+		Pricing          *model.CostPricing      `json:"pricing"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := validateClusterLabels(req.Labels); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateCachePolicy(req.CachePolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validatePrometheusConfig(req.PrometheusConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Pricing.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if _, err := model.GetClusterByName(req.Name); err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "cluster already exists"})
@@ -170,6 +218,16 @@ func (cm *ClusterManager) CreateCluster(c *gin.Context) {
 		IsDefault:      req.IsDefault,
 		SkipSystemSync: req.SkipSystemSync,
 		Enable:         true,
+		Labels:         req.Labels,
+		CachePolicy:    req.CachePolicy,
+
+		AlertmanagerURL: req.AlertmanagerURL,
+	}
+	if !req.PrometheusConfig.IsZero() {
+		cluster.PrometheusConfig = req.PrometheusConfig
+	}
+	if !req.Pricing.IsZero() {
+		cluster.Pricing = req.Pricing
 	}
 
 	if err := model.AddCluster(cluster); err != nil {
@@ -178,6 +236,7 @@ func (cm *ClusterManager) CreateCluster(c *gin.Context) {
 	}
 
 	syncNow <- struct{}{}
+	rbac.TriggerSync()
 
 	c.JSON(http.StatusCreated, gin.H{
 		"id":      cluster.ID,
@@ -194,20 +253,42 @@ func (cm *ClusterManager) UpdateCluster(c *gin.Context) {
 	}
 
 	var req struct {
-		Name           string `json:"name"`
-		Description    string `json:"description"`
-		Config         string `json:"config"`
-		PrometheusURL  string `json:"prometheusURL"`
-		InCluster      bool   `json:"inCluster"`
-		IsDefault      bool   `json:"isDefault"`
-		Enabled        bool   `json:"enabled"`
-		SkipSystemSync bool   `json:"skipSystemSync"`
+		Name           string              `json:"name"`
+		Description    string              `json:"description"`
+		Config         string              `json:"config"`
+		PrometheusURL  string              `json:"prometheusURL"`
+		InCluster      bool                `json:"inCluster"`
+		IsDefault      bool                `json:"isDefault"`
+		Enabled        bool                `json:"enabled"`
+		SkipSystemSync bool                `json:"skipSystemSync"`
+		Labels         map[string]string   `json:"labels"`
+		CachePolicy    *common.CachePolicy `json:"cachePolicy"`
+
+		PrometheusConfig *model.PrometheusConfig `json:"prometheusConfig"`
+		AlertmanagerURL  string                  `json:"alertmanagerURL"`
+		Pricing          *model.CostPricing      `json:"pricing"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := validateClusterLabels(req.Labels); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateCachePolicy(req.CachePolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validatePrometheusConfig(req.PrometheusConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Pricing.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	cluster, err := model.GetClusterByID(uint(id))
 	if err != nil {
@@ -219,6 +300,11 @@ func (cm *ClusterManager) UpdateCluster(c *gin.Context) {
 		return
 	}
 
+	if cluster.ManagedBy == model.ManagedByConfig {
+		c.JSON(http.StatusForbidden, gin.H{"error": model.ErrConfigManaged.Error()})
+		return
+	}
+
 	if req.IsDefault && !cluster.IsDefault {
 		if err := model.ClearDefaultCluster(); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -229,6 +315,7 @@ func (cm *ClusterManager) UpdateCluster(c *gin.Context) {
 	updates := map[string]interface{}{
 		"description":      req.Description,
 		"prometheus_url":   req.PrometheusURL,
+		"alertmanager_url": req.AlertmanagerURL,
 		"in_cluster":       req.InCluster,
 		"is_default":       req.IsDefault,
 		"enable":           req.Enabled,
@@ -243,12 +330,39 @@ func (cm *ClusterManager) UpdateCluster(c *gin.Context) {
 		updates["config"] = model.SecretString(req.Config)
 	}
 
+	if req.Labels != nil {
+		updates["labels"] = model.MapString(req.Labels)
+	}
+
+	if req.CachePolicy != nil {
+		updates["cache_policy"] = *req.CachePolicy
+	}
+
+	// An empty pricing disables cost reports
+	if req.Pricing != nil {
+		if req.Pricing.IsZero() {
+			updates["pricing"] = nil
+		} else {
+			updates["pricing"] = *req.Pricing
+		}
+	}
+
+	// An empty prometheusConfig clears the settings
+	if req.PrometheusConfig != nil {
+		if req.PrometheusConfig.IsZero() {
+			updates["prometheus_config"] = nil
+		} else {
+			updates["prometheus_config"] = *req.PrometheusConfig
+		}
+	}
+
 	if err := model.UpdateCluster(cluster, updates); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	syncNow <- struct{}{}
+	rbac.TriggerSync()
 
 	c.JSON(http.StatusOK, gin.H{"message": "cluster updated successfully"})
 }
@@ -271,6 +385,11 @@ func (cm *ClusterManager) DeleteCluster(c *gin.Context) {
 		return
 	}
 
+	if cluster.ManagedBy == model.ManagedByConfig {
+		c.JSON(http.StatusForbidden, gin.H{"error": model.ErrConfigManaged.Error()})
+		return
+	}
+
 	if cluster.IsDefault {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot delete default cluster"})
 		return
@@ -282,6 +401,7 @@ func (cm *ClusterManager) DeleteCluster(c *gin.Context) {
 	}
 
 	syncNow <- struct{}{}
+	rbac.TriggerSync()
 
 	c.JSON(http.StatusOK, gin.H{"message": "cluster deleted successfully"})
 }
@@ -312,6 +432,7 @@ func (cm *ClusterManager) ImportClustersFromKubeconfig(c *gin.Context) {
 			return
 		}
 		syncNow <- struct{}{}
+		rbac.TriggerSync()
 		// wait for sync to complete
 		time.Sleep(1 * time.Second)
 		c.JSON(http.StatusCreated, gin.H{"message": fmt.Sprintf("imported %d clusters successfully", 1)})
@@ -326,7 +447,37 @@ func (cm *ClusterManager) ImportClustersFromKubeconfig(c *gin.Context) {
 
 	importedCount := ImportClustersFromKubeconfig(kubeconfig)
 	syncNow <- struct{}{}
+	rbac.TriggerSync()
 	// wait for sync to complete
 	time.Sleep(1 * time.Second)
 	c.JSON(http.StatusCreated, gin.H{"message": fmt.Sprintf("imported %d clusters successfully", importedCount)})
 }
+
+// validateClusterLabels checks that cluster labels use Kubernetes label syntax
+// so they can be matched by label selectors.
+func validateClusterLabels(clusterLabels map[string]string) error {
+	for k, v := range clusterLabels {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %q: %s", k, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			return fmt.Errorf("invalid label value %q: %s", v, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+func validateCachePolicy(policy *common.CachePolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.MaxAnnotationBytes < 0 {
+		return fmt.Errorf("maxAnnotationBytes must not be negative")
+	}
+	for _, kind := range append(slices.Clone(policy.Cached), policy.Uncached...) {
+		if strings.TrimSpace(kind) == "" {
+			return fmt.Errorf("cache policy kinds must not be empty")
+		}
+	}
+	return nil
+}
@@ -1,20 +1,24 @@
 package cluster
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/pixelvide/kube-sentinel/pkg/alertmanager"
+	"github.com/pixelvide/kube-sentinel/pkg/common"
 	"github.com/pixelvide/kube-sentinel/pkg/kube"
 	"github.com/pixelvide/kube-sentinel/pkg/model"
 	"github.com/pixelvide/kube-sentinel/pkg/prometheus"
 	"github.com/pixelvide/kube-sentinel/pkg/rbac"
-	"github.com/pixelvide/kube-sentinel/pkg/utils"
 	"gorm.io/gorm"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
@@ -28,11 +32,37 @@ type ClientSet struct {
 	K8sClient  *kube.K8sClient
 	PromClient *prometheus.Client
 
+	AlertmanagerClient *alertmanager.Client
+
 	Configuration *rest.Config
 
-	DiscoveredPrometheusURL string
-	config                  string
-	prometheusURL           string
+	DiscoveredPrometheusURL   string
+	config                    string
+	DiscoveredAlertmanagerURL string
+	prometheusURL             string
+	prometheusConfig          string
+	alertmanagerURL           string
+	cachePolicy               string
+
+	// execPlugin and execCredentialsVersion track the per-user exec credentials
+	// a user client was built with.
+	execPlugin             string
+	execCredentialsVersion string
+}
+
+// AccessibleNamespaces returns the namespaces of the cluster the user can access.
+func (cs *ClientSet) AccessibleNamespaces(ctx context.Context, user model.User) ([]string, error) {
+	var nsList corev1.NamespaceList
+	if err := cs.K8sClient.List(ctx, &nsList); err != nil {
+		return nil, err
+	}
+	namespaces := make([]string, 0, len(nsList.Items))
+	for _, ns := range nsList.Items {
+		if rbac.CanAccessNamespace(user, cs.Name, ns.Name) {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+	return namespaces, nil
 }
 
 type UserClient struct {
@@ -51,22 +81,37 @@ type ClusterManager struct {
 	activeUsersMu  sync.RWMutex
 }
 
-func createClientSetInCluster(name, prometheusURL string, skipSystemSync bool) (*ClientSet, error) {
+// monitoringConfig holds the Prometheus and Alertmanager settings of a cluster.
+type monitoringConfig struct {
+	prometheusURL    string
+	prometheusConfig *model.PrometheusConfig
+	alertmanagerURL  string
+}
+
+func monitoringConfigOf(cluster *model.Cluster) monitoringConfig {
+	return monitoringConfig{
+		prometheusURL:    cluster.PrometheusURL,
+		prometheusConfig: cluster.PrometheusConfig,
+		alertmanagerURL:  cluster.AlertmanagerURL,
+	}
+}
+
+func createClientSetInCluster(name string, monitoring monitoringConfig, skipSystemSync bool, cachePolicy *common.CachePolicy) (*ClientSet, error) {
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	return newClientSet(name, config, prometheusURL, skipSystemSync)
+	return newClientSet(name, config, monitoring, skipSystemSync, cachePolicy)
 }
 
-func createClientSetFromConfig(name, content, prometheusURL string, skipSystemSync bool) (*ClientSet, error) {
+func createClientSetFromConfig(name, content string, monitoring monitoringConfig, skipSystemSync bool, cachePolicy *common.CachePolicy) (*ClientSet, error) {
 	restConfig, err := clientcmd.RESTConfigFromKubeConfig([]byte(content))
 	if err != nil {
 		klog.Warningf("Failed to create REST config for cluster %s: %v", name, err)
 		return nil, err
 	}
-	cs, err := newClientSet(name, restConfig, prometheusURL, skipSystemSync)
+	cs, err := newClientSet(name, restConfig, monitoring, skipSystemSync, cachePolicy)
 	if err != nil {
 		return nil, err
 	}
@@ -75,15 +120,22 @@ func createClientSetFromConfig(name, content, prometheusURL string, skipSystemSy
 	return cs, nil
 }
 
-func newClientSet(name string, k8sConfig *rest.Config, prometheusURL string, skipSystemSync bool) (*ClientSet, error) {
+func newClientSet(name string, k8sConfig *rest.Config, monitoring monitoringConfig, skipSystemSync bool, cachePolicy *common.CachePolicy) (*ClientSet, error) {
 	cs := &ClientSet{
-		Name:          name,
-		Configuration: k8sConfig,
-		prometheusURL: prometheusURL,
-	}
+		Name:             name,
+		Configuration:    k8sConfig,
+		prometheusURL:    monitoring.prometheusURL,
+		prometheusConfig: prometheusConfigKey(monitoring.prometheusConfig),
+		alertmanagerURL:  monitoring.alertmanagerURL,
+		cachePolicy:      cachePolicyKey(cachePolicy),
+	}
+	prometheusURL := monitoring.prometheusURL
+	alertmanagerURL := monitoring.alertmanagerURL
 	var err error
 	cs.K8sClient, err = kube.NewClient(kube.ClientOptions{
-		Config: k8sConfig,
+		Config:      k8sConfig,
+		Name:        name,
+		CachePolicy: cachePolicy,
 	})
 	if err != nil {
 		klog.Warningf("Failed to create k8s client for cluster %s: %v", name, err)
@@ -101,21 +153,24 @@ func newClientSet(name string, k8sConfig *rest.Config, prometheusURL string, ski
 		}
 	}
 	if prometheusURL != "" {
-		var rt = http.DefaultTransport
-		var err error
-		if isClusterLocalURL(prometheusURL) {
-			rt, err = createK8sProxyTransport(k8sConfig, prometheusURL)
-			if err != nil {
-				klog.Warningf("Failed to create k8s proxy transport for cluster %s: %v, using direct connection", name, err)
-			} else {
-				klog.Infof("Using k8s API proxy for Prometheus in cluster %s", name)
-			}
-		}
-		cs.PromClient, err = prometheus.NewClientWithRoundTripper(prometheusURL, rt)
+		cs.PromClient, err = newPrometheusClient(name, k8sConfig, prometheusURL, monitoring.prometheusConfig)
 		if err != nil {
 			klog.Warningf("Failed to create Prometheus client for cluster %s, some features may not work as expected, err: %v", name, err)
 		}
 	}
+	if alertmanagerURL == "" && !skipSystemSync {
+		alertmanagerURL = discoveryAlertmanagerURL(cs.K8sClient)
+		if alertmanagerURL != "" {
+			cs.DiscoveredAlertmanagerURL = alertmanagerURL
+			klog.Infof("Discovered Alertmanager URL for cluster %s: %s", name, cs.DiscoveredAlertmanagerURL)
+		}
+	}
+	if alertmanagerURL != "" {
+		cs.AlertmanagerClient, err = newAlertmanagerClient(name, k8sConfig, alertmanagerURL, monitoring.prometheusConfig)
+		if err != nil {
+			klog.Warningf("Failed to create Alertmanager client for cluster %s, err: %v", name, err)
+		}
+	}
 	if !skipSystemSync {
 		v, err := cs.K8sClient.ClientSet.Discovery().ServerVersion()
 		if err != nil {
@@ -131,6 +186,56 @@ func newClientSet(name string, k8sConfig *rest.Config, prometheusURL string, ski
 	return cs, nil
 }
 
+// newPrometheusClient creates the Prometheus client of a cluster.
+func newPrometheusClient(name string, k8sConfig *rest.Config, prometheusURL string, promConfig *model.PrometheusConfig) (*prometheus.Client, error) {
+	rt, err := newMonitoringTransport(name, k8sConfig, prometheusURL, promConfig)
+	if err != nil {
+		return nil, err
+	}
+	return prometheus.NewClientWithRoundTripper(prometheusURL, rt)
+}
+
+// newAlertmanagerClient creates the Alertmanager client of a cluster. It uses
+// the Prometheus authentication settings, since both usually sit behind the
+// same gateway.
+func newAlertmanagerClient(name string, k8sConfig *rest.Config, alertmanagerURL string, promConfig *model.PrometheusConfig) (*alertmanager.Client, error) {
+	rt, err := newMonitoringTransport(name, k8sConfig, alertmanagerURL, promConfig)
+	if err != nil {
+		return nil, err
+	}
+	return alertmanager.NewClientWithRoundTripper(alertmanagerURL, rt)
+}
+
+// newMonitoringTransport returns the transport to a monitoring service. Cluster
+// local URLs are reached through the Kubernetes API server proxy.
+func newMonitoringTransport(name string, k8sConfig *rest.Config, serviceURL string, promConfig *model.PrometheusConfig) (http.RoundTripper, error) {
+	var rt http.RoundTripper
+	proxied := false
+	if isClusterLocalURL(serviceURL) {
+		proxyTransport, err := createK8sProxyTransport(k8sConfig, serviceURL)
+		if err != nil {
+			klog.Warningf("Failed to create k8s proxy transport for %s in cluster %s: %v, using direct connection", serviceURL, name, err)
+		} else {
+			klog.Infof("Using k8s API proxy for %s in cluster %s", serviceURL, name)
+			rt = proxyTransport
+			proxied = true
+		}
+	}
+	if rt == nil {
+		rt = http.DefaultTransport
+		tlsConfig, err := prometheusTLSConfig(promConfig)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig != nil {
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = tlsConfig
+			rt = transport
+		}
+	}
+	return newPrometheusTransport(name, rt, promConfig, proxied)
+}
+
 func isClusterLocalURL(urlStr string) bool {
 	return strings.Contains(urlStr, ".svc.cluster.local") || strings.Contains(urlStr, ".svc:")
 }
@@ -340,10 +445,17 @@ func ImportClustersFromKubeconfig(kubeconfig *clientcmdapi.Config) int64 {
 }
 
 func processAuthInfo(authInfo *clientcmdapi.AuthInfo) (*clientcmdapi.AuthInfo, bool, bool) {
-	if strings.Contains(authInfo.Exec.Command, "glab") {
+	switch detectExecPlugin(authInfo.Exec.Command, authInfo.Exec.Args) {
+	case execPluginGlab:
 		return processGlabAuth(authInfo), true, true
-	} else if strings.Contains(authInfo.Exec.Command, "aws") || strings.Contains(authInfo.Exec.Command, "aws-iam-authenticator") {
+	case execPluginAWS:
 		return processAWSAuth(authInfo), true, true
+	case execPluginGKE:
+		return processGKEAuth(authInfo), true, true
+	case execPluginAzure:
+		return processAzureAuth(authInfo), true, true
+	case execPluginOIDC:
+		return processOIDCAuth(authInfo), true, true
 	}
 	return authInfo, false, false
 }
@@ -456,10 +568,18 @@ func syncClusters(cm *ClusterManager) error {
 	activeUserIDs := cm.getActiveUserIDs(now)
 	cm.activeUsersMu.RUnlock()
 
+	// The credentials of the users are only compared for user-level clusters
+	var credentials execCredentialsVersions
+	if len(activeUserIDs) > 0 && slices.ContainsFunc(clusters, func(c *model.Cluster) bool { return c.Enable && c.SkipSystemSync }) {
+		if credentials, err = loadExecCredentialsVersions(activeUserIDs); err != nil {
+			klog.Warningf("Failed to load user exec credentials: %v", err)
+		}
+	}
+
 	dbClusterMap := make(map[string]*model.Cluster)
 	for _, cluster := range clusters {
 		dbClusterMap[cluster.Name] = cluster
-		cm.updateClusterStatus(cluster, activeUserIDs, now)
+		cm.updateClusterStatus(cluster, activeUserIDs, credentials, now)
 	}
 
 	cm.cleanupDeletedClusters(dbClusterMap)
@@ -484,7 +604,7 @@ func (cm *ClusterManager) getActiveUserIDs(now time.Time) []uint {
 	return activeUserIDs
 }
 
-func (cm *ClusterManager) updateClusterStatus(cluster *model.Cluster, activeUserIDs []uint, now time.Time) {
+func (cm *ClusterManager) updateClusterStatus(cluster *model.Cluster, activeUserIDs []uint, credentials execCredentialsVersions, now time.Time) {
 	if !cluster.Enable || len(activeUserIDs) == 0 {
 		cm.stopClusterSync(cluster)
 		return
@@ -493,7 +613,7 @@ func (cm *ClusterManager) updateClusterStatus(cluster *model.Cluster, activeUser
 	if !cluster.SkipSystemSync {
 		cm.handleSharedSync(cluster)
 	} else {
-		cm.handleUserLevelSync(cluster, activeUserIDs, now)
+		cm.handleUserLevelSync(cluster, activeUserIDs, credentials, now)
 	}
 }
 
@@ -556,7 +676,7 @@ func (cm *ClusterManager) handleSharedSync(cluster *model.Cluster) {
 	}
 }
 
-func (cm *ClusterManager) handleUserLevelSync(cluster *model.Cluster, activeUserIDs []uint, now time.Time) {
+func (cm *ClusterManager) handleUserLevelSync(cluster *model.Cluster, activeUserIDs []uint, credentials execCredentialsVersions, now time.Time) {
 	cm.mu.Lock()
 	// Stop shared client if it was previously shared
 	if cs, ok := cm.clusters[cluster.Name]; ok {
@@ -581,7 +701,7 @@ func (cm *ClusterManager) handleUserLevelSync(cluster *model.Cluster, activeUser
 		cm.mu.RLock()
 		userMap := cm.userClients[cluster.Name]
 		uc, exists := userMap[userID]
-		needsUpdate := !exists || shouldUpdateUserClient(uc, cluster)
+		needsUpdate := !exists || shouldUpdateUserClient(uc, cluster, userID, credentials)
 		cm.mu.RUnlock()
 
 		if needsUpdate {
@@ -656,7 +776,9 @@ func (cm *ClusterManager) cleanupDeletedClusters(dbClusterMap map[string]*model.
 	}
 }
 
-func shouldUpdateUserClient(uc *UserClient, cluster *model.Cluster) bool {
+// shouldUpdateUserClient reports whether the client of a user is outdated.
+// credentials is nil when they could not be loaded, and is then not compared.
+func shouldUpdateUserClient(uc *UserClient, cluster *model.Cluster, userID uint, credentials execCredentialsVersions) bool {
 	if uc.ClientSet == nil {
 		return true // It had an error before
 	}
@@ -666,6 +788,19 @@ func shouldUpdateUserClient(uc *UserClient, cluster *model.Cluster) bool {
 	if uc.ClientSet.prometheusURL != cluster.PrometheusURL {
 		return true
 	}
+	if uc.ClientSet.prometheusConfig != prometheusConfigKey(cluster.PrometheusConfig) {
+		return true
+	}
+	if uc.ClientSet.alertmanagerURL != cluster.AlertmanagerURL {
+		return true
+	}
+	if uc.ClientSet.cachePolicy != cachePolicyKey(cluster.CachePolicy) {
+		return true
+	}
+	if credentials != nil && uc.ClientSet.execCredentialsVersion != credentials.version(uc.ClientSet.execPlugin, userID) {
+		klog.Infof("Exec credentials changed for user %d in cluster %s, updating", userID, cluster.Name)
+		return true
+	}
 	return false
 }
 
@@ -675,45 +810,35 @@ func buildUserClientSet(cluster *model.Cluster, user *model.User) (*UserClient,
 		return nil, err
 	}
 
-	userConfig, err := model.GetUserConfig(user.ID)
+	execPlugin, err := injectUserExecCredentials(restConfig, user)
 	if err != nil {
 		return nil, err
 	}
 
-	if restConfig.ExecProvider != nil {
-		if strings.Contains(restConfig.ExecProvider.Command, "glab") {
-			glabConfigDir, err := utils.GetUserGlabConfigDir(userConfig.StorageNamespace)
-			if err != nil {
-				return nil, err
-			}
-			restConfig.ExecProvider.Env = append(restConfig.ExecProvider.Env,
-				clientcmdapi.ExecEnvVar{Name: "GLAB_CONFIG_DIR", Value: glabConfigDir},
-			)
-		}
-
-		if strings.Contains(restConfig.ExecProvider.Command, "aws") || strings.Contains(restConfig.ExecProvider.Command, "aws-iam-authenticator") {
-			awsCredsPath := utils.GetUserAWSCredentialsPath(userConfig.StorageNamespace)
-			restConfig.ExecProvider.Env = append(restConfig.ExecProvider.Env,
-				clientcmdapi.ExecEnvVar{Name: "AWS_SHARED_CREDENTIALS_FILE", Value: awsCredsPath},
-			)
-		}
-	}
-
 	// Create new client with cache ENABLED (user wants sync)
 	k8sClient, err := kube.NewClient(kube.ClientOptions{
 		Config:       restConfig,
 		DisableCache: false,
+		Name:         cluster.Name,
+		UserClient:   true,
+		CachePolicy:  cluster.CachePolicy,
 	})
 	if err != nil {
 		return nil, err
 	}
 
 	cs := &ClientSet{
-		Name:          cluster.Name,
-		Configuration: restConfig,
-		prometheusURL: cluster.PrometheusURL,
-		K8sClient:     k8sClient,
-		config:        string(cluster.Config),
+		Name:             cluster.Name,
+		Configuration:    restConfig,
+		prometheusURL:    cluster.PrometheusURL,
+		prometheusConfig: prometheusConfigKey(cluster.PrometheusConfig),
+		alertmanagerURL:  cluster.AlertmanagerURL,
+		cachePolicy:      cachePolicyKey(cluster.CachePolicy),
+		K8sClient:        k8sClient,
+		config:           string(cluster.Config),
+
+		execPlugin:             execPlugin,
+		execCredentialsVersion: execCredentialsVersion(execPlugin, user.ID),
 	}
 
 	// Discovery and Prometheus discovery (optional, could be improved)
@@ -759,6 +884,24 @@ func shouldUpdateCluster(cs *ClientSet, cluster *model.Cluster) bool {
 		return true
 	}
 
+	// alertmanager URL change
+	if cs.alertmanagerURL != cluster.AlertmanagerURL {
+		klog.Infof("Alertmanager URL changed for cluster %s, updating", cluster.Name)
+		return true
+	}
+
+	// prometheus auth, headers or TLS change
+	if cs.prometheusConfig != prometheusConfigKey(cluster.PrometheusConfig) {
+		klog.Infof("Prometheus settings changed for cluster %s, updating", cluster.Name)
+		return true
+	}
+
+	// cache policy change
+	if cs.cachePolicy != cachePolicyKey(cluster.CachePolicy) {
+		klog.Infof("Cache policy changed for cluster %s, updating", cluster.Name)
+		return true
+	}
+
 	// k8s version change
 	// If SkipSystemSync is true, we skip the version check to avoid auth errors on user-only clusters
 	if cluster.SkipSystemSync {
@@ -780,9 +923,19 @@ func shouldUpdateCluster(cs *ClientSet, cluster *model.Cluster) bool {
 
 func buildClientSet(cluster *model.Cluster) (*ClientSet, error) {
 	if cluster.InCluster {
-		return createClientSetInCluster(cluster.Name, cluster.PrometheusURL, cluster.SkipSystemSync)
+		return createClientSetInCluster(cluster.Name, monitoringConfigOf(cluster), cluster.SkipSystemSync, cluster.CachePolicy)
 	}
-	return createClientSetFromConfig(cluster.Name, string(cluster.Config), cluster.PrometheusURL, cluster.SkipSystemSync)
+	return createClientSetFromConfig(cluster.Name, string(cluster.Config), monitoringConfigOf(cluster), cluster.SkipSystemSync, cluster.CachePolicy)
+}
+
+// cachePolicyKey returns a comparable form of a cluster cache policy.
+func cachePolicyKey(policy *common.CachePolicy) string {
+	if policy == nil {
+		return ""
+	}
+	value, _ := policy.Value()
+	s, _ := value.(string)
+	return s
 }
 
 func NewClusterManager() (*ClusterManager, error) {
@@ -837,6 +990,14 @@ func (cm *ClusterManager) UpdateUserActivity(userID uint) {
 	}
 }
 
+// TriggerSync requests an asynchronous reload of the clusters from the database.
+func TriggerSync() {
+	select {
+	case syncNow <- struct{}{}:
+	default:
+	}
+}
+
 func (cm *ClusterManager) startCleanupRoutine() {
 	ticker := time.NewTicker(5 * time.Minute) // Check every 5 minutes
 	defer ticker.Stop()
@@ -0,0 +1,303 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/utils"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Exec credential plugins that get per-user credentials injected.
+const (
+	execPluginNone  = ""
+	execPluginGlab  = "glab"
+	execPluginAWS   = "aws"
+	execPluginGKE   = "gke"
+	execPluginAzure = "azure"
+	execPluginOIDC  = "oidc"
+)
+
+// azureDeviceLoginTimeout bounds how long a started device code login waits
+// for the user to complete sign-in.
+const azureDeviceLoginTimeout = 10 * time.Minute
+
+// ErrAzureDeviceLoginPending is returned when the user already has a device
+// code login running for the cluster.
+var ErrAzureDeviceLoginPending = errors.New("a device login for this cluster is already pending")
+
+// azureDeviceLogins holds the pending device code logins by cluster and user.
+var azureDeviceLogins = struct {
+	sync.Mutex
+	pending map[string]struct{}
+}{pending: map[string]struct{}{}}
+
+// detectExecPlugin returns the managed exec plugin used by an exec config.
+func detectExecPlugin(command string, args []string) string {
+	switch {
+	case strings.Contains(command, "glab"):
+		return execPluginGlab
+	case strings.Contains(command, "gke-gcloud-auth-plugin"):
+		return execPluginGKE
+	case strings.Contains(command, "oidc-login") || strings.Contains(command, "oidc_login"),
+		slices.Contains(args, "oidc-login"),
+		hasExecArg(args, "--oidc-issuer-url"):
+		// int128/kubelogin may also be installed as "kubelogin", so it is
+		// told apart from Azure kubelogin by its --oidc-issuer-url flag.
+		return execPluginOIDC
+	case strings.Contains(command, "kubelogin"):
+		return execPluginAzure
+	case strings.Contains(command, "aws"):
+		return execPluginAWS
+	}
+	return execPluginNone
+}
+
+func processGKEAuth(authInfo *clientcmdapi.AuthInfo) *clientcmdapi.AuthInfo {
+	copiedAuthInfo := authInfo.DeepCopy()
+	copiedAuthInfo.Exec.Command = "gke-gcloud-auth-plugin"
+	// Use the per-user service account key instead of the gcloud login session
+	if !hasExecArg(copiedAuthInfo.Exec.Args, "--use_application_default_credentials") {
+		copiedAuthInfo.Exec.Args = append(copiedAuthInfo.Exec.Args, "--use_application_default_credentials")
+	}
+	return copiedAuthInfo
+}
+
+func processAzureAuth(authInfo *clientcmdapi.AuthInfo) *clientcmdapi.AuthInfo {
+	copiedAuthInfo := authInfo.DeepCopy()
+	copiedAuthInfo.Exec.Command = "kubelogin"
+	// Login mode and token cache are chosen per user when the client is built
+	copiedAuthInfo.Exec.Args = removeExecArg(copiedAuthInfo.Exec.Args, "--token-cache-dir")
+	return copiedAuthInfo
+}
+
+func processOIDCAuth(authInfo *clientcmdapi.AuthInfo) *clientcmdapi.AuthInfo {
+	copiedAuthInfo := authInfo.DeepCopy()
+	// Browser based grants cannot work on a server, the grant is chosen per user
+	for _, flag := range []string{"--grant-type", "--token-cache-dir", "--username", "--password", "--oidc-client-secret"} {
+		copiedAuthInfo.Exec.Args = removeExecArg(copiedAuthInfo.Exec.Args, flag)
+	}
+	return copiedAuthInfo
+}
+
+// injectUserExecCredentials points the exec plugin of restConfig at the user's
+// own credentials, or for OIDC replaces it with an in-process login. It
+// returns the detected plugin.
+func injectUserExecCredentials(restConfig *rest.Config, user *model.User) (string, error) {
+	if restConfig.ExecProvider == nil {
+		return execPluginNone, nil
+	}
+	execConfig := restConfig.ExecProvider
+	plugin := detectExecPlugin(execConfig.Command, execConfig.Args)
+	if plugin == execPluginNone {
+		return plugin, nil
+	}
+
+	userConfig, err := model.GetUserConfig(user.ID)
+	if err != nil {
+		return plugin, err
+	}
+
+	switch plugin {
+	case execPluginGlab:
+		glabConfigDir, err := utils.GetUserGlabConfigDir(userConfig.StorageNamespace)
+		if err != nil {
+			return plugin, err
+		}
+		execConfig.Env = append(execConfig.Env,
+			clientcmdapi.ExecEnvVar{Name: "GLAB_CONFIG_DIR", Value: glabConfigDir},
+		)
+
+	case execPluginAWS:
+		awsCredsPath := utils.GetUserAWSCredentialsPath(userConfig.StorageNamespace)
+		execConfig.Env = append(execConfig.Env,
+			clientcmdapi.ExecEnvVar{Name: "AWS_SHARED_CREDENTIALS_FILE", Value: awsCredsPath},
+		)
+
+	case execPluginGKE:
+		gcpCredsPath := utils.GetUserGCPCredentialsPath(userConfig.StorageNamespace)
+		execConfig.Env = append(execConfig.Env,
+			clientcmdapi.ExecEnvVar{Name: "GOOGLE_APPLICATION_CREDENTIALS", Value: gcpCredsPath},
+			clientcmdapi.ExecEnvVar{Name: "CLOUDSDK_CONFIG", Value: strings.TrimSuffix(gcpCredsPath, "/application_default_credentials.json")},
+		)
+
+	case execPluginAzure:
+		azureConfig, err := model.GetUserAzureConfig(user.ID)
+		if err != nil {
+			return plugin, fmt.Errorf("azure credentials are not configured for user %s", user.Key())
+		}
+		cacheDir, err := utils.GetUserKubeloginCacheDir(userConfig.StorageNamespace)
+		if err != nil {
+			return plugin, err
+		}
+		execConfig.Args = setExecArg(execConfig.Args, "--token-cache-dir", cacheDir)
+		execConfig.Args = setExecArg(execConfig.Args, "--login", azureConfig.LoginMode)
+		if azureConfig.TenantID != "" {
+			execConfig.Args = setExecArg(execConfig.Args, "--tenant-id", azureConfig.TenantID)
+		}
+		if azureConfig.LoginMode == model.AzureLoginModeSPN {
+			execConfig.Env = append(execConfig.Env,
+				clientcmdapi.ExecEnvVar{Name: "AAD_SERVICE_PRINCIPAL_CLIENT_ID", Value: azureConfig.ClientID},
+				clientcmdapi.ExecEnvVar{Name: "AAD_SERVICE_PRINCIPAL_CLIENT_SECRET", Value: string(azureConfig.ClientSecret)},
+			)
+		}
+
+	case execPluginOIDC:
+		oidcConfig, err := model.GetUserOIDCConfig(user.ID)
+		if err != nil {
+			return plugin, fmt.Errorf("OIDC credentials are not configured for user %s", user.Key())
+		}
+		if err := injectOIDCCredentials(restConfig, oidcConfig); err != nil {
+			return plugin, err
+		}
+	}
+	return plugin, nil
+}
+
+// execCredentialsVersion identifies the state of the credentials stored in the
+// database for plugins that receive them at client build time, so user clients
+// can be rebuilt after the user changes them. File based plugins pick up
+// changes on their own and have no version.
+func execCredentialsVersion(plugin string, userID uint) string {
+	switch plugin {
+	case execPluginAzure:
+		if cfg, err := model.GetUserAzureConfig(userID); err == nil {
+			return cfg.UpdatedAt.String()
+		}
+	case execPluginOIDC:
+		if cfg, err := model.GetUserOIDCConfig(userID); err == nil {
+			return cfg.UpdatedAt.String()
+		}
+	}
+	return ""
+}
+
+// execCredentialsVersions holds the execCredentialsVersion of the active users
+// by plugin, loaded once per sync.
+type execCredentialsVersions map[string]map[uint]string
+
+func loadExecCredentialsVersions(userIDs []uint) (execCredentialsVersions, error) {
+	azureConfigs, err := model.GetUserAzureConfigs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	oidcConfigs, err := model.GetUserOIDCConfigs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	versions := execCredentialsVersions{
+		execPluginAzure: make(map[uint]string, len(azureConfigs)),
+		execPluginOIDC:  make(map[uint]string, len(oidcConfigs)),
+	}
+	for _, cfg := range azureConfigs {
+		versions[execPluginAzure][cfg.UserID] = cfg.UpdatedAt.String()
+	}
+	for _, cfg := range oidcConfigs {
+		versions[execPluginOIDC][cfg.UserID] = cfg.UpdatedAt.String()
+	}
+	return versions, nil
+}
+
+func (v execCredentialsVersions) version(plugin string, userID uint) string {
+	return v[plugin][userID]
+}
+
+func hasExecArg(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag || strings.HasPrefix(arg, flag+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// removeExecArg drops every "--flag value" and "--flag=value" occurrence.
+func removeExecArg(args []string, flag string) []string {
+	result := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == flag {
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+			}
+			continue
+		}
+		if strings.HasPrefix(arg, flag+"=") {
+			continue
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+// setExecArg replaces any existing value of flag with value.
+func setExecArg(args []string, flag, value string) []string {
+	return append(removeExecArg(args, flag), flag+"="+value)
+}
+
+// AzureDeviceLogin starts a kubelogin device code flow for the user against the
+// given cluster and returns the sign-in instructions to show to the user.
+func AzureDeviceLogin(clusterName string, user *model.User) (string, error) {
+	cluster, err := model.GetClusterByName(clusterName)
+	if err != nil {
+		return "", err
+	}
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig([]byte(cluster.Config))
+	if err != nil {
+		return "", err
+	}
+	execConfig := restConfig.ExecProvider
+	if execConfig == nil || detectExecPlugin(execConfig.Command, execConfig.Args) != execPluginAzure {
+		return "", fmt.Errorf("cluster %s does not use Azure kubelogin", clusterName)
+	}
+
+	userConfig, err := model.GetUserConfig(user.ID)
+	if err != nil {
+		return "", err
+	}
+	cacheDir, err := utils.GetUserKubeloginCacheDir(userConfig.StorageNamespace)
+	if err != nil {
+		return "", err
+	}
+	args := setExecArg(execConfig.Args, "--token-cache-dir", cacheDir)
+	args = setExecArg(args, "--login", model.AzureLoginModeDeviceCode)
+	if azureConfig, err := model.GetUserAzureConfig(user.ID); err == nil && azureConfig.TenantID != "" {
+		args = setExecArg(args, "--tenant-id", azureConfig.TenantID)
+	}
+	env := make([]string, 0, len(execConfig.Env))
+	for _, e := range execConfig.Env {
+		env = append(env, e.Name+"="+e.Value)
+	}
+
+	key := fmt.Sprintf("%s/%d", clusterName, user.ID)
+	azureDeviceLogins.Lock()
+	if _, ok := azureDeviceLogins.pending[key]; ok {
+		azureDeviceLogins.Unlock()
+		return "", ErrAzureDeviceLoginPending
+	}
+	azureDeviceLogins.pending[key] = struct{}{}
+	azureDeviceLogins.Unlock()
+	release := func() {
+		azureDeviceLogins.Lock()
+		delete(azureDeviceLogins.pending, key)
+		azureDeviceLogins.Unlock()
+	}
+
+	message, done, err := utils.KubeloginDeviceCodeLogin(args, env, azureDeviceLoginTimeout)
+	if done == nil {
+		release()
+		return "", err
+	}
+	go func() {
+		<-done
+		release()
+	}()
+	return message, err
+}
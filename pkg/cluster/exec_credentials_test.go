@@ -0,0 +1,104 @@
+package cluster
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestDetectExecPlugin(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		args     []string
+		expected string
+	}{
+		{"glab", "glab", []string{"auth", "token"}, execPluginGlab},
+		{"aws cli", "aws", []string{"eks", "get-token"}, execPluginAWS},
+		{"aws iam authenticator", "aws-iam-authenticator", []string{"token"}, execPluginAWS},
+		{"gke", "/usr/bin/gke-gcloud-auth-plugin", nil, execPluginGKE},
+		{"azure kubelogin", "kubelogin", []string{"get-token", "--server-id", "abc"}, execPluginAzure},
+		{"kubectl oidc-login", "kubectl", []string{"oidc-login", "get-token", "--oidc-issuer-url=https://idp"}, execPluginOIDC},
+		{"oidc kubelogin", "kubelogin", []string{"get-token", "--oidc-issuer-url", "https://idp"}, execPluginOIDC},
+		{"unknown", "my-plugin", nil, execPluginNone},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, detectExecPlugin(tc.command, tc.args))
+		})
+	}
+}
+
+func TestSetExecArg(t *testing.T) {
+	args := []string{"get-token", "--login", "azurecli", "--server-id=abc", "--token-cache-dir=/tmp/x"}
+	args = setExecArg(args, "--login", "spn")
+	args = setExecArg(args, "--token-cache-dir", "/data/cache")
+	assert.Equal(t, []string{"get-token", "--server-id=abc", "--login=spn", "--token-cache-dir=/data/cache"}, args)
+}
+
+func TestProcessExecAuth(t *testing.T) {
+	gke := processGKEAuth(&clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: "/google/bin/gke-gcloud-auth-plugin"}})
+	assert.Equal(t, "gke-gcloud-auth-plugin", gke.Exec.Command)
+	assert.Equal(t, []string{"--use_application_default_credentials"}, gke.Exec.Args)
+
+	oidc := processOIDCAuth(&clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+		Command: "kubectl",
+		Args:    []string{"oidc-login", "get-token", "--oidc-issuer-url=https://idp", "--grant-type", "browser", "--oidc-client-secret=s3cr3t"},
+	}})
+	assert.Equal(t, []string{"oidc-login", "get-token", "--oidc-issuer-url=https://idp"}, oidc.Exec.Args)
+}
+
+func TestInjectOIDCCredentials(t *testing.T) {
+	var issuer *httptest.Server
+	idToken := "e30." + base64.RawURLEncoding.EncodeToString([]byte(`{"exp":4102444800}`)) + ".sig"
+	issuer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(map[string]string{"token_endpoint": issuer.URL + "/token"})
+		case "/token":
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "password", r.Form.Get("grant_type"))
+			assert.Equal(t, "alice", r.Form.Get("username"))
+			assert.Equal(t, "pa55", r.Form.Get("password"))
+			assert.Equal(t, "openid groups", r.Form.Get("scope"))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "a", "id_token": idToken, "expires_in": 60})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer issuer.Close()
+
+	restConfig := &rest.Config{ExecProvider: &clientcmdapi.ExecConfig{
+		Command: "kubectl",
+		Args:    []string{"oidc-login", "get-token", "--oidc-issuer-url=" + issuer.URL, "--oidc-client-id", "k8s", "--oidc-extra-scope=groups"},
+	}}
+	err := injectOIDCCredentials(restConfig, &model.UserOIDCConfig{Username: "alice", Password: "pa55"})
+	require.NoError(t, err)
+	assert.Nil(t, restConfig.ExecProvider, "no plugin process gets the credentials")
+
+	var authorization string
+	rt := restConfig.WrapTransport(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		authorization = r.Header.Get("Authorization")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}))
+	req, _ := http.NewRequest(http.MethodGet, "https://api.example.com", nil)
+	_, err = rt.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer "+idToken, authorization)
+
+	err = injectOIDCCredentials(&rest.Config{ExecProvider: &clientcmdapi.ExecConfig{Args: []string{"oidc-login"}}}, &model.UserOIDCConfig{})
+	assert.Error(t, err)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
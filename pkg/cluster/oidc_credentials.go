@@ -0,0 +1,194 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"golang.org/x/oauth2"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+)
+
+// oidcRequestTimeout bounds the discovery and token requests to the issuer.
+const oidcRequestTimeout = 30 * time.Second
+
+// injectOIDCCredentials replaces the kubectl oidc-login exec plugin of
+// restConfig with an in-process password grant. The plugin only takes the
+// password and client secret as arguments, which any local user could read
+// from the process list. The issuer, client ID, scopes and CA are kept from
+// the plugin arguments.
+func injectOIDCCredentials(restConfig *rest.Config, oidcConfig *model.UserOIDCConfig) error {
+	args := restConfig.ExecProvider.Args
+	issuer := execArgValues(args, "--oidc-issuer-url")
+	clientID := execArgValues(args, "--oidc-client-id")
+	if len(issuer) == 0 || len(clientID) == 0 {
+		return fmt.Errorf("the oidc-login plugin needs --oidc-issuer-url and --oidc-client-id")
+	}
+	httpClient, err := oidcHTTPClient(args)
+	if err != nil {
+		return err
+	}
+
+	source := &oidcTokenSource{
+		issuer:     issuer[0],
+		httpClient: httpClient,
+		username:   oidcConfig.Username,
+		password:   string(oidcConfig.Password),
+		config: oauth2.Config{
+			ClientID:     clientID[0],
+			ClientSecret: string(oidcConfig.ClientSecret),
+			Scopes:       append([]string{"openid"}, execArgValues(args, "--oidc-extra-scope")...),
+		},
+	}
+	restConfig.ExecProvider = nil
+	restConfig.WrapTransport = transport.TokenSourceWrapTransport(oauth2.ReuseTokenSource(nil, source))
+	return nil
+}
+
+// oidcHTTPClient returns the client for requests to the issuer, trusting the
+// CA given to the plugin.
+func oidcHTTPClient(args []string) (*http.Client, error) {
+	tlsConfig := &tls.Config{}
+	if insecure := execArgValues(args, "--insecure-skip-tls-verify"); len(insecure) > 0 && insecure[0] != "false" {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	if files := execArgValues(args, "--certificate-authority"); len(files) > 0 {
+		pool := x509.NewCertPool()
+		for _, file := range files {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read OIDC certificate authority: %w", err)
+			}
+			pool.AppendCertsFromPEM(pem)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: base, Timeout: oidcRequestTimeout}, nil
+}
+
+// oidcTokenSource gets ID tokens with the resource owner password grant and
+// refreshes them with the refresh token when the issuer returns one.
+type oidcTokenSource struct {
+	issuer     string
+	httpClient *http.Client
+	username   string
+	password   string
+	config     oauth2.Config
+
+	// token is the last token from the issuer, used for refreshes
+	token *oauth2.Token
+}
+
+// Token returns the ID token as the access token, because the API server
+// authenticates OIDC users by their ID token.
+func (s *oidcTokenSource) Token() (*oauth2.Token, error) {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, s.httpClient)
+	if s.config.Endpoint.TokenURL == "" {
+		tokenURL, err := s.discoverTokenURL(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.config.Endpoint = oauth2.Endpoint{TokenURL: tokenURL}
+	}
+
+	var token *oauth2.Token
+	var err error
+	if s.token != nil && s.token.RefreshToken != "" {
+		token, err = s.config.TokenSource(ctx, &oauth2.Token{RefreshToken: s.token.RefreshToken}).Token()
+	}
+	if token == nil || err != nil {
+		token, err = s.config.PasswordCredentialsToken(ctx, s.username, s.password)
+		if err != nil {
+			return nil, fmt.Errorf("OIDC password grant failed: %w", err)
+		}
+	}
+	s.token = token
+
+	idToken, _ := token.Extra("id_token").(string)
+	if idToken == "" {
+		return nil, fmt.Errorf("the OIDC issuer returned no id_token")
+	}
+	expiry := token.Expiry
+	if exp, ok := idTokenExpiry(idToken); ok {
+		expiry = exp
+	}
+	return &oauth2.Token{AccessToken: idToken, TokenType: "Bearer", Expiry: expiry}, nil
+}
+
+func (s *oidcTokenSource) discoverTokenURL(ctx context.Context) (string, error) {
+	url := strings.TrimSuffix(s.issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OIDC discovery failed: %s returned %s", url, resp.Status)
+	}
+	var discovery struct {
+		TokenEndpoint string `json:"token_endpoint"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return "", fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if discovery.TokenEndpoint == "" {
+		return "", fmt.Errorf("OIDC discovery failed: %s has no token_endpoint", url)
+	}
+	return discovery.TokenEndpoint, nil
+}
+
+// idTokenExpiry reads the exp claim of a JWT without verifying it; the API
+// server verifies the token.
+func idTokenExpiry(idToken string) (time.Time, bool) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+// execArgValues returns the values of every "--flag value" and "--flag=value"
+// occurrence. A flag without a value yields "true".
+func execArgValues(args []string, flag string) []string {
+	var values []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == flag:
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				values = append(values, args[i+1])
+				i++
+			} else {
+				values = append(values, "true")
+			}
+		case strings.HasPrefix(arg, flag+"="):
+			values = append(values, strings.TrimPrefix(arg, flag+"="))
+		}
+	}
+	return values
+}
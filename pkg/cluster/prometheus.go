@@ -2,11 +2,21 @@ package cluster
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"golang.org/x/net/http/httpguts"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -44,3 +54,168 @@ func discoveryPrometheusURL(kc *kube.K8sClient) string {
 	}
 	return ""
 }
+
+var alertmanagerDiscoveryLabels = []client.MatchingLabels{
+	{
+		"app.kubernetes.io/name": "alertmanager",
+	},
+	{
+		"app": "alertmanager",
+	},
+	{
+		"app.kubernetes.io/name": "vmalertmanager",
+	},
+}
+
+func discoveryAlertmanagerURL(kc *kube.K8sClient) string {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	for _, matchLabels := range alertmanagerDiscoveryLabels {
+		var svcList corev1.ServiceList
+		if err := kc.List(ctx, &svcList, matchLabels); err != nil {
+			continue
+		}
+		for _, svc := range svcList.Items {
+			// Skip headless services such as alertmanager-operated
+			if svc.Spec.Type != corev1.ServiceTypeClusterIP && svc.Spec.Type != corev1.ServiceTypeLoadBalancer || svc.Spec.ClusterIP == corev1.ClusterIPNone {
+				continue
+			}
+			for _, port := range svc.Spec.Ports {
+				if port.Name == "http-web" || port.Name == "web" || port.Port == 9093 || len(svc.Spec.Ports) == 1 {
+					return fmt.Sprintf("http://%s.%s.svc.cluster.local:%d", svc.Name, svc.Namespace, port.Port)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// prometheusTLSConfig builds the TLS configuration of a direct Prometheus
+// connection. It returns nil when no TLS settings are configured.
+func prometheusTLSConfig(cfg *model.PrometheusConfig) (*tls.Config, error) {
+	if cfg == nil || cfg.TLS == nil {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.TLS.ServerName,
+		InsecureSkipVerify: cfg.TLS.InsecureSkipVerify, //nolint:gosec // explicitly configured per cluster
+	}
+	if cfg.TLS.CAData != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(cfg.TLS.CAData)) {
+			return nil, fmt.Errorf("invalid CA bundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.TLS.CertData != "" || cfg.TLS.KeyData != "" {
+		cert, err := tls.X509KeyPair([]byte(cfg.TLS.CertData), []byte(cfg.TLS.KeyData))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// validatePrometheusConfig checks the settings before they are saved.
+func validatePrometheusConfig(cfg *model.PrometheusConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	switch cfg.AuthType {
+	case "":
+	case model.PrometheusAuthBasic:
+		if cfg.Username == "" {
+			return fmt.Errorf("prometheus basic auth requires a username")
+		}
+	case model.PrometheusAuthBearer:
+		if cfg.BearerToken == "" {
+			return fmt.Errorf("prometheus bearer auth requires a token")
+		}
+	case model.PrometheusAuthOAuth2:
+		if cfg.OAuth2 == nil || cfg.OAuth2.ClientID == "" || cfg.OAuth2.ClientSecret == "" || cfg.OAuth2.TokenURL == "" {
+			return fmt.Errorf("prometheus oauth2 auth requires clientId, clientSecret and tokenUrl")
+		}
+	default:
+		return fmt.Errorf("invalid prometheus authType %q, must be basic, bearer or oauth2", cfg.AuthType)
+	}
+	for name := range cfg.Headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return fmt.Errorf("invalid prometheus header name %q", name)
+		}
+	}
+	_, err := prometheusTLSConfig(cfg)
+	return err
+}
+
+// newPrometheusTransport adds the authentication and headers of cfg to base.
+// When proxied through the Kubernetes API server, the API server consumes the
+// Authorization header, so only the custom headers are sent.
+func newPrometheusTransport(name string, base http.RoundTripper, cfg *model.PrometheusConfig, proxied bool) (http.RoundTripper, error) {
+	if cfg.IsZero() {
+		return base, nil
+	}
+	rt := base
+	if cfg.AuthType != "" && proxied {
+		klog.Warningf("Prometheus %s auth for cluster %s is not sent through the API server proxy, use a directly reachable URL", cfg.AuthType, name)
+	} else {
+		switch cfg.AuthType {
+		case model.PrometheusAuthBasic:
+			rt = &prometheusAuthTransport{base: rt, basicUser: cfg.Username, basicPassword: cfg.Password}
+		case model.PrometheusAuthBearer:
+			rt = &prometheusAuthTransport{base: rt, bearerToken: cfg.BearerToken}
+		case model.PrometheusAuthOAuth2:
+			cc := clientcredentials.Config{
+				ClientID:     cfg.OAuth2.ClientID,
+				ClientSecret: cfg.OAuth2.ClientSecret,
+				TokenURL:     cfg.OAuth2.TokenURL,
+				Scopes:       cfg.OAuth2.Scopes,
+			}
+			if len(cfg.OAuth2.EndpointParams) > 0 {
+				cc.EndpointParams = url.Values{}
+				for k, v := range cfg.OAuth2.EndpointParams {
+					cc.EndpointParams.Set(k, v)
+				}
+			}
+			// The token endpoint is fetched directly, never through the API server proxy
+			ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: 30 * time.Second})
+			rt = &oauth2.Transport{Source: cc.TokenSource(ctx), Base: rt}
+		}
+	}
+	if len(cfg.Headers) > 0 {
+		rt = &prometheusAuthTransport{base: rt, headers: cfg.Headers}
+	}
+	return rt, nil
+}
+
+// prometheusAuthTransport sets static credentials and headers on requests.
+type prometheusAuthTransport struct {
+	base          http.RoundTripper
+	basicUser     string
+	basicPassword string
+	bearerToken   string
+	headers       map[string]string
+}
+
+func (t *prometheusAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	switch {
+	case t.basicUser != "":
+		req.SetBasicAuth(t.basicUser, t.basicPassword)
+	case t.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+t.bearerToken)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// prometheusConfigKey returns a comparable form of the Prometheus settings.
+func prometheusConfigKey(cfg *model.PrometheusConfig) string {
+	if cfg.IsZero() {
+		return ""
+	}
+	data, _ := json.Marshal(cfg)
+	return string(data)
+}
@@ -0,0 +1,86 @@
+package cluster
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPrometheusTransport(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	do := func(cfg *model.PrometheusConfig, proxied bool) http.Header {
+		rt, err := newPrometheusTransport("test", http.DefaultTransport, cfg, proxied)
+		require.NoError(t, err)
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		resp, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return got
+	}
+
+	h := do(&model.PrometheusConfig{AuthType: model.PrometheusAuthBasic, Username: "u", Password: "p"}, false)
+	assert.Equal(t, "Basic dTpw", h.Get("Authorization"))
+
+	h = do(&model.PrometheusConfig{
+		AuthType:    model.PrometheusAuthBearer,
+		BearerToken: "token",
+		Headers:     map[string]string{"X-Scope-OrgID": "tenant-a"},
+	}, false)
+	assert.Equal(t, "Bearer token", h.Get("Authorization"))
+	assert.Equal(t, "tenant-a", h.Get("X-Scope-OrgID"))
+
+	// Through the API server proxy only the custom headers are sent
+	h = do(&model.PrometheusConfig{
+		AuthType:    model.PrometheusAuthBearer,
+		BearerToken: "token",
+		Headers:     map[string]string{"X-Scope-OrgID": "tenant-a"},
+	}, true)
+	assert.Empty(t, h.Get("Authorization"))
+	assert.Equal(t, "tenant-a", h.Get("X-Scope-OrgID"))
+}
+
+func TestNewPrometheusTransportOAuth2(t *testing.T) {
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"oauth-token","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer tokenSrv.Close()
+
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	rt, err := newPrometheusTransport("test", http.DefaultTransport, &model.PrometheusConfig{
+		AuthType: model.PrometheusAuthOAuth2,
+		OAuth2:   &model.PrometheusOAuth2{ClientID: "id", ClientSecret: "secret", TokenURL: tokenSrv.URL},
+	}, false)
+	require.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, "Bearer oauth-token", auth)
+}
+
+func TestValidatePrometheusConfig(t *testing.T) {
+	assert.NoError(t, validatePrometheusConfig(nil))
+	assert.NoError(t, validatePrometheusConfig(&model.PrometheusConfig{Headers: map[string]string{"X-Scope-OrgID": "a"}}))
+	assert.Error(t, validatePrometheusConfig(&model.PrometheusConfig{AuthType: "digest"}))
+	assert.Error(t, validatePrometheusConfig(&model.PrometheusConfig{AuthType: model.PrometheusAuthBearer}))
+	assert.Error(t, validatePrometheusConfig(&model.PrometheusConfig{AuthType: model.PrometheusAuthOAuth2, OAuth2: &model.PrometheusOAuth2{ClientID: "id"}}))
+	assert.Error(t, validatePrometheusConfig(&model.PrometheusConfig{Headers: map[string]string{"bad header": "a"}}))
+	assert.Error(t, validatePrometheusConfig(&model.PrometheusConfig{TLS: &model.PrometheusTLS{CAData: "not a pem"}}))
+}
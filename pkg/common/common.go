@@ -48,6 +48,9 @@ var (
 
 	APIKeyProvider = "api_key"
 
+	// ConfigFile is the path of the declarative configuration file
+	ConfigFile = ""
+
 	AllowedOrigins []string
 )
 
@@ -141,6 +144,12 @@ func LoadEnvs() {
 		klog.Warning("INSECURE_SKIP_VERIFY is set to true, SSL certificate verification will be skipped")
 	}
 
+	if v := os.Getenv("KUBE_SENTINEL_CONFIG"); v != "" {
+		ConfigFile = v
+	} else if _, err := os.Stat("kube-sentinel.yaml"); err == nil {
+		ConfigFile = "kube-sentinel.yaml"
+	}
+
 	if v := os.Getenv("ALLOWED_ORIGINS"); v != "" {
 		AllowedOrigins = strings.Split(v, ",")
 		for i := range AllowedOrigins {
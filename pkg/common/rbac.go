@@ -19,6 +19,9 @@ type Role struct {
 	Resources   []string `yaml:"resources" json:"resources"`
 	Namespaces  []string `yaml:"namespaces" json:"namespaces"`
 	Verbs       []string `yaml:"verbs" json:"verbs"`
+	// ClusterSelector is a label selector matched against cluster labels.
+	// A role applies to a cluster if either Clusters or ClusterSelector matches.
+	ClusterSelector string `yaml:"clusterSelector,omitempty" json:"clusterSelector,omitempty"`
 }
 
 type RoleMapping struct {
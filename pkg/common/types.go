@@ -1,6 +1,12 @@
 package common
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -42,11 +48,12 @@ type ImportClustersRequest struct {
 }
 
 type ClusterInfo struct {
-	Name           string `json:"name"`
-	Version        string `json:"version"`
-	IsDefault      bool   `json:"isDefault"`
-	Error          string `json:"error,omitempty"`
-	SkipSystemSync bool   `json:"skipSystemSync"`
+	Name           string            `json:"name"`
+	Version        string            `json:"version"`
+	IsDefault      bool              `json:"isDefault"`
+	Error          string            `json:"error,omitempty"`
+	SkipSystemSync bool              `json:"skipSystemSync"`
+	Labels         map[string]string `json:"labels,omitempty"`
 }
 
 type MetricsCell struct {
@@ -73,3 +80,69 @@ type NodeListWithMetrics struct {
 	// +optional
 	metav1.ListMeta `json:"metadata" protobuf:"bytes,1,opt,name=metadata"`
 }
+
+// CachePolicy controls which kinds a cluster client keeps in its informer
+// cache and how cached objects are trimmed. Kinds are written as "Kind" (any
+// group) or "Kind.group", e.g. "Secret", "Event.events.k8s.io" or "Deployment.apps".
+type CachePolicy struct {
+	// Cached, when not empty, is the only set of kinds that may be cached.
+	Cached []string `json:"cached,omitempty"`
+	// Uncached kinds are always read live from the API server.
+	Uncached []string `json:"uncached,omitempty"`
+	// StripManagedFields drops metadata.managedFields before caching.
+	StripManagedFields bool `json:"stripManagedFields,omitempty"`
+	// MaxAnnotationBytes drops annotations with larger values before caching.
+	// Zero keeps all annotations.
+	MaxAnnotationBytes int `json:"maxAnnotationBytes,omitempty"`
+}
+
+// DefaultCachePolicy keeps Secrets and Events out of the cache. They are
+// numerous or sensitive and rarely worth holding in memory.
+func DefaultCachePolicy() CachePolicy {
+	return CachePolicy{
+		Uncached: []string{"Secret", "Event"},
+	}
+}
+
+// WithDefaults returns p with the uncached kinds of DefaultCachePolicy added,
+// except those p caches explicitly, e.g. with Cached: ["Secret"].
+func (p CachePolicy) WithDefaults() CachePolicy {
+	merged := p
+	merged.Uncached = slices.Clone(p.Uncached)
+	for _, kind := range DefaultCachePolicy().Uncached {
+		optedIn := slices.ContainsFunc(p.Cached, func(cached string) bool {
+			cachedKind, _, _ := strings.Cut(strings.TrimSpace(cached), ".")
+			return strings.EqualFold(cachedKind, kind)
+		})
+		if !optedIn && !slices.Contains(merged.Uncached, kind) {
+			merged.Uncached = append(merged.Uncached, kind)
+		}
+	}
+	return merged
+}
+
+func (p *CachePolicy) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into CachePolicy", value)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, p)
+}
+
+func (p CachePolicy) Value() (driver.Value, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
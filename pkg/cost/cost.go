@@ -0,0 +1,250 @@
+// Package cost allocates the cost of a cluster to namespaces, workloads and
+// labels from the resources the pods request or use.
+package cost
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// HoursPerMonth is the average number of hours in a month.
+const HoursPerMonth = 730
+
+const gib = 1024 * 1024 * 1024
+
+// Basis of the CPU and memory cost
+const (
+	// BasisRequests charges the resource requests of the pods.
+	BasisRequests = "requests"
+	// BasisUsage charges the larger of the requests and the average usage.
+	BasisUsage = "usage"
+)
+
+// Group by options of a report
+const (
+	GroupByNamespace = "namespace"
+	GroupByWorkload  = "workload"
+	GroupByLabel     = "label"
+)
+
+// Unallocated is the group of items that do not have the label of a report.
+const Unallocated = "__unallocated__"
+
+// Item is the resources of one pod, volume claim or load balancer.
+type Item struct {
+	Namespace string
+	// Workload is "Kind/name" of the controller of the pod, or of the pod
+	// itself. Claims take the workload of a pod mounting them.
+	Workload string
+	Labels   map[string]string
+
+	CPUCores      float64
+	MemoryGiB     float64
+	StorageGiB    float64
+	LoadBalancers float64
+}
+
+// Allocation is the cost of a namespace, workload or label value over a
+// number of hours: the projected month of its current resources, or the
+// average resources of a past month.
+type Allocation struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+
+	CPUCores      float64 `json:"cpuCores"`
+	MemoryGiB     float64 `json:"memoryGiB"`
+	StorageGiB    float64 `json:"storageGiB"`
+	LoadBalancers float64 `json:"loadBalancers"`
+
+	CPUCost          float64 `json:"cpuCost"`
+	MemoryCost       float64 `json:"memoryCost"`
+	StorageCost      float64 `json:"storageCost"`
+	LoadBalancerCost float64 `json:"loadBalancerCost"`
+	TotalCost        float64 `json:"totalCost"`
+}
+
+// Items returns the cost items of pods, claims and services. usage, keyed by
+// "namespace/pod", is only used with BasisUsage.
+func Items(pods []corev1.Pod, pvcs []corev1.PersistentVolumeClaim, services []corev1.Service, usage map[string]prometheus.PodUsage, basis string) []Item {
+	items := make([]Item, 0, len(pods)+len(pvcs)+len(services))
+	claimWorkloads := map[string]string{}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		item := Item{Namespace: pod.Namespace, Workload: podWorkload(&pod), Labels: pod.Labels}
+		var memory float64
+		for _, container := range pod.Spec.Containers {
+			item.CPUCores += float64(container.Resources.Requests.Cpu().MilliValue()) / 1000.0
+			memory += float64(container.Resources.Requests.Memory().Value())
+		}
+		if u, ok := usage[pod.Namespace+"/"+pod.Name]; ok && basis == BasisUsage {
+			item.CPUCores = max(item.CPUCores, u.CPU)
+			memory = max(memory, u.Memory)
+		}
+		item.MemoryGiB = memory / gib
+		items = append(items, item)
+
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				claimWorkloads[pod.Namespace+"/"+volume.PersistentVolumeClaim.ClaimName] = item.Workload
+			}
+		}
+	}
+
+	for _, pvc := range pvcs {
+		capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]
+		if !ok {
+			capacity = pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		}
+		workload, ok := claimWorkloads[pvc.Namespace+"/"+pvc.Name]
+		if !ok {
+			workload = "PersistentVolumeClaim/" + pvc.Name
+		}
+		items = append(items, Item{
+			Namespace:  pvc.Namespace,
+			Workload:   workload,
+			Labels:     pvc.Labels,
+			StorageGiB: float64(capacity.Value()) / gib,
+		})
+	}
+
+	for _, svc := range services {
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		items = append(items, Item{
+			Namespace:     svc.Namespace,
+			Workload:      "Service/" + svc.Name,
+			Labels:        svc.Labels,
+			LoadBalancers: 1,
+		})
+	}
+	return items
+}
+
+// PeriodItems returns the cost items of the average resources of a period.
+// Claims take the workload and labels of a pod that mounted them.
+func PeriodItems(usage *prometheus.PeriodUsage, basis string) []Item {
+	items := make([]Item, 0, len(usage.Pods)+len(usage.Claims)+len(usage.LoadBalancers))
+	for _, pod := range usage.Pods {
+		cpu, memory := pod.Requests.CPU, pod.Requests.Memory
+		if basis == BasisUsage {
+			cpu, memory = max(cpu, pod.Usage.CPU), max(memory, pod.Usage.Memory)
+		}
+		items = append(items, Item{
+			Namespace: pod.Namespace,
+			Workload:  pod.Workload,
+			Labels:    pod.Labels,
+			CPUCores:  cpu,
+			MemoryGiB: memory / gib,
+		})
+	}
+
+	for key, bytes := range usage.Claims {
+		namespace, name, _ := strings.Cut(key, "/")
+		item := Item{Namespace: namespace, Workload: "PersistentVolumeClaim/" + name, StorageGiB: bytes / gib}
+		if pod, ok := usage.Pods[namespace+"/"+usage.ClaimPods[key]]; ok {
+			item.Workload, item.Labels = pod.Workload, pod.Labels
+		}
+		items = append(items, item)
+	}
+
+	for key, count := range usage.LoadBalancers {
+		namespace, name, _ := strings.Cut(key, "/")
+		items = append(items, Item{Namespace: namespace, Workload: "Service/" + name, LoadBalancers: count})
+	}
+	return items
+}
+
+// podWorkload returns "Kind/name" of the controller of a pod. Pods of a
+// ReplicaSet are attributed to its Deployment.
+func podWorkload(pod *corev1.Pod) string {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller == nil || !*ref.Controller {
+			continue
+		}
+		if ref.Kind == "ReplicaSet" {
+			if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; hash != "" && strings.HasSuffix(ref.Name, "-"+hash) {
+				return "Deployment/" + strings.TrimSuffix(ref.Name, "-"+hash)
+			}
+		}
+		return ref.Kind + "/" + ref.Name
+	}
+	return "Pod/" + pod.Name
+}
+
+// Allocate groups items by namespace, by workload or by the value of label
+// and prices them over hours. The result is sorted by total cost, highest
+// first.
+func Allocate(items []Item, pricing model.CostPricing, groupBy, label string, hours float64) []Allocation {
+	groups := map[string]*Allocation{}
+	for _, item := range items {
+		var key string
+		a := Allocation{}
+		switch groupBy {
+		case GroupByWorkload:
+			key = item.Namespace + "/" + item.Workload
+			a.Name, a.Namespace = item.Workload, item.Namespace
+		case GroupByLabel:
+			a.Name = item.Labels[label]
+			if a.Name == "" {
+				a.Name = Unallocated
+			}
+			key = a.Name
+		default:
+			key = item.Namespace
+			a.Name = item.Namespace
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &a
+			groups[key] = group
+		}
+		group.CPUCores += item.CPUCores
+		group.MemoryGiB += item.MemoryGiB
+		group.StorageGiB += item.StorageGiB
+		group.LoadBalancers += item.LoadBalancers
+	}
+
+	result := make([]Allocation, 0, len(groups))
+	for _, group := range groups {
+		group.price(pricing, hours)
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalCost != result[j].TotalCost {
+			return result[i].TotalCost > result[j].TotalCost
+		}
+		return result[i].Namespace+"/"+result[i].Name < result[j].Namespace+"/"+result[j].Name
+	})
+	return result
+}
+
+// Total sums allocations into one named "total".
+func Total(allocations []Allocation, pricing model.CostPricing, hours float64) Allocation {
+	total := Allocation{Name: "total"}
+	for _, a := range allocations {
+		total.CPUCores += a.CPUCores
+		total.MemoryGiB += a.MemoryGiB
+		total.StorageGiB += a.StorageGiB
+		total.LoadBalancers += a.LoadBalancers
+	}
+	total.price(pricing, hours)
+	return total
+}
+
+// price charges hourly prices for hours and monthly prices prorated by
+// HoursPerMonth.
+func (a *Allocation) price(pricing model.CostPricing, hours float64) {
+	a.CPUCost = a.CPUCores * pricing.CPUCoreHour * hours
+	a.MemoryCost = a.MemoryGiB * pricing.MemoryGiBHour * hours
+	a.StorageCost = a.StorageGiB * pricing.StorageGiBMonth * hours / HoursPerMonth
+	a.LoadBalancerCost = a.LoadBalancers * pricing.LoadBalancerMonth * hours / HoursPerMonth
+	a.TotalCost = a.CPUCost + a.MemoryCost + a.StorageCost + a.LoadBalancerCost
+}
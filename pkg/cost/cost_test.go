@@ -0,0 +1,132 @@
+package cost
+
+import (
+	"testing"
+
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func testPod(namespace, name, owner, cpu, memory string, labels map[string]string) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app",
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			}},
+		}}},
+	}
+	if owner != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: owner, Controller: ptr.To(true)}}
+	}
+	return pod
+}
+
+func TestAllocate(t *testing.T) {
+	pods := []corev1.Pod{
+		testPod("shop", "api-7d9f-abcde", "api-7d9f", "500m", "1Gi", map[string]string{"team": "web", "pod-template-hash": "7d9f"}),
+		testPod("shop", "api-7d9f-fghij", "api-7d9f", "500m", "1Gi", map[string]string{"team": "web", "pod-template-hash": "7d9f"}),
+		testPod("db", "postgres-0", "", "2", "4Gi", nil),
+	}
+	pods[2].Spec.Volumes = []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{
+		PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data-postgres-0"},
+	}}}
+	finished := testPod("shop", "migrate", "", "4", "8Gi", nil)
+	finished.Status.Phase = corev1.PodSucceeded
+	pods = append(pods, finished)
+
+	pvcs := []corev1.PersistentVolumeClaim{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "db", Name: "data-postgres-0"},
+		Status:     corev1.PersistentVolumeClaimStatus{Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("100Gi")}},
+	}}
+	services := []corev1.Service{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "api", Labels: map[string]string{"team": "web"}}, Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "internal"}, Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}},
+	}
+	pricing := model.CostPricing{CPUCoreHour: 0.01, MemoryGiBHour: 0.001, StorageGiBMonth: 0.1, LoadBalancerMonth: 20}
+
+	items := Items(pods, pvcs, services, nil, BasisRequests)
+	require.Len(t, items, 5)
+
+	byNamespace := Allocate(items, pricing, GroupByNamespace, "", HoursPerMonth)
+	require.Len(t, byNamespace, 2)
+	assert.Equal(t, "shop", byNamespace[0].Name)
+	assert.InDelta(t, 1.0, byNamespace[0].CPUCores, 1e-9)
+	assert.InDelta(t, 1.0, byNamespace[0].LoadBalancers, 1e-9)
+	assert.InDelta(t, 7.3+1.46+20, byNamespace[0].TotalCost, 1e-9)
+	assert.Equal(t, "db", byNamespace[1].Name)
+	assert.InDelta(t, 14.6+2.92+10, byNamespace[1].TotalCost, 1e-9)
+
+	byWorkload := Allocate(items, pricing, GroupByWorkload, "", HoursPerMonth)
+	names := map[string]Allocation{}
+	for _, a := range byWorkload {
+		names[a.Namespace+"/"+a.Name] = a
+	}
+	assert.Contains(t, names, "shop/Deployment/api")
+	assert.Contains(t, names, "shop/Service/api")
+	assert.InDelta(t, 100.0, names["db/Pod/postgres-0"].StorageGiB, 1e-9)
+
+	byTeam := Allocate(items, pricing, GroupByLabel, "team", HoursPerMonth)
+	require.Len(t, byTeam, 2)
+	assert.Equal(t, "web", byTeam[0].Name)
+	assert.Equal(t, Unallocated, byTeam[1].Name)
+
+	total := Total(byNamespace, pricing, HoursPerMonth)
+	assert.InDelta(t, byNamespace[0].TotalCost+byNamespace[1].TotalCost, total.TotalCost, 1e-9)
+}
+
+func TestItemsUsageBasis(t *testing.T) {
+	pods := []corev1.Pod{testPod("shop", "api", "", "500m", "1Gi", nil)}
+	usage := map[string]prometheus.PodUsage{"shop/api": {CPU: 2, Memory: 512 * 1024 * 1024}}
+
+	items := Items(pods, nil, nil, usage, BasisUsage)
+	require.Len(t, items, 1)
+	assert.InDelta(t, 2.0, items[0].CPUCores, 1e-9)
+	assert.InDelta(t, 1.0, items[0].MemoryGiB, 1e-9)
+
+	items = Items(pods, nil, nil, usage, BasisRequests)
+	assert.InDelta(t, 0.5, items[0].CPUCores, 1e-9)
+}
+
+func TestPeriodItems(t *testing.T) {
+	usage := &prometheus.PeriodUsage{
+		Pods: map[string]*prometheus.PodPeriodUsage{
+			"shop/api-7d9f-abcde": {
+				Namespace: "shop", Workload: "Deployment/api", Labels: map[string]string{"team": "web"},
+				Requests: prometheus.PodUsage{CPU: 0.5, Memory: 1024 * 1024 * 1024},
+				Usage:    prometheus.PodUsage{CPU: 1},
+			},
+			"db/postgres-0": {Namespace: "db", Workload: "StatefulSet/postgres", Labels: map[string]string{}, Requests: prometheus.PodUsage{CPU: 2}},
+		},
+		Claims:        map[string]float64{"db/data-postgres-0": 100 * gib, "db/orphan": 10 * gib},
+		ClaimPods:     map[string]string{"db/data-postgres-0": "postgres-0"},
+		LoadBalancers: map[string]float64{"shop/api": 0.5},
+	}
+	pricing := model.CostPricing{CPUCoreHour: 0.01, MemoryGiBHour: 0.001, StorageGiBMonth: 0.1, LoadBalancerMonth: 20}
+
+	items := PeriodItems(usage, BasisRequests)
+	require.Len(t, items, 5)
+	byWorkload := map[string]Allocation{}
+	for _, a := range Allocate(items, pricing, GroupByWorkload, "", HoursPerMonth/2) {
+		byWorkload[a.Namespace+"/"+a.Name] = a
+	}
+	assert.InDelta(t, 0.5, byWorkload["shop/Deployment/api"].CPUCores, 1e-9)
+	assert.InDelta(t, 100.0, byWorkload["db/StatefulSet/postgres"].StorageGiB, 1e-9)
+	assert.InDelta(t, 10.0, byWorkload["db/PersistentVolumeClaim/orphan"].StorageGiB, 1e-9)
+	// Half a month: hourly prices for 365 hours, monthly prices halved.
+	assert.InDelta(t, 2*0.01*365+100*0.1/2, byWorkload["db/StatefulSet/postgres"].TotalCost, 1e-9)
+	assert.InDelta(t, 0.5*20/2, byWorkload["shop/Service/api"].TotalCost, 1e-9)
+
+	items = PeriodItems(usage, BasisUsage)
+	byNamespace := Allocate(items, pricing, GroupByNamespace, "", HoursPerMonth)
+	require.Len(t, byNamespace, 2)
+	assert.InDelta(t, 1.0, byNamespace[1].CPUCores, 1e-9)
+}
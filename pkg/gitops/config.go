@@ -0,0 +1,282 @@
+// Package gitops reconciles the declarative kube-sentinel.yaml configuration
+// file into the database. Records created from the file are marked as managed
+// by the configuration and are read-only in the admin APIs.
+package gitops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+)
+
+// Config is the schema of the declarative configuration file.
+type Config struct {
+	Clusters       []ClusterSpec       `json:"clusters,omitempty"`
+	Roles          []RoleSpec          `json:"roles,omitempty"`
+	OAuthProviders []OAuthProviderSpec `json:"oauthProviders,omitempty"`
+	AIProfiles     []AIProfileSpec     `json:"aiProfiles,omitempty"`
+	AppConfigs     map[string]string   `json:"appConfigs,omitempty"`
+}
+
+// SecretKeyRef references a key of a Secret in the cluster kube-sentinel runs in.
+type SecretKeyRef struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Key       string `json:"key,omitempty"`
+}
+
+type ClusterSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// Exactly one of the kubeconfig sources or inCluster must be set
+	Kubeconfig       string        `json:"kubeconfig,omitempty"`
+	KubeconfigFile   string        `json:"kubeconfigFile,omitempty"`
+	KubeconfigSecret *SecretKeyRef `json:"kubeconfigSecret,omitempty"`
+	InCluster        bool          `json:"inCluster,omitempty"`
+
+	PrometheusURL  string              `json:"prometheusURL,omitempty"`
+	IsDefault      bool                `json:"isDefault,omitempty"`
+	Enabled        *bool               `json:"enabled,omitempty"`
+	SkipSystemSync bool                `json:"skipSystemSync,omitempty"`
+	Labels         map[string]string   `json:"labels,omitempty"`
+	CachePolicy    *common.CachePolicy `json:"cachePolicy,omitempty"`
+
+	// PrometheusConfig sets Prometheus authentication, headers and TLS
+	PrometheusConfig *model.PrometheusConfig `json:"prometheusConfig,omitempty"`
+
+	// AlertmanagerURL is discovered in the cluster when empty
+	AlertmanagerURL string `json:"alertmanagerURL,omitempty"`
+
+	// Pricing enables cost allocation reports for the cluster
+	Pricing *model.CostPricing `json:"pricing,omitempty"`
+}
+
+type RoleSpec struct {
+	Name            string   `json:"name"`
+	Description     string   `json:"description,omitempty"`
+	Clusters        []string `json:"clusters,omitempty"`
+	ClusterSelector string   `json:"clusterSelector,omitempty"`
+	Namespaces      []string `json:"namespaces,omitempty"`
+	Resources       []string `json:"resources,omitempty"`
+	Verbs           []string `json:"verbs,omitempty"`
+
+	Assignments RoleAssignmentsSpec `json:"assignments,omitempty"`
+}
+
+type RoleAssignmentsSpec struct {
+	Users  []string `json:"users,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+type OAuthProviderSpec struct {
+	Name         string `json:"name"`
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+	AuthURL      string `json:"authUrl,omitempty"`
+	TokenURL     string `json:"tokenUrl,omitempty"`
+	UserInfoURL  string `json:"userInfoUrl,omitempty"`
+	Scopes       string `json:"scopes,omitempty"`
+	Issuer       string `json:"issuer,omitempty"`
+	Enabled      *bool  `json:"enabled,omitempty"`
+}
+
+type AIProfileSpec struct {
+	Name              string   `json:"name"`
+	Provider          string   `json:"provider"`
+	BaseURL           string   `json:"baseUrl,omitempty"`
+	DefaultModel      string   `json:"defaultModel,omitempty"`
+	APIKey            string   `json:"apiKey,omitempty"`
+	IsSystem          bool     `json:"isSystem,omitempty"`
+	Enabled           *bool    `json:"enabled,omitempty"`
+	AllowUserOverride bool     `json:"allowUserOverride,omitempty"`
+	AllowedModels     []string `json:"allowedModels,omitempty"`
+}
+
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${VAR} references with environment variables, so secrets
+// can be kept out of the file. Unset variables are reported as an error
+// rather than silently becoming empty credentials.
+func expandEnv(data string) (string, error) {
+	var missing []string
+	expanded := envRef.ReplaceAllStringFunc(data, func(ref string) string {
+		name := envRef.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variables not set: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// Parse decodes and validates a configuration file.
+func Parse(data []byte) (*Config, error) {
+	expanded, err := expandEnv(string(data))
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.UnmarshalStrict([]byte(expanded), &cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration file: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (cfg *Config) validate() error {
+	seen := map[string]bool{}
+	unique := func(kind, name string) error {
+		if name == "" {
+			return fmt.Errorf("%s name is required", kind)
+		}
+		key := kind + "/" + strings.ToLower(name)
+		if seen[key] {
+			return fmt.Errorf("duplicate %s %q", kind, name)
+		}
+		seen[key] = true
+		return nil
+	}
+
+	defaults := 0
+	for _, c := range cfg.Clusters {
+		if err := unique(KindCluster, c.Name); err != nil {
+			return err
+		}
+		sources := 0
+		for _, set := range []bool{c.Kubeconfig != "", c.KubeconfigFile != "", c.KubeconfigSecret != nil, c.InCluster} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return fmt.Errorf("cluster %q: exactly one of kubeconfig, kubeconfigFile, kubeconfigSecret or inCluster must be set", c.Name)
+		}
+		if c.KubeconfigSecret != nil && c.KubeconfigSecret.Name == "" {
+			return fmt.Errorf("cluster %q: kubeconfigSecret.name is required", c.Name)
+		}
+		if err := c.Pricing.Validate(); err != nil {
+			return fmt.Errorf("cluster %q: %w", c.Name, err)
+		}
+		if c.IsDefault {
+			defaults++
+		}
+	}
+	if defaults > 1 {
+		return fmt.Errorf("only one cluster can be the default cluster")
+	}
+
+	for _, r := range cfg.Roles {
+		if err := unique(KindRole, r.Name); err != nil {
+			return err
+		}
+		if err := rbac.ValidateClusterSelector(r.ClusterSelector); err != nil {
+			return fmt.Errorf("role %q: %w", r.Name, err)
+		}
+	}
+	for _, p := range cfg.OAuthProviders {
+		if err := unique(KindOAuthProvider, p.Name); err != nil {
+			return err
+		}
+		if p.ClientID == "" || p.ClientSecret == "" {
+			return fmt.Errorf("oauth provider %q: clientId and clientSecret are required", p.Name)
+		}
+	}
+	systemProfiles := 0
+	for _, p := range cfg.AIProfiles {
+		if err := unique(KindAIProfile, p.Name); err != nil {
+			return err
+		}
+		if p.IsSystem {
+			systemProfiles++
+		}
+	}
+	if systemProfiles > 1 {
+		return fmt.Errorf("only one AI profile can be the system profile")
+	}
+	return nil
+}
+
+// readSecret returns a key of a Secret in the cluster kube-sentinel runs in.
+// It is a variable so tests can replace it.
+var readSecret = func(ctx context.Context, ref SecretKeyRef) (string, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return "", err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return "", err
+	}
+	secret, err := clientset.CoreV1().Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	data, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found in secret %s/%s", ref.Key, ref.Namespace, ref.Name)
+	}
+	return string(data), nil
+}
+
+// podNamespace returns the namespace kube-sentinel runs in.
+func podNamespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	if data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	return "default"
+}
+
+// kubeconfig resolves the kubeconfig of a cluster from its configured source.
+func (c ClusterSpec) kubeconfig(ctx context.Context) (string, error) {
+	switch {
+	case c.InCluster:
+		return "", nil
+	case c.Kubeconfig != "":
+		return c.Kubeconfig, nil
+	case c.KubeconfigFile != "":
+		data, err := os.ReadFile(c.KubeconfigFile)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case c.KubeconfigSecret != nil:
+		ref := *c.KubeconfigSecret
+		if ref.Namespace == "" {
+			ref.Namespace = podNamespace()
+		}
+		if ref.Key == "" {
+			ref.Key = "kubeconfig"
+		}
+		return readSecret(ctx, ref)
+	}
+	return "", fmt.Errorf("no kubeconfig source")
+}
+
+func boolOrTrue(b *bool) bool {
+	return b == nil || *b
+}
+
+// configManaged reports whether a record is owned by the configuration file.
+func configManaged(managedBy string) bool {
+	return managedBy == model.ManagedByConfig
+}
@@ -0,0 +1,569 @@
+package gitops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+)
+
+const (
+	KindCluster        = "cluster"
+	KindRole           = "role"
+	KindRoleAssignment = "roleAssignment"
+	KindOAuthProvider  = "oauthProvider"
+	KindAIProfile      = "aiProfile"
+	KindAppConfig      = "appConfig"
+)
+
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionAdopt   = "adopt"   // an existing record is taken over by the file
+	ActionRelease = "release" // a record is no longer managed by the file
+)
+
+// Change is a difference between the configuration file and the database.
+type Change struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s %q", c.Action, c.Kind, c.Name)
+}
+
+// reconciler compares the configuration with the database and, unless dryRun
+// is set, writes the differences.
+type reconciler struct {
+	ctx     context.Context
+	dryRun  bool
+	changes []Change
+}
+
+func (r *reconciler) record(kind, name, action string) {
+	r.changes = append(r.changes, Change{Kind: kind, Name: name, Action: action})
+}
+
+// Apply reconciles the database with cfg and returns the changes made. With
+// dryRun the changes are only computed. Errors of one record do not stop the
+// reconciliation of the others.
+func Apply(ctx context.Context, cfg *Config, dryRun bool) ([]Change, error) {
+	r := &reconciler{ctx: ctx, dryRun: dryRun}
+	err := errors.Join(
+		r.clusters(cfg.Clusters),
+		r.roles(cfg.Roles),
+		r.oauthProviders(cfg.OAuthProviders),
+		r.aiProfiles(cfg.AIProfiles),
+		r.appConfigs(cfg.AppConfigs),
+	)
+	return r.changes, err
+}
+
+func updateAction(managedBy string) string {
+	if configManaged(managedBy) {
+		return ActionUpdate
+	}
+	return ActionAdopt
+}
+
+func (r *reconciler) clusters(specs []ClusterSpec) error {
+	var existing []model.Cluster
+	if err := model.DB.Find(&existing).Error; err != nil {
+		return err
+	}
+	byName := make(map[string]model.Cluster, len(existing))
+	for _, c := range existing {
+		byName[c.Name] = c
+	}
+
+	var errs []error
+	desired := map[string]bool{}
+	for _, spec := range specs {
+		desired[spec.Name] = true
+		config, err := spec.kubeconfig(r.ctx)
+		if err == nil && !spec.InCluster {
+			_, err = clientcmd.Load([]byte(config))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cluster %q: %w", spec.Name, err))
+			continue
+		}
+
+		want := model.Cluster{
+			Name:            spec.Name,
+			Description:     spec.Description,
+			Config:          model.SecretString(config),
+			PrometheusURL:   spec.PrometheusURL,
+			AlertmanagerURL: spec.AlertmanagerURL,
+			InCluster:       spec.InCluster,
+			IsDefault:       spec.IsDefault,
+			Enable:          boolOrTrue(spec.Enabled),
+			SkipSystemSync:  spec.SkipSystemSync,
+			Labels:          model.MapString(spec.Labels),
+			CachePolicy:     spec.CachePolicy,
+			Pricing:         spec.Pricing,
+			ManagedBy:       model.ManagedByConfig,
+		}
+		if !spec.PrometheusConfig.IsZero() {
+			want.PrometheusConfig = spec.PrometheusConfig
+		}
+
+		cur, ok := byName[spec.Name]
+		if !ok {
+			r.record(KindCluster, spec.Name, ActionCreate)
+			if !r.dryRun {
+				err = r.clearDefaultCluster(want.IsDefault)
+				if err == nil {
+					err = create(&want, map[string]interface{}{"enable": want.Enable})
+				}
+			}
+		} else if !clusterEqual(cur, want) {
+			r.record(KindCluster, spec.Name, updateAction(cur.ManagedBy))
+			if !r.dryRun {
+				err = r.clearDefaultCluster(want.IsDefault && !cur.IsDefault)
+				if err == nil {
+					updates := map[string]interface{}{
+						"description":       want.Description,
+						"config":            want.Config,
+						"prometheus_url":    want.PrometheusURL,
+						"alertmanager_url":  want.AlertmanagerURL,
+						"in_cluster":        want.InCluster,
+						"is_default":        want.IsDefault,
+						"enable":            want.Enable,
+						"skip_system_sync":  want.SkipSystemSync,
+						"labels":            want.Labels,
+						"cache_policy":      want.CachePolicy,
+						"managed_by":        want.ManagedBy,
+						"prometheus_config": nil,
+						"pricing":           nil,
+					}
+					if want.PrometheusConfig != nil {
+						updates["prometheus_config"] = *want.PrometheusConfig
+					}
+					if want.Pricing != nil {
+						updates["pricing"] = *want.Pricing
+					}
+					err = model.UpdateCluster(&cur, updates)
+				}
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cluster %q: %w", spec.Name, err))
+		}
+	}
+
+	for _, cur := range existing {
+		if !configManaged(cur.ManagedBy) || desired[cur.Name] {
+			continue
+		}
+		r.record(KindCluster, cur.Name, ActionDelete)
+		if !r.dryRun {
+			if err := model.DeleteCluster(&cur); err != nil {
+				errs = append(errs, fmt.Errorf("cluster %q: %w", cur.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// create inserts a record. gorm replaces zero values of columns that have a
+// default (e.g. enabled: false) on insert, so those columns are written again
+// from columns.
+func create(value interface{}, columns map[string]interface{}) error {
+	if err := model.DB.Create(value).Error; err != nil {
+		return err
+	}
+	return model.DB.Model(value).Updates(columns).Error
+}
+
+func (r *reconciler) clearDefaultCluster(clear bool) error {
+	if !clear {
+		return nil
+	}
+	return model.ClearDefaultCluster()
+}
+
+func clusterEqual(cur, want model.Cluster) bool {
+	return cur.Description == want.Description &&
+		cur.Config == want.Config &&
+		cur.PrometheusURL == want.PrometheusURL &&
+		cur.AlertmanagerURL == want.AlertmanagerURL &&
+		cur.InCluster == want.InCluster &&
+		cur.IsDefault == want.IsDefault &&
+		cur.Enable == want.Enable &&
+		cur.SkipSystemSync == want.SkipSystemSync &&
+		maps.Equal(cur.Labels, want.Labels) &&
+		reflect.DeepEqual(cur.CachePolicy, want.CachePolicy) &&
+		reflect.DeepEqual(cur.PrometheusConfig, want.PrometheusConfig) &&
+		reflect.DeepEqual(cur.Pricing, want.Pricing) &&
+		cur.ManagedBy == want.ManagedBy
+}
+
+func (r *reconciler) roles(specs []RoleSpec) error {
+	var existing []model.Role
+	if err := model.DB.Preload("Assignments").Find(&existing).Error; err != nil {
+		return err
+	}
+	byName := make(map[string]model.Role, len(existing))
+	for _, role := range existing {
+		byName[role.Name] = role
+	}
+
+	var errs []error
+	desired := map[string]bool{}
+	for _, spec := range specs {
+		desired[spec.Name] = true
+		want := model.Role{
+			Name:            spec.Name,
+			Description:     spec.Description,
+			Clusters:        spec.Clusters,
+			ClusterSelector: spec.ClusterSelector,
+			Namespaces:      spec.Namespaces,
+			Resources:       spec.Resources,
+			Verbs:           spec.Verbs,
+			ManagedBy:       model.ManagedByConfig,
+		}
+
+		var err error
+		cur, ok := byName[spec.Name]
+		switch {
+		case !ok:
+			r.record(KindRole, spec.Name, ActionCreate)
+			if !r.dryRun {
+				err = model.DB.Create(&want).Error
+			}
+			cur = want
+		case cur.IsSystem:
+			// Rules of the built-in roles are fixed; only their
+			// assignments can be declared in the file.
+		case !roleEqual(cur, want):
+			r.record(KindRole, spec.Name, updateAction(cur.ManagedBy))
+			if !r.dryRun {
+				cur.Description = want.Description
+				cur.Clusters = want.Clusters
+				cur.ClusterSelector = want.ClusterSelector
+				cur.Namespaces = want.Namespaces
+				cur.Resources = want.Resources
+				cur.Verbs = want.Verbs
+				cur.ManagedBy = want.ManagedBy
+				err = model.DB.Omit("Assignments").Save(&cur).Error
+			}
+		}
+		if err == nil {
+			err = r.roleAssignments(cur, spec.Assignments)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("role %q: %w", spec.Name, err))
+		}
+	}
+
+	for _, cur := range existing {
+		if desired[cur.Name] {
+			continue
+		}
+		var err error
+		if configManaged(cur.ManagedBy) {
+			r.record(KindRole, cur.Name, ActionDelete)
+			if !r.dryRun {
+				err = model.DB.Where("role_id = ?", cur.ID).Delete(&model.RoleAssignment{}).Error
+				if err == nil {
+					err = model.DB.Delete(&cur).Error
+				}
+			}
+		} else {
+			// Assignments declared on a role that was removed from the file
+			err = r.roleAssignments(cur, RoleAssignmentsSpec{})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("role %q: %w", cur.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func roleEqual(cur, want model.Role) bool {
+	return cur.Description == want.Description &&
+		slices.Equal(cur.Clusters, want.Clusters) &&
+		cur.ClusterSelector == want.ClusterSelector &&
+		slices.Equal(cur.Namespaces, want.Namespaces) &&
+		slices.Equal(cur.Resources, want.Resources) &&
+		slices.Equal(cur.Verbs, want.Verbs) &&
+		cur.ManagedBy == want.ManagedBy
+}
+
+func (r *reconciler) roleAssignments(role model.Role, spec RoleAssignmentsSpec) error {
+	type subject struct{ kind, name string }
+	desired := map[subject]bool{}
+	for _, u := range spec.Users {
+		desired[subject{model.SubjectTypeUser, u}] = true
+	}
+	for _, g := range spec.Groups {
+		desired[subject{model.SubjectTypeGroup, g}] = true
+	}
+
+	var errs []error
+	for _, a := range role.Assignments {
+		key := subject{a.SubjectType, a.Subject}
+		name := fmt.Sprintf("%s/%s:%s", role.Name, a.SubjectType, a.Subject)
+		switch {
+		case desired[key]:
+			delete(desired, key)
+			if configManaged(a.ManagedBy) {
+				continue
+			}
+			r.record(KindRoleAssignment, name, ActionAdopt)
+			if !r.dryRun {
+				errs = append(errs, model.DB.Model(&a).Update("managed_by", model.ManagedByConfig).Error)
+			}
+		case configManaged(a.ManagedBy):
+			r.record(KindRoleAssignment, name, ActionDelete)
+			if !r.dryRun {
+				errs = append(errs, model.DB.Delete(&a).Error)
+			}
+		}
+	}
+
+	keys := slices.SortedFunc(maps.Keys(desired), func(a, b subject) int {
+		return strings.Compare(a.kind+":"+a.name, b.kind+":"+b.name)
+	})
+	for _, key := range keys {
+		r.record(KindRoleAssignment, fmt.Sprintf("%s/%s:%s", role.Name, key.kind, key.name), ActionCreate)
+		if !r.dryRun {
+			errs = append(errs, model.DB.Create(&model.RoleAssignment{
+				RoleID:      role.ID,
+				SubjectType: key.kind,
+				Subject:     key.name,
+				ManagedBy:   model.ManagedByConfig,
+			}).Error)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *reconciler) oauthProviders(specs []OAuthProviderSpec) error {
+	existing, err := model.GetAllOAuthProviders()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]model.OAuthProvider, len(existing))
+	for _, p := range existing {
+		byName[string(p.Name)] = p
+	}
+
+	var errs []error
+	desired := map[string]bool{}
+	for _, spec := range specs {
+		name := strings.ToLower(spec.Name)
+		desired[name] = true
+		want := model.OAuthProvider{
+			Name:         model.LowerCaseString(name),
+			ClientID:     spec.ClientID,
+			ClientSecret: model.SecretString(spec.ClientSecret),
+			AuthURL:      spec.AuthURL,
+			TokenURL:     spec.TokenURL,
+			UserInfoURL:  spec.UserInfoURL,
+			Scopes:       spec.Scopes,
+			Issuer:       spec.Issuer,
+			Enabled:      boolOrTrue(spec.Enabled),
+			ManagedBy:    model.ManagedByConfig,
+		}
+		if want.Scopes == "" {
+			want.Scopes = "openid,profile,email"
+		}
+
+		var err error
+		cur, ok := byName[name]
+		if !ok {
+			r.record(KindOAuthProvider, name, ActionCreate)
+			if !r.dryRun {
+				want.AppID = model.CurrentApp.ID
+				err = create(&want, map[string]interface{}{"enabled": want.Enabled})
+			}
+		} else if !oauthProviderEqual(cur, want) {
+			r.record(KindOAuthProvider, name, updateAction(cur.ManagedBy))
+			if !r.dryRun {
+				err = model.UpdateOAuthProvider(&cur, map[string]interface{}{
+					"client_id":     want.ClientID,
+					"client_secret": want.ClientSecret,
+					"auth_url":      want.AuthURL,
+					"token_url":     want.TokenURL,
+					"user_info_url": want.UserInfoURL,
+					"scopes":        want.Scopes,
+					"issuer":        want.Issuer,
+					"enabled":       want.Enabled,
+					"managed_by":    want.ManagedBy,
+				})
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("oauth provider %q: %w", name, err))
+		}
+	}
+
+	for _, cur := range existing {
+		if !configManaged(cur.ManagedBy) || desired[string(cur.Name)] {
+			continue
+		}
+		r.record(KindOAuthProvider, string(cur.Name), ActionDelete)
+		if !r.dryRun {
+			if err := model.DeleteOAuthProvider(cur.ID); err != nil {
+				errs = append(errs, fmt.Errorf("oauth provider %q: %w", cur.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func oauthProviderEqual(cur, want model.OAuthProvider) bool {
+	return cur.ClientID == want.ClientID &&
+		cur.ClientSecret == want.ClientSecret &&
+		cur.AuthURL == want.AuthURL &&
+		cur.TokenURL == want.TokenURL &&
+		cur.UserInfoURL == want.UserInfoURL &&
+		cur.Scopes == want.Scopes &&
+		cur.Issuer == want.Issuer &&
+		cur.Enabled == want.Enabled &&
+		cur.ManagedBy == want.ManagedBy
+}
+
+func (r *reconciler) aiProfiles(specs []AIProfileSpec) error {
+	var existing []model.AIProviderProfile
+	if err := model.DB.Find(&existing).Error; err != nil {
+		return err
+	}
+	byName := make(map[string]model.AIProviderProfile, len(existing))
+	for _, p := range existing {
+		byName[p.Name] = p
+	}
+
+	var errs []error
+	desired := map[string]bool{}
+	for _, spec := range specs {
+		desired[spec.Name] = true
+		want := model.AIProviderProfile{
+			Name:              spec.Name,
+			Provider:          spec.Provider,
+			BaseURL:           spec.BaseURL,
+			DefaultModel:      spec.DefaultModel,
+			APIKey:            model.SecretString(spec.APIKey),
+			IsSystem:          spec.IsSystem,
+			IsEnabled:         boolOrTrue(spec.Enabled),
+			AllowUserOverride: spec.AllowUserOverride,
+			AllowedModels:     spec.AllowedModels,
+			ManagedBy:         model.ManagedByConfig,
+		}
+
+		var err error
+		cur, ok := byName[spec.Name]
+		if !ok {
+			r.record(KindAIProfile, spec.Name, ActionCreate)
+			if !r.dryRun {
+				err = create(&want, map[string]interface{}{"is_enabled": want.IsEnabled})
+				cur = want
+			}
+		} else if !aiProfileEqual(cur, want) {
+			r.record(KindAIProfile, spec.Name, updateAction(cur.ManagedBy))
+			if !r.dryRun {
+				want.Model = cur.Model
+				err = model.DB.Save(&want).Error
+				cur = want
+			}
+		}
+		if err == nil && want.IsSystem && !r.dryRun {
+			err = model.DB.Model(&model.AIProviderProfile{}).Where("id <> ? AND is_system = ?", cur.ID, true).Update("is_system", false).Error
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ai profile %q: %w", spec.Name, err))
+		}
+	}
+
+	for _, cur := range existing {
+		if !configManaged(cur.ManagedBy) || desired[cur.Name] {
+			continue
+		}
+		r.record(KindAIProfile, cur.Name, ActionDelete)
+		if !r.dryRun {
+			if err := model.DB.Delete(&cur).Error; err != nil {
+				errs = append(errs, fmt.Errorf("ai profile %q: %w", cur.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func aiProfileEqual(cur, want model.AIProviderProfile) bool {
+	return cur.Provider == want.Provider &&
+		cur.BaseURL == want.BaseURL &&
+		cur.DefaultModel == want.DefaultModel &&
+		cur.APIKey == want.APIKey &&
+		cur.IsSystem == want.IsSystem &&
+		cur.IsEnabled == want.IsEnabled &&
+		cur.AllowUserOverride == want.AllowUserOverride &&
+		slices.Equal(cur.AllowedModels, want.AllowedModels) &&
+		cur.ManagedBy == want.ManagedBy
+}
+
+func (r *reconciler) appConfigs(values map[string]string) error {
+	existing, err := model.GetAppConfigs(model.CurrentApp.ID)
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string]model.AppConfig, len(existing))
+	for _, c := range existing {
+		byKey[c.Key] = c
+	}
+
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		value := values[key]
+		cur, ok := byKey[key]
+		switch {
+		case !ok:
+			r.record(KindAppConfig, key, ActionCreate)
+		case cur.Value != value || !configManaged(cur.ManagedBy):
+			r.record(KindAppConfig, key, updateAction(cur.ManagedBy))
+		default:
+			continue
+		}
+		if r.dryRun {
+			continue
+		}
+		err := model.SetAppConfig(model.CurrentApp.ID, key, value)
+		if err == nil {
+			err = model.DB.Model(&model.AppConfig{}).Where("app_id = ? AND key = ?", model.CurrentApp.ID, key).Update("managed_by", model.ManagedByConfig).Error
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("app config %q: %w", key, err))
+		}
+	}
+
+	// Settings removed from the file keep their value but become editable again
+	for _, cur := range existing {
+		if _, ok := values[cur.Key]; ok || !configManaged(cur.ManagedBy) {
+			continue
+		}
+		r.record(KindAppConfig, cur.Key, ActionRelease)
+		if !r.dryRun {
+			if err := model.DB.Model(&cur).Update("managed_by", "").Error; err != nil {
+				errs = append(errs, fmt.Errorf("app config %q: %w", cur.Key, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func logChanges(prefix string, changes []Change) {
+	for _, c := range changes {
+		klog.Infof("%s: %s", prefix, c)
+	}
+}
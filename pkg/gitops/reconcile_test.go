@@ -0,0 +1,153 @@
+package gitops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: c
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: c
+  context:
+    cluster: c
+    user: u
+current-context: c
+users:
+- name: u
+  user:
+    token: t
+`
+
+func TestParse(t *testing.T) {
+	t.Setenv("TEST_CLIENT_SECRET", "s3cret")
+
+	cfg, err := Parse([]byte(`
+oauthProviders:
+- name: GitHub
+  clientId: id
+  clientSecret: ${TEST_CLIENT_SECRET}
+appConfigs:
+  LOCAL_LOGIN_ENABLED: "false"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", cfg.OAuthProviders[0].ClientSecret)
+	assert.Equal(t, "false", cfg.AppConfigs["LOCAL_LOGIN_ENABLED"])
+
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{
+			name: "unset variable",
+			data: "aiProfiles:\n- name: p\n  apiKey: ${TEST_UNSET_VARIABLE}\n",
+			err:  "TEST_UNSET_VARIABLE",
+		},
+		{
+			name: "unknown field",
+			data: "cluster: []\n",
+			err:  "unknown field",
+		},
+		{
+			name: "two kubeconfig sources",
+			data: "clusters:\n- name: a\n  inCluster: true\n  kubeconfigFile: /tmp/k\n",
+			err:  "exactly one of",
+		},
+		{
+			name: "duplicate role",
+			data: "roles:\n- name: dev\n- name: Dev\n",
+			err:  "duplicate role",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	common.DBType = "sqlite"
+	common.DBDSN = "file::memory:?cache=shared"
+	model.InitDB()
+	require.NoError(t, model.InitDefaultRole())
+
+	disabled := false
+	cfg := &Config{
+		Clusters: []ClusterSpec{
+			{Name: "prod", Kubeconfig: testKubeconfig, Labels: map[string]string{"env": "prod"}, Enabled: &disabled},
+		},
+		Roles: []RoleSpec{
+			{
+				Name:        "developers",
+				Clusters:    []string{"prod"},
+				Namespaces:  []string{"dev"},
+				Resources:   []string{"*"},
+				Verbs:       []string{"get"},
+				Assignments: RoleAssignmentsSpec{Groups: []string{"devs"}},
+			},
+			{Name: "admin", Assignments: RoleAssignmentsSpec{Users: []string{"alice"}}},
+		},
+		OAuthProviders: []OAuthProviderSpec{{Name: "GitHub", ClientID: "id", ClientSecret: "secret"}},
+		AIProfiles:     []AIProfileSpec{{Name: "openai", Provider: "openai", IsSystem: true}},
+		AppConfigs:     map[string]string{model.LocalLoginEnabledKey: "false"},
+	}
+	ctx := context.Background()
+
+	changes, err := Apply(ctx, cfg, false)
+	require.NoError(t, err)
+	assert.Contains(t, changes, Change{Kind: KindCluster, Name: "prod", Action: ActionCreate})
+	assert.Contains(t, changes, Change{Kind: KindRoleAssignment, Name: "admin/user:alice", Action: ActionCreate})
+	assert.Contains(t, changes, Change{Kind: KindAppConfig, Name: model.LocalLoginEnabledKey, Action: ActionAdopt})
+
+	c, err := model.GetClusterByName("prod")
+	require.NoError(t, err)
+	assert.Equal(t, model.ManagedByConfig, c.ManagedBy)
+	assert.False(t, c.Enable)
+
+	admin, err := model.GetRoleByName("admin")
+	require.NoError(t, err)
+	assert.Empty(t, admin.ManagedBy, "system roles stay editable")
+	assert.True(t, model.IsAppConfigManaged(model.CurrentApp.ID, model.LocalLoginEnabledKey))
+
+	changes, err = Apply(ctx, cfg, true)
+	require.NoError(t, err)
+	assert.Empty(t, changes, "a second run is a no-op")
+
+	// Out-of-band edits show up as drift without being corrected
+	require.NoError(t, model.DB.Model(&model.Role{}).Where("name = ?", "developers").Update("verbs", "*").Error)
+	changes, err = Apply(ctx, cfg, true)
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Kind: KindRole, Name: "developers", Action: ActionUpdate}}, changes)
+
+	// Removing records from the file prunes them
+	cfg.Roles = cfg.Roles[:1]
+	cfg.AppConfigs = nil
+	changes, err = Apply(ctx, cfg, false)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Change{
+		{Kind: KindRole, Name: "developers", Action: ActionUpdate},
+		{Kind: KindRoleAssignment, Name: "admin/user:alice", Action: ActionDelete},
+		{Kind: KindAppConfig, Name: model.LocalLoginEnabledKey, Action: ActionRelease},
+	}, changes)
+	assert.False(t, model.IsAppConfigManaged(model.CurrentApp.ID, model.LocalLoginEnabledKey))
+
+	cfg.Clusters = nil
+	changes, err = Apply(ctx, cfg, false)
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Kind: KindCluster, Name: "prod", Action: ActionDelete}}, changes)
+	_, err = model.GetClusterByName("prod")
+	assert.Error(t, err)
+}
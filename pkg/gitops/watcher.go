@@ -0,0 +1,167 @@
+package gitops
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// The file is polled rather than watched with inotify: ConfigMap mounts
+	// replace the file through a symlink swap that watchers easily miss.
+	pollInterval = 30 * time.Second
+	// driftInterval is how often an unchanged file is compared with the database.
+	driftInterval = 5 * time.Minute
+
+	reconcileTimeout = time.Minute
+)
+
+// Status is the state of the configuration file reconciliation.
+type Status struct {
+	Enabled       bool       `json:"enabled"`
+	Path          string     `json:"path,omitempty"`
+	Hash          string     `json:"hash,omitempty"`
+	LastAppliedAt *time.Time `json:"lastAppliedAt,omitempty"`
+	LastCheckedAt *time.Time `json:"lastCheckedAt,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	// Changes made by the last reconciliation
+	Changes []Change `json:"changes"`
+	// Drift lists differences between the file and the database found
+	// without the file having changed, e.g. direct database edits.
+	Drift []Change `json:"drift"`
+}
+
+var (
+	mu     sync.Mutex
+	status = Status{Changes: []Change{}, Drift: []Change{}}
+	// invalidHash is the hash of a file that failed to parse, so the same
+	// error is not reported on every poll.
+	invalidHash string
+)
+
+// Start reconciles the configuration file at path and keeps watching it for
+// changes. It does nothing when path is empty.
+func Start(path string) {
+	if path == "" {
+		return
+	}
+	mu.Lock()
+	status.Enabled = true
+	status.Path = path
+	mu.Unlock()
+
+	klog.Infof("Reconciling configuration file %s", path)
+	reconcile(true)
+	go watch()
+}
+
+func watch() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		reconcile(false)
+	}
+}
+
+// reconcile applies the file when it changed since the last reconciliation or
+// when force is set. Otherwise it periodically checks the database for drift.
+func reconcile(force bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+	defer cancel()
+
+	now := time.Now()
+	data, err := os.ReadFile(status.Path)
+	if err != nil {
+		setError(err)
+		return
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	changed := force || hash != status.Hash
+	if !changed && status.LastCheckedAt != nil && now.Sub(*status.LastCheckedAt) < driftInterval {
+		return
+	}
+
+	if !force && hash == invalidHash {
+		return
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		invalidHash = hash
+		setError(err)
+		return
+	}
+	invalidHash = ""
+
+	changes, err := Apply(ctx, cfg, !changed)
+	status.LastCheckedAt = &now
+	status.Error = ""
+	if err != nil {
+		setError(err)
+	}
+	if changes == nil {
+		changes = []Change{}
+	}
+
+	if !changed {
+		if len(changes) > 0 && len(status.Drift) == 0 {
+			logChanges("Configuration drift detected", changes)
+		}
+		status.Drift = changes
+		return
+	}
+
+	// Keep the previous hash on errors so failed records are retried on the
+	// next poll
+	if err == nil {
+		status.Hash = hash
+	}
+	status.LastAppliedAt = &now
+	status.Changes = changes
+	status.Drift = []Change{}
+	logChanges("Configuration file applied", changes)
+	if len(changes) > 0 {
+		cluster.TriggerSync()
+		rbac.TriggerSync()
+		model.InvalidateClusterGroupSettings()
+	}
+}
+
+func setError(err error) {
+	klog.Errorf("Failed to reconcile configuration file %s: %v", status.Path, err)
+	status.Error = err.Error()
+}
+
+// GetStatus returns the state of the configuration file reconciliation.
+func GetStatus(c *gin.Context) {
+	mu.Lock()
+	defer mu.Unlock()
+	c.JSON(http.StatusOK, status)
+}
+
+// Reconcile applies the configuration file immediately, correcting any drift.
+func Reconcile(c *gin.Context) {
+	mu.Lock()
+	enabled := status.Enabled
+	mu.Unlock()
+	if !enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no configuration file is configured"})
+		return
+	}
+	reconcile(true)
+	GetStatus(c)
+}
@@ -38,6 +38,7 @@ func CreateAIProfile(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	profile.ManagedBy = ""
 
 	if err := model.DB.Create(&profile).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create profile"})
@@ -53,11 +54,16 @@ func UpdateAIProfile(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
 		return
 	}
+	if profile.ManagedBy == model.ManagedByConfig {
+		c.JSON(http.StatusForbidden, gin.H{"error": model.ErrConfigManaged.Error()})
+		return
+	}
 
 	if err := c.ShouldBindJSON(&profile); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	profile.ManagedBy = ""
 
 	// If this profile is being set as system, unset all others
 	if profile.IsSystem {
@@ -76,7 +82,16 @@ func UpdateAIProfile(c *gin.Context) {
 
 func DeleteAIProfile(c *gin.Context) {
 	id := c.Param("id")
-	if err := model.DB.Delete(&model.AIProviderProfile{}, id).Error; err != nil {
+	var profile model.AIProviderProfile
+	if err := model.DB.First(&profile, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		return
+	}
+	if profile.ManagedBy == model.ManagedByConfig {
+		c.JSON(http.StatusForbidden, gin.H{"error": model.ErrConfigManaged.Error()})
+		return
+	}
+	if err := model.DB.Delete(&profile).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete profile"})
 		return
 	}
@@ -90,6 +105,10 @@ func ToggleAIProfile(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
 		return
 	}
+	if profile.ManagedBy == model.ManagedByConfig {
+		c.JSON(http.StatusForbidden, gin.H{"error": model.ErrConfigManaged.Error()})
+		return
+	}
 
 	profile.IsEnabled = !profile.IsEnabled
 	if err := model.DB.Save(&profile).Error; err != nil {
@@ -148,6 +167,13 @@ func UpdateAIGovernance(c *gin.Context) {
 		return
 	}
 
+	for _, key := range []string{model.AIAllowUserKeys, model.AIForceUserKeys, model.AIAllowUserOverride} {
+		if model.IsAppConfigManaged(model.CurrentApp.ID, key) {
+			c.JSON(http.StatusForbidden, gin.H{"error": key + ": " + model.ErrConfigManaged.Error()})
+			return
+		}
+	}
+
 	// Update AppConfigs
 	if err := model.SetAppConfig(model.CurrentApp.ID, model.AIAllowUserKeys, input.AllowUserKeys); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update AI governance (allow keys)"})
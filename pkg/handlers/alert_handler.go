@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/alertmanager"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+)
+
+// silenceResource is the RBAC resource name for creating and expiring silences.
+const silenceResource = "silences"
+
+// maxSilenceDuration keeps silences from outliving the incident they are for.
+const maxSilenceDuration = 30 * 24 * time.Hour
+
+func alertmanagerClient(c *gin.Context) (*cluster.ClientSet, bool) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	if cs.AlertmanagerClient == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Alertmanager client not available"})
+		return nil, false
+	}
+	return cs, true
+}
+
+// ListAlerts returns the alerts of the cluster, optionally filtered by
+// namespace, by resource (?resource=deployments&name=api) or by pod, and by
+// the alert filters of the groups of the cluster. Users
+// without the admin role only see alerts of namespaces they can access.
+func ListAlerts(c *gin.Context) {
+	cs, ok := alertmanagerClient(c)
+	if !ok {
+		return
+	}
+	user := c.MustGet("user").(model.User)
+	isAdmin := rbac.UserHasRole(user, model.DefaultAdminRole.Name)
+
+	namespace := c.Query("namespace")
+	resource, name := c.Query("resource"), c.Query("name")
+	if pod := c.Query("pod"); pod != "" {
+		resource, name = "pods", pod
+	}
+	if resource != "" && (namespace == "" || name == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "namespace and name are required to filter by resource"})
+		return
+	}
+
+	filters := append(c.QueryArray("filter"), model.GetClusterGroupSettings(cs.Name).AlertFilters...)
+	if namespace != "" {
+		filters = append(filters, fmt.Sprintf("%s=%q", alertmanager.NamespaceLabel, namespace))
+	}
+	alerts, err := cs.AlertmanagerClient.ListAlerts(c.Request.Context(), filters...)
+	if err != nil {
+		c.JSON(alertmanagerErrorStatus(err), gin.H{"error": fmt.Sprintf("failed to list alerts: %v", err)})
+		return
+	}
+
+	state := c.Query("state")
+	result := make([]alertmanager.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		if !isAdmin && !rbac.CanAccessNamespace(user, cs.Name, alert.Labels[alertmanager.NamespaceLabel]) {
+			continue
+		}
+		if resource != "" && !alertmanager.MatchResource(alert.Labels, resource, namespace, name) {
+			continue
+		}
+		if state != "" && alert.Status.State != state {
+			continue
+		}
+		result = append(result, alert)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ListSilences returns the silences of the cluster. Users without the admin
+// role only see silences scoped to a namespace they can access.
+func ListSilences(c *gin.Context) {
+	cs, ok := alertmanagerClient(c)
+	if !ok {
+		return
+	}
+	user := c.MustGet("user").(model.User)
+	isAdmin := rbac.UserHasRole(user, model.DefaultAdminRole.Name)
+
+	filters := append(c.QueryArray("filter"), model.GetClusterGroupSettings(cs.Name).AlertFilters...)
+	if namespace := c.Query("namespace"); namespace != "" {
+		filters = append(filters, fmt.Sprintf("%s=%q", alertmanager.NamespaceLabel, namespace))
+	}
+	silences, err := cs.AlertmanagerClient.ListSilences(c.Request.Context(), filters...)
+	if err != nil {
+		c.JSON(alertmanagerErrorStatus(err), gin.H{"error": fmt.Sprintf("failed to list silences: %v", err)})
+		return
+	}
+
+	result := make([]alertmanager.Silence, 0, len(silences))
+	for _, silence := range silences {
+		if !isAdmin {
+			ns := silenceNamespace(silence.Matchers)
+			if ns == "" || !rbac.CanAccessNamespace(user, cs.Name, ns) {
+				continue
+			}
+		}
+		result = append(result, silence)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CreateSilence creates a silence. Silences must match a single namespace
+// with namespace="..." unless the user is an admin, and the user needs the
+// create verb on "silences" in that namespace.
+func CreateSilence(c *gin.Context) {
+	cs, ok := alertmanagerClient(c)
+	if !ok {
+		return
+	}
+	user := c.MustGet("user").(model.User)
+
+	var req struct {
+		Matchers []alertmanager.Matcher `json:"matchers" binding:"required"`
+		StartsAt *time.Time             `json:"startsAt"`
+		EndsAt   *time.Time             `json:"endsAt"`
+		Duration string                 `json:"duration"`
+		Comment  string                 `json:"comment" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Matchers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one matcher is required"})
+		return
+	}
+	for _, m := range req.Matchers {
+		if m.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "matcher name is required"})
+			return
+		}
+	}
+
+	silence := alertmanager.Silence{
+		Matchers:  req.Matchers,
+		StartsAt:  time.Now(),
+		CreatedBy: user.Key(),
+		Comment:   req.Comment,
+	}
+	if req.StartsAt != nil {
+		silence.StartsAt = *req.StartsAt
+	}
+	switch {
+	case req.EndsAt != nil:
+		silence.EndsAt = *req.EndsAt
+	case req.Duration != "":
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid duration"})
+			return
+		}
+		silence.EndsAt = silence.StartsAt.Add(d)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endsAt or duration is required"})
+		return
+	}
+	if !silence.EndsAt.After(silence.StartsAt) || !silence.EndsAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endsAt must be in the future and after startsAt"})
+		return
+	}
+	if silence.EndsAt.Sub(silence.StartsAt) > maxSilenceDuration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("silences can last at most %s", maxSilenceDuration)})
+		return
+	}
+
+	if !canManageSilence(c, user, cs.Name, silence.Matchers, common.VerbCreate) {
+		return
+	}
+
+	id, err := cs.AlertmanagerClient.CreateSilence(c.Request.Context(), silence)
+	if err != nil {
+		c.JSON(alertmanagerErrorStatus(err), gin.H{"error": fmt.Sprintf("failed to create silence: %v", err)})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": id, "message": "silence created successfully"})
+}
+
+// ExpireSilence expires a silence, subject to the delete verb on "silences"
+// in the namespace of the silence.
+func ExpireSilence(c *gin.Context) {
+	cs, ok := alertmanagerClient(c)
+	if !ok {
+		return
+	}
+	user := c.MustGet("user").(model.User)
+
+	silence, err := cs.AlertmanagerClient.GetSilence(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(alertmanagerErrorStatus(err), gin.H{"error": fmt.Sprintf("failed to get silence: %v", err)})
+		return
+	}
+	if !canManageSilence(c, user, cs.Name, silence.Matchers, common.VerbDelete) {
+		return
+	}
+	if err := cs.AlertmanagerClient.ExpireSilence(c.Request.Context(), silence.ID); err != nil {
+		c.JSON(alertmanagerErrorStatus(err), gin.H{"error": fmt.Sprintf("failed to expire silence: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "silence expired successfully"})
+}
+
+// canManageSilence checks the RBAC verb on silences in the namespace the
+// matchers select and writes the error response if it is not allowed.
+func canManageSilence(c *gin.Context, user model.User, clusterName string, matchers []alertmanager.Matcher, verb common.Verb) bool {
+	if rbac.UserHasRole(user, model.DefaultAdminRole.Name) {
+		return true
+	}
+	ns := silenceNamespace(matchers)
+	if ns == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can manage silences that are not limited to one namespace with a namespace=\"...\" matcher"})
+		return false
+	}
+	if !rbac.CanAccess(user, silenceResource, string(verb), clusterName, ns) {
+		c.JSON(http.StatusForbidden, gin.H{"error": rbac.NoAccess(user.Key(), string(verb), silenceResource, ns, clusterName)})
+		return false
+	}
+	return true
+}
+
+// silenceNamespace returns the namespace of a namespace="..." matcher, or ""
+// if the silence may match alerts of several namespaces.
+func silenceNamespace(matchers []alertmanager.Matcher) string {
+	ns := ""
+	for _, m := range matchers {
+		if m.Name != alertmanager.NamespaceLabel {
+			continue
+		}
+		if !m.Equal() || m.Value == "" || (ns != "" && ns != m.Value) {
+			return ""
+		}
+		ns = m.Value
+	}
+	return ns
+}
+
+func alertmanagerErrorStatus(err error) int {
+	var apiErr *alertmanager.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 {
+		return apiErr.StatusCode
+	}
+	return http.StatusBadGateway
+}
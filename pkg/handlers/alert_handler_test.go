@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/pixelvide/kube-sentinel/pkg/alertmanager"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSilenceNamespace(t *testing.T) {
+	notEqual := false
+	tests := []struct {
+		name     string
+		matchers []alertmanager.Matcher
+		expected string
+	}{
+		{"namespace matcher", []alertmanager.Matcher{{Name: "alertname", Value: "X"}, {Name: "namespace", Value: "dev"}}, "dev"},
+		{"no namespace matcher", []alertmanager.Matcher{{Name: "alertname", Value: "X"}}, ""},
+		{"regex namespace", []alertmanager.Matcher{{Name: "namespace", Value: "dev|prod", IsRegex: true}}, ""},
+		{"negative namespace", []alertmanager.Matcher{{Name: "namespace", Value: "dev", IsEqual: &notEqual}}, ""},
+		{"conflicting namespaces", []alertmanager.Matcher{{Name: "namespace", Value: "dev"}, {Name: "namespace", Value: "prod"}}, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, silenceNamespace(tc.matchers))
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/handlers/resources"
+	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// bulkConcurrency is the number of objects changed at the same time.
+	bulkConcurrency = 5
+	// bulkMaxObjects is the most objects one bulk operation may change.
+	bulkMaxObjects = 500
+)
+
+// Bulk operations
+const (
+	BulkDelete   = "delete"
+	BulkLabel    = "label"
+	BulkAnnotate = "annotate"
+	BulkScale    = "scale"
+	BulkPatch    = "patch"
+)
+
+// BulkRequest selects objects of one kind by name or label selector and
+// the operation to run on them.
+type BulkRequest struct {
+	// Kind is a resource such as "jobs", a kind such as "Job", or the CRD
+	// name of a custom resource
+	Kind      string   `json:"kind" binding:"required"`
+	Namespace string   `json:"namespace"`
+	Names     []string `json:"names"`
+	// Selector is a label selector, used when Names is empty
+	Selector  string `json:"selector"`
+	Operation string `json:"operation" binding:"required"`
+
+	// Add and Remove are the labels or annotations to set and to remove
+	Add    map[string]string `json:"add"`
+	Remove []string          `json:"remove"`
+	// Replicas is the replica count of a scale operation
+	Replicas *int32 `json:"replicas"`
+	// Patch and PatchType ("merge", "strategic" or "json") are the patch of a
+	// patch operation
+	Patch     json.RawMessage `json:"patch"`
+	PatchType string          `json:"patchType"`
+
+	// DryRun lists the objects that would be affected without changing them
+	DryRun bool `json:"dryRun"`
+}
+
+// BulkResult is the outcome of the operation on one object.
+type BulkResult struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+
+	prev *unstructured.Unstructured
+	obj  *unstructured.Unstructured
+}
+
+// BulkOperation deletes, labels, annotates, scales or patches the objects of
+// a kind given by name or label selector. Access is checked for every
+// object, the objects are changed a few at a time and each change is
+// recorded in the audit log.
+func BulkOperation(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+
+	var req BulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	patchType, patch, err := bulkPatch(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	verb := common.VerbUpdate
+	action := "patch"
+	if req.Operation == BulkDelete {
+		verb = common.VerbDelete
+		action = "delete"
+	}
+
+	mapping, err := resolveFederatedResource(cs.K8sClient.RESTMapper(), req.Kind)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+	if namespaced && req.Namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for " + mapping.Resource.Resource})
+		return
+	}
+	if !namespaced {
+		req.Namespace = ""
+	}
+	resource := kube.ResourceName(mapping.Resource)
+	scalable := false
+	if req.Operation == BulkScale {
+		if scalable, err = hasScaleSubresource(cs, mapping); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to discover the scale subresource: " + err.Error()})
+			return
+		}
+	}
+
+	objects, results, err := bulkObjects(c, cs, mapping, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(objects)+len(results) > bulkMaxObjects {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("a bulk operation can change at most %d objects, %d match", bulkMaxObjects, len(objects)+len(results))})
+		return
+	}
+
+	var allowed []*BulkResult
+	for _, obj := range objects {
+		result := &BulkResult{Name: obj.GetName(), Namespace: obj.GetNamespace(), prev: obj, obj: obj.DeepCopy()}
+		results = append(results, result)
+		rbacNamespace := obj.GetNamespace()
+		if rbacNamespace == "" {
+			rbacNamespace = "_all"
+		}
+		if !rbac.CanAccess(user, resource, string(verb), cs.Name, rbacNamespace) {
+			result.Error = rbac.NoAccess(user.Key(), string(verb), resource, rbacNamespace, cs.Name)
+			continue
+		}
+		if req.Operation == BulkScale && !scalable {
+			result.Error = fmt.Sprintf("%s has no scale subresource", resource)
+			continue
+		}
+		if req.DryRun {
+			result.Success = true
+			continue
+		}
+		allowed = append(allowed, result)
+	}
+
+	ctx := c.Request.Context()
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, bulkConcurrency)
+	for _, result := range allowed {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(r *BulkResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			var err error
+			switch req.Operation {
+			case BulkDelete:
+				err = cs.K8sClient.Delete(ctx, r.obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+				r.obj = nil
+			case BulkScale:
+				err = scaleObject(ctx, cs, r.obj, client.RawPatch(patchType, patch))
+			default:
+				err = cs.K8sClient.Patch(ctx, r.obj, client.RawPatch(patchType, patch), client.FieldOwner(kube.FieldManager))
+			}
+			if err != nil {
+				r.Error = err.Error()
+				return
+			}
+			r.Success = true
+		}(result)
+	}
+	wg.Wait()
+
+	// Audit entries are written one at a time after the changes
+	for _, r := range allowed {
+		var curr client.Object
+		if r.obj != nil {
+			curr = r.obj
+		}
+		resources.RecordAudit(c, action, resource, r.prev, curr, r.Success, r.Error)
+	}
+
+	if results == nil {
+		results = []*BulkResult{}
+	}
+	failed := 0
+	for _, r := range results {
+		if !r.Success {
+			failed++
+		}
+	}
+	response := gin.H{
+		"kind":      mapping.GroupVersionKind.Kind,
+		"resource":  resource,
+		"operation": req.Operation,
+		"dryRun":    req.DryRun,
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+		"results":   results,
+	}
+	if failed > 0 {
+		response["error"] = fmt.Sprintf("%d of %d objects failed", failed, len(results))
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+	switch {
+	case req.DryRun:
+		response["message"] = fmt.Sprintf("%d objects would be affected", len(results))
+	case req.Operation == BulkDelete:
+		response["message"] = fmt.Sprintf("%d objects deleted", len(results))
+	default:
+		response["message"] = fmt.Sprintf("%d objects updated", len(results))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// bulkObjects returns the objects of the request, and the results of the
+// names that were not found or could not be read.
+func bulkObjects(c *gin.Context, cs *cluster.ClientSet, mapping *meta.RESTMapping, req *BulkRequest) ([]*unstructured.Unstructured, []*BulkResult, error) {
+	ctx := c.Request.Context()
+	if (len(req.Names) == 0) == (req.Selector == "") {
+		return nil, nil, fmt.Errorf("either names or selector is required")
+	}
+
+	if len(req.Names) > 0 {
+		if len(req.Names) > bulkMaxObjects {
+			return nil, nil, fmt.Errorf("a bulk operation can change at most %d objects", bulkMaxObjects)
+		}
+		var objects []*unstructured.Unstructured
+		var missing []*BulkResult
+		for _, name := range req.Names {
+			obj := &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(mapping.GroupVersionKind)
+			if err := cs.K8sClient.Get(ctx, types.NamespacedName{Namespace: req.Namespace, Name: name}, obj); err != nil {
+				message := err.Error()
+				if apierrors.IsNotFound(err) {
+					message = "not found"
+				}
+				missing = append(missing, &BulkResult{Name: name, Namespace: req.Namespace, Error: message})
+				continue
+			}
+			objects = append(objects, obj)
+		}
+		return objects, missing, nil
+	}
+
+	selector, err := labels.Parse(req.Selector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid selector: %w", err)
+	}
+	if selector.Empty() {
+		return nil, nil, fmt.Errorf("selector must not be empty")
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(mapping.GroupVersionKind.GroupVersion().WithKind(mapping.GroupVersionKind.Kind + "List"))
+	opts := []client.ListOption{client.MatchingLabelsSelector{Selector: selector}}
+	if req.Namespace != "" {
+		opts = append(opts, client.InNamespace(req.Namespace))
+	}
+	if err := cs.K8sClient.List(ctx, list, opts...); err != nil {
+		return nil, nil, err
+	}
+	objects := make([]*unstructured.Unstructured, 0, len(list.Items))
+	for i := range list.Items {
+		objects = append(objects, &list.Items[i])
+	}
+	return objects, nil, nil
+}
+
+// hasScaleSubresource reports whether the resource of mapping serves the
+// scale subresource, which knows where the replica count of the kind is.
+func hasScaleSubresource(cs *cluster.ClientSet, mapping *meta.RESTMapping) (bool, error) {
+	resources, err := cs.K8sClient.ClientSet.Discovery().ServerResourcesForGroupVersion(mapping.GroupVersionKind.GroupVersion().String())
+	if err != nil {
+		return false, err
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == mapping.Resource.Resource+"/scale" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// scaleObject patches the scale subresource of obj and reads obj back, so
+// that the audit log records its new state.
+func scaleObject(ctx context.Context, cs *cluster.ClientSet, obj *unstructured.Unstructured, patch client.Patch) error {
+	scale := &unstructured.Unstructured{}
+	scale.SetGroupVersionKind(autoscalingv1.SchemeGroupVersion.WithKind("Scale"))
+	if err := cs.K8sClient.SubResource("scale").Patch(ctx, obj, patch, client.FieldOwner(kube.FieldManager), client.WithSubResourceBody(scale)); err != nil {
+		return err
+	}
+	if err := cs.K8sClient.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+		klog.Warningf("Failed to read %s %s/%s after scaling: %v", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+	}
+	return nil
+}
+
+// bulkPatch validates the operation of req and returns the patch it applies
+// to every object.
+func bulkPatch(req *BulkRequest) (types.PatchType, []byte, error) {
+	switch req.Operation {
+	case BulkDelete:
+		return "", nil, nil
+
+	case BulkLabel, BulkAnnotate:
+		if len(req.Add) == 0 && len(req.Remove) == 0 {
+			return "", nil, fmt.Errorf("add or remove is required to %s", req.Operation)
+		}
+		values := map[string]interface{}{}
+		for _, key := range req.Remove {
+			values[key] = nil
+		}
+		for key, value := range req.Add {
+			values[key] = value
+		}
+		field := "labels"
+		if req.Operation == BulkAnnotate {
+			field = "annotations"
+		}
+		patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{field: values}})
+		return types.MergePatchType, patch, err
+
+	case BulkScale:
+		if req.Replicas == nil || *req.Replicas < 0 {
+			return "", nil, fmt.Errorf("a non-negative replicas is required to scale")
+		}
+		patch, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"replicas": *req.Replicas}})
+		return types.MergePatchType, patch, err
+
+	case BulkPatch:
+		if len(req.Patch) == 0 {
+			return "", nil, fmt.Errorf("patch is required")
+		}
+		switch req.PatchType {
+		case "", "merge":
+			return types.MergePatchType, req.Patch, nil
+		case "strategic":
+			return types.StrategicMergePatchType, req.Patch, nil
+		case "json":
+			return types.JSONPatchType, req.Patch, nil
+		}
+		return "", nil, fmt.Errorf("patchType must be one of: merge, strategic, json")
+	}
+	return "", nil, fmt.Errorf("operation must be one of: delete, label, annotate, scale, patch")
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+func TestBulkPatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       BulkRequest
+		patchType types.PatchType
+		patch     string
+		wantErr   bool
+	}{
+		{"delete", BulkRequest{Operation: BulkDelete}, "", "", false},
+		{
+			"label",
+			BulkRequest{Operation: BulkLabel, Add: map[string]string{"team": "web"}, Remove: []string{"old"}},
+			types.MergePatchType, `{"metadata":{"labels":{"old":null,"team":"web"}}}`, false,
+		},
+		{
+			"annotate",
+			BulkRequest{Operation: BulkAnnotate, Add: map[string]string{"note": "x"}},
+			types.MergePatchType, `{"metadata":{"annotations":{"note":"x"}}}`, false,
+		},
+		{"label without changes", BulkRequest{Operation: BulkLabel}, "", "", true},
+		{"scale", BulkRequest{Operation: BulkScale, Replicas: ptr.To[int32](0)}, types.MergePatchType, `{"spec":{"replicas":0}}`, false},
+		{"scale without replicas", BulkRequest{Operation: BulkScale}, "", "", true},
+		{"scale negative", BulkRequest{Operation: BulkScale, Replicas: ptr.To[int32](-1)}, "", "", true},
+		{
+			"strategic patch",
+			BulkRequest{Operation: BulkPatch, PatchType: "strategic", Patch: json.RawMessage(`{"spec":{"paused":true}}`)},
+			types.StrategicMergePatchType, `{"spec":{"paused":true}}`, false,
+		},
+		{"patch without body", BulkRequest{Operation: BulkPatch}, "", "", true},
+		{"unknown patch type", BulkRequest{Operation: BulkPatch, PatchType: "xml", Patch: json.RawMessage(`{}`)}, "", "", true},
+		{"unknown operation", BulkRequest{Operation: "restart"}, "", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			patchType, patch, err := bulkPatch(&tc.req)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.patchType, patchType)
+			if tc.patch != "" {
+				assert.JSONEq(t, tc.patch, string(patch))
+			}
+		})
+	}
+}
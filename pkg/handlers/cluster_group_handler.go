@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/alertmanager"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+type clusterGroupReq struct {
+	Name              string   `json:"name" binding:"required"`
+	Description       string   `json:"description"`
+	Selector          string   `json:"selector"`
+	DisabledAnalyzers []string `json:"disabledAnalyzers"`
+	AlertFilters      []string `json:"alertFilters"`
+}
+
+// validate checks the selector and alert filters of the request.
+func (r *clusterGroupReq) validate() error {
+	if _, err := labels.Parse(r.Selector); err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+	for _, filter := range r.AlertFilters {
+		if err := alertmanager.ValidateFilter(filter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListClusterGroups returns all cluster groups together with the clusters they select.
+func ListClusterGroups(c *gin.Context) {
+	groups, err := model.ListClusterGroups()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	clusters, err := model.ListClusters()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := make([]gin.H, 0, len(groups))
+	for i := range groups {
+		members := make([]string, 0)
+		for _, cluster := range clusters {
+			if groups[i].Matches(cluster.Labels) {
+				members = append(members, cluster.Name)
+			}
+		}
+		result = append(result, gin.H{
+			"id":                groups[i].ID,
+			"name":              groups[i].Name,
+			"description":       groups[i].Description,
+			"selector":          groups[i].Selector,
+			"disabledAnalyzers": groups[i].DisabledAnalyzers,
+			"alertFilters":      groups[i].AlertFilters,
+			"clusters":          members,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// CreateClusterGroup creates a new cluster group.
+func CreateClusterGroup(c *gin.Context) {
+	var req clusterGroupReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group := model.ClusterGroup{
+		Name:              req.Name,
+		Description:       req.Description,
+		Selector:          req.Selector,
+		DisabledAnalyzers: req.DisabledAnalyzers,
+		AlertFilters:      req.AlertFilters,
+	}
+	if err := model.AddClusterGroup(&group); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create cluster group: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": group})
+}
+
+// UpdateClusterGroup updates an existing cluster group.
+func UpdateClusterGroup(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cluster group id"})
+		return
+	}
+	var req clusterGroupReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := model.GetClusterGroupByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cluster group not found"})
+		return
+	}
+	group.Name = req.Name
+	group.Description = req.Description
+	group.Selector = req.Selector
+	group.DisabledAnalyzers = req.DisabledAnalyzers
+	group.AlertFilters = req.AlertFilters
+	if err := model.UpdateClusterGroup(group); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update cluster group: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": group})
+}
+
+// DeleteClusterGroup deletes a cluster group.
+func DeleteClusterGroup(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cluster group id"})
+		return
+	}
+	if err := model.DeleteClusterGroup(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete cluster group: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "cluster group deleted successfully"})
+}
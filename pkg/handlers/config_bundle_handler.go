@@ -0,0 +1,975 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	"github.com/pixelvide/kube-sentinel/pkg/utils"
+	"github.com/pixelvide/kube-sentinel/pkg/version"
+	"gorm.io/gorm"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	configBundleAPIVersion = "kube-sentinel.io/v1"
+	configBundleKind       = "ConfigBundle"
+	configBundleMaxSize    = 32 << 20
+
+	// exportKeyHeader encrypts the secrets of an export with another key than
+	// KUBE_SENTINEL_ENCRYPT_KEY, e.g. the key of the target instance.
+	exportKeyHeader = "X-Export-Encrypt-Key"
+	// sourceKeyHeader is the key the secrets of an imported bundle were
+	// encrypted with, when it differs from KUBE_SENTINEL_ENCRYPT_KEY.
+	sourceKeyHeader = "X-Source-Encrypt-Key"
+
+	configImportMerge   = "merge"
+	configImportReplace = "replace"
+)
+
+var errConfigImportDryRun = errors.New("dry run")
+
+// ConfigBundle is a versioned backup of the configuration stored in the
+// database. Secrets are kept encrypted; keyFingerprint identifies the key.
+type ConfigBundle struct {
+	APIVersion     string    `json:"apiVersion"`
+	Kind           string    `json:"kind"`
+	AppVersion     string    `json:"appVersion"`
+	ExportedAt     time.Time `json:"exportedAt"`
+	KeyFingerprint string    `json:"keyFingerprint"`
+
+	Clusters       []bundleCluster       `json:"clusters"`
+	Roles          []bundleRole          `json:"roles"`
+	OAuthProviders []bundleOAuthProvider `json:"oauthProviders"`
+	AIProfiles     []bundleAIProfile     `json:"aiProfiles"`
+	Templates      []bundleTemplate      `json:"templates"`
+	Knowledge      []bundleKnowledge     `json:"knowledge"`
+	AppConfigs     []bundleAppConfig     `json:"appConfigs"`
+}
+
+type bundleCluster struct {
+	Name            string              `json:"name"`
+	Description     string              `json:"description,omitempty"`
+	Config          string              `json:"config,omitempty"`
+	PrometheusURL   string              `json:"prometheusURL,omitempty"`
+	AlertmanagerURL string              `json:"alertmanagerURL,omitempty"`
+	InCluster       bool                `json:"inCluster,omitempty"`
+	IsDefault       bool                `json:"isDefault,omitempty"`
+	Enabled         bool                `json:"enabled"`
+	SkipSystemSync  bool                `json:"skipSystemSync,omitempty"`
+	Labels          map[string]string   `json:"labels,omitempty"`
+	CachePolicy     *common.CachePolicy `json:"cachePolicy,omitempty"`
+	Pricing         *model.CostPricing  `json:"pricing,omitempty"`
+
+	// PrometheusConfig is the JSON encoded model.PrometheusConfig, encrypted
+	// like the other secrets since it carries credentials.
+	PrometheusConfig string `json:"prometheusConfig,omitempty"`
+}
+
+type bundleRole struct {
+	Name            string                 `json:"name"`
+	Description     string                 `json:"description,omitempty"`
+	IsSystem        bool                   `json:"isSystem,omitempty"`
+	Clusters        []string               `json:"clusters,omitempty"`
+	ClusterSelector string                 `json:"clusterSelector,omitempty"`
+	Namespaces      []string               `json:"namespaces,omitempty"`
+	Resources       []string               `json:"resources,omitempty"`
+	Verbs           []string               `json:"verbs,omitempty"`
+	Assignments     []bundleRoleAssignment `json:"assignments,omitempty"`
+}
+
+type bundleRoleAssignment struct {
+	SubjectType string `json:"subjectType"`
+	Subject     string `json:"subject"`
+}
+
+type bundleOAuthProvider struct {
+	Name         string `json:"name"`
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+	AuthURL      string `json:"authUrl,omitempty"`
+	TokenURL     string `json:"tokenUrl,omitempty"`
+	UserInfoURL  string `json:"userInfoUrl,omitempty"`
+	Scopes       string `json:"scopes,omitempty"`
+	Issuer       string `json:"issuer,omitempty"`
+	Enabled      bool   `json:"enabled"`
+}
+
+type bundleAIProfile struct {
+	Name              string   `json:"name"`
+	Provider          string   `json:"provider"`
+	BaseURL           string   `json:"baseUrl,omitempty"`
+	DefaultModel      string   `json:"defaultModel,omitempty"`
+	APIKey            string   `json:"apiKey,omitempty"`
+	IsSystem          bool     `json:"isSystem,omitempty"`
+	IsEnabled         bool     `json:"isEnabled"`
+	AllowUserOverride bool     `json:"allowUserOverride,omitempty"`
+	AllowedModels     []string `json:"allowedModels,omitempty"`
+}
+
+type bundleTemplate struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	YAML        string `json:"yaml"`
+}
+
+type bundleKnowledge struct {
+	Cluster  string          `json:"cluster"`
+	Content  string          `json:"content"`
+	AddedBy  string          `json:"addedBy,omitempty"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+type bundleAppConfig struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// keyFingerprint identifies an encryption key without revealing it.
+func keyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte("kube-sentinel-bundle:" + key))
+	return fmt.Sprintf("%x", sum[:8])
+}
+
+func toBundleCluster(c model.Cluster, secret func(model.SecretString) string) bundleCluster {
+	return bundleCluster{
+		Name:            c.Name,
+		Description:     c.Description,
+		Config:          secret(c.Config),
+		PrometheusURL:   c.PrometheusURL,
+		AlertmanagerURL: c.AlertmanagerURL,
+		InCluster:       c.InCluster,
+		IsDefault:       c.IsDefault,
+		Enabled:         c.Enable,
+		SkipSystemSync:  c.SkipSystemSync,
+		Labels:          c.Labels,
+		CachePolicy:     c.CachePolicy,
+		Pricing:         c.Pricing,
+
+		PrometheusConfig: secret(prometheusConfigSecret(c.PrometheusConfig)),
+	}
+}
+
+func prometheusConfigSecret(cfg *model.PrometheusConfig) model.SecretString {
+	if cfg.IsZero() {
+		return ""
+	}
+	data, _ := json.Marshal(cfg)
+	return model.SecretString(data)
+}
+
+func toBundleRole(r model.Role) bundleRole {
+	role := bundleRole{
+		Name:            r.Name,
+		Description:     r.Description,
+		IsSystem:        r.IsSystem,
+		Clusters:        r.Clusters,
+		ClusterSelector: r.ClusterSelector,
+		Namespaces:      r.Namespaces,
+		Resources:       r.Resources,
+		Verbs:           r.Verbs,
+	}
+	for _, a := range r.Assignments {
+		role.Assignments = append(role.Assignments, bundleRoleAssignment{SubjectType: a.SubjectType, Subject: a.Subject})
+	}
+	return role
+}
+
+func toBundleOAuthProvider(p model.OAuthProvider, secret func(model.SecretString) string) bundleOAuthProvider {
+	return bundleOAuthProvider{
+		Name:         string(p.Name),
+		ClientID:     p.ClientID,
+		ClientSecret: secret(p.ClientSecret),
+		AuthURL:      p.AuthURL,
+		TokenURL:     p.TokenURL,
+		UserInfoURL:  p.UserInfoURL,
+		Scopes:       p.Scopes,
+		Issuer:       p.Issuer,
+		Enabled:      p.Enabled,
+	}
+}
+
+func toBundleAIProfile(p model.AIProviderProfile, secret func(model.SecretString) string) bundleAIProfile {
+	return bundleAIProfile{
+		Name:              p.Name,
+		Provider:          p.Provider,
+		BaseURL:           p.BaseURL,
+		DefaultModel:      p.DefaultModel,
+		APIKey:            secret(p.APIKey),
+		IsSystem:          p.IsSystem,
+		IsEnabled:         p.IsEnabled,
+		AllowUserOverride: p.AllowUserOverride,
+		AllowedModels:     p.AllowedModels,
+	}
+}
+
+func plainSecret(s model.SecretString) string {
+	return string(s)
+}
+
+// exportConfigBundle reads the configuration from the database and encrypts
+// its secrets with key.
+func exportConfigBundle(key string) (*ConfigBundle, error) {
+	var encryptErr error
+	secret := func(s model.SecretString) string {
+		if s == "" {
+			return ""
+		}
+		encrypted := utils.EncryptStringWithKey(string(s), key)
+		if len(encrypted) > 17 && encrypted[:17] == "encryption_error:" {
+			encryptErr = errors.New(encrypted)
+		}
+		return encrypted
+	}
+
+	bundle := &ConfigBundle{
+		APIVersion:     configBundleAPIVersion,
+		Kind:           configBundleKind,
+		AppVersion:     version.Version,
+		ExportedAt:     time.Now().UTC(),
+		KeyFingerprint: keyFingerprint(key),
+	}
+
+	var clusters []model.Cluster
+	if err := model.DB.Order("name").Find(&clusters).Error; err != nil {
+		return nil, err
+	}
+	clusterNames := make(map[uint]string, len(clusters))
+	for _, c := range clusters {
+		clusterNames[c.ID] = c.Name
+		bundle.Clusters = append(bundle.Clusters, toBundleCluster(c, secret))
+	}
+
+	var roles []model.Role
+	if err := model.DB.Preload("Assignments").Order("name").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range roles {
+		bundle.Roles = append(bundle.Roles, toBundleRole(r))
+	}
+
+	providers, err := model.GetAllOAuthProviders()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range providers {
+		bundle.OAuthProviders = append(bundle.OAuthProviders, toBundleOAuthProvider(p, secret))
+	}
+
+	var profiles []model.AIProviderProfile
+	if err := model.DB.Order("name").Find(&profiles).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		bundle.AIProfiles = append(bundle.AIProfiles, toBundleAIProfile(p, secret))
+	}
+
+	var templates []model.ResourceTemplate
+	if err := model.DB.Order("name").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range templates {
+		bundle.Templates = append(bundle.Templates, bundleTemplate{Name: t.Name, Description: t.Description, YAML: t.YAML})
+	}
+
+	var knowledge []model.ClusterKnowledgeBase
+	if err := model.DB.Order("id").Find(&knowledge).Error; err != nil {
+		return nil, err
+	}
+	for _, k := range knowledge {
+		name, ok := clusterNames[k.ClusterID]
+		if !ok {
+			continue
+		}
+		bundle.Knowledge = append(bundle.Knowledge, bundleKnowledge{
+			Cluster:  name,
+			Content:  k.Content,
+			AddedBy:  k.AddedBy,
+			Metadata: json.RawMessage(k.Metadata),
+		})
+	}
+
+	configs, err := model.GetAppConfigs(model.CurrentApp.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range configs {
+		bundle.AppConfigs = append(bundle.AppConfigs, bundleAppConfig{Key: c.Key, Value: c.Value})
+	}
+
+	if encryptErr != nil {
+		return nil, encryptErr
+	}
+	return bundle, nil
+}
+
+// ConfigImportChange describes a change made (or previewed) by an import.
+type ConfigImportChange struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Action string `json:"action"` // create, update, delete or skip
+	Reason string `json:"reason,omitempty"`
+}
+
+type configImporter struct {
+	tx      *gorm.DB
+	replace bool
+	key     string
+	changes []ConfigImportChange
+}
+
+func (imp *configImporter) record(kind, name, action string) {
+	imp.changes = append(imp.changes, ConfigImportChange{Kind: kind, Name: name, Action: action})
+}
+
+func (imp *configImporter) skipManaged(kind, name string) {
+	imp.changes = append(imp.changes, ConfigImportChange{
+		Kind:   kind,
+		Name:   name,
+		Action: "skip",
+		Reason: "managed by the configuration file",
+	})
+}
+
+func (imp *configImporter) decrypt(kind, name, encrypted string) (model.SecretString, error) {
+	if encrypted == "" {
+		return "", nil
+	}
+	plain, err := utils.DecryptStringWithKey(encrypted, imp.key)
+	if err != nil {
+		return "", fmt.Errorf("%s %q: cannot decrypt secret: %w", kind, name, err)
+	}
+	return model.SecretString(plain), nil
+}
+
+// sameJSON compares two bundle entries; omitempty makes nil and empty
+// slices and maps equal.
+func sameJSON(a, b interface{}) bool {
+	x, errA := json.Marshal(a)
+	y, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(x, y)
+}
+
+// importConfigBundle writes bundle into the database in a single
+// transaction. In merge mode records missing from the bundle are kept; in
+// replace mode they are deleted. With dryRun the transaction is rolled back
+// and only the changes are returned. Records managed by the configuration
+// file are never modified.
+func importConfigBundle(bundle *ConfigBundle, mode string, dryRun bool, key string) ([]ConfigImportChange, error) {
+	imp := &configImporter{replace: mode == configImportReplace, key: key, changes: []ConfigImportChange{}}
+	err := model.DB.Transaction(func(tx *gorm.DB) error {
+		imp.tx = tx
+		if err := imp.clusters(bundle.Clusters); err != nil {
+			return err
+		}
+		if err := imp.roles(bundle.Roles); err != nil {
+			return err
+		}
+		if err := imp.oauthProviders(bundle.OAuthProviders); err != nil {
+			return err
+		}
+		if err := imp.aiProfiles(bundle.AIProfiles); err != nil {
+			return err
+		}
+		if err := imp.templates(bundle.Templates); err != nil {
+			return err
+		}
+		if err := imp.knowledge(bundle.Knowledge); err != nil {
+			return err
+		}
+		if err := imp.appConfigs(bundle.AppConfigs); err != nil {
+			return err
+		}
+		if dryRun {
+			return errConfigImportDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errConfigImportDryRun) {
+		err = nil
+	}
+	return imp.changes, err
+}
+
+func (imp *configImporter) clusters(items []bundleCluster) error {
+	var existing []model.Cluster
+	if err := imp.tx.Find(&existing).Error; err != nil {
+		return err
+	}
+	byName := make(map[string]model.Cluster, len(existing))
+	for _, c := range existing {
+		byName[c.Name] = c
+	}
+
+	seen := map[string]bool{}
+	for _, item := range items {
+		seen[item.Name] = true
+		config, err := imp.decrypt("cluster", item.Name, item.Config)
+		if err != nil {
+			return err
+		}
+		promConfigData, err := imp.decrypt("cluster", item.Name, item.PrometheusConfig)
+		if err != nil {
+			return err
+		}
+		var promConfig *model.PrometheusConfig
+		if promConfigData != "" {
+			promConfig = &model.PrometheusConfig{}
+			if err := json.Unmarshal([]byte(promConfigData), promConfig); err != nil {
+				return fmt.Errorf("cluster %q: invalid prometheusConfig: %w", item.Name, err)
+			}
+		}
+		plain := item
+		plain.Config = string(config)
+		plain.PrometheusConfig = string(promConfigData)
+
+		cur, ok := byName[item.Name]
+		if ok && cur.ManagedBy == model.ManagedByConfig {
+			imp.skipManaged("cluster", item.Name)
+			continue
+		}
+		if ok && sameJSON(toBundleCluster(cur, plainSecret), plain) {
+			continue
+		}
+		if item.IsDefault {
+			if err := imp.tx.Model(&model.Cluster{}).Where("is_default = ? AND name <> ?", true, item.Name).Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		values := map[string]interface{}{
+			"description":      item.Description,
+			"config":           config,
+			"prometheus_url":   item.PrometheusURL,
+			"alertmanager_url": item.AlertmanagerURL,
+			"in_cluster":       item.InCluster,
+			"is_default":       item.IsDefault,
+			"enable":           item.Enabled,
+			"skip_system_sync": item.SkipSystemSync,
+			"labels":           model.MapString(item.Labels),
+			"cache_policy":     item.CachePolicy,
+		}
+		if promConfig != nil {
+			values["prometheus_config"] = *promConfig
+		} else {
+			values["prometheus_config"] = nil
+		}
+		if item.Pricing != nil {
+			values["pricing"] = *item.Pricing
+		} else {
+			values["pricing"] = nil
+		}
+		if !ok {
+			imp.record("cluster", item.Name, "create")
+			cur = model.Cluster{Name: item.Name}
+			if err := imp.tx.Create(&cur).Error; err != nil {
+				return fmt.Errorf("cluster %q: %w", item.Name, err)
+			}
+		} else {
+			imp.record("cluster", item.Name, "update")
+		}
+		if err := imp.tx.Model(&cur).Updates(values).Error; err != nil {
+			return fmt.Errorf("cluster %q: %w", item.Name, err)
+		}
+	}
+
+	if !imp.replace {
+		return nil
+	}
+	for _, cur := range existing {
+		if seen[cur.Name] || cur.ManagedBy == model.ManagedByConfig {
+			continue
+		}
+		imp.record("cluster", cur.Name, "delete")
+		if err := imp.tx.Where("cluster_id = ?", cur.ID).Delete(&model.ClusterKnowledgeBase{}).Error; err != nil {
+			return err
+		}
+		if err := imp.tx.Delete(&cur).Error; err != nil {
+			return fmt.Errorf("cluster %q: %w", cur.Name, err)
+		}
+	}
+	return nil
+}
+
+func (imp *configImporter) roles(items []bundleRole) error {
+	var existing []model.Role
+	if err := imp.tx.Preload("Assignments").Find(&existing).Error; err != nil {
+		return err
+	}
+	byName := make(map[string]model.Role, len(existing))
+	for _, r := range existing {
+		byName[r.Name] = r
+	}
+
+	seen := map[string]bool{}
+	for _, item := range items {
+		seen[item.Name] = true
+		cur, ok := byName[item.Name]
+		if ok && cur.ManagedBy == model.ManagedByConfig {
+			imp.skipManaged("role", item.Name)
+			continue
+		}
+
+		if !ok {
+			imp.record("role", item.Name, "create")
+			cur = model.Role{Name: item.Name}
+		}
+		// Rules of the built-in roles are fixed; only assignments are imported
+		rulesChanged := !cur.IsSystem && !sameJSON(withoutAssignments(toBundleRole(cur)), withoutAssignments(item))
+		if ok && rulesChanged {
+			imp.record("role", item.Name, "update")
+		}
+		if !ok || rulesChanged {
+			if err := rbac.ValidateClusterSelector(item.ClusterSelector); err != nil {
+				return fmt.Errorf("role %q: %w", item.Name, err)
+			}
+			cur.Description = item.Description
+			cur.Clusters = item.Clusters
+			cur.ClusterSelector = item.ClusterSelector
+			cur.Namespaces = item.Namespaces
+			cur.Resources = item.Resources
+			cur.Verbs = item.Verbs
+			if err := imp.tx.Omit("Assignments").Save(&cur).Error; err != nil {
+				return fmt.Errorf("role %q: %w", item.Name, err)
+			}
+		}
+		if err := imp.roleAssignments(cur, item.Assignments); err != nil {
+			return err
+		}
+	}
+
+	if !imp.replace {
+		return nil
+	}
+	for _, cur := range existing {
+		if seen[cur.Name] || cur.IsSystem || cur.ManagedBy == model.ManagedByConfig {
+			continue
+		}
+		imp.record("role", cur.Name, "delete")
+		if err := imp.tx.Where("role_id = ?", cur.ID).Delete(&model.RoleAssignment{}).Error; err != nil {
+			return err
+		}
+		if err := imp.tx.Delete(&cur).Error; err != nil {
+			return fmt.Errorf("role %q: %w", cur.Name, err)
+		}
+	}
+	return nil
+}
+
+func withoutAssignments(r bundleRole) bundleRole {
+	r.Assignments = nil
+	r.IsSystem = false
+	return r
+}
+
+func (imp *configImporter) roleAssignments(role model.Role, items []bundleRoleAssignment) error {
+	wanted := map[bundleRoleAssignment]bool{}
+	for _, a := range items {
+		wanted[a] = true
+	}
+	for _, a := range role.Assignments {
+		key := bundleRoleAssignment{SubjectType: a.SubjectType, Subject: a.Subject}
+		if wanted[key] {
+			delete(wanted, key)
+			continue
+		}
+		if !imp.replace || a.ManagedBy == model.ManagedByConfig {
+			continue
+		}
+		imp.record("roleAssignment", fmt.Sprintf("%s/%s:%s", role.Name, a.SubjectType, a.Subject), "delete")
+		if err := imp.tx.Delete(&a).Error; err != nil {
+			return err
+		}
+	}
+	for _, a := range items {
+		if !wanted[a] {
+			continue
+		}
+		if a.SubjectType != model.SubjectTypeUser && a.SubjectType != model.SubjectTypeGroup {
+			return fmt.Errorf("role %q: invalid subject type %q", role.Name, a.SubjectType)
+		}
+		delete(wanted, a)
+		imp.record("roleAssignment", fmt.Sprintf("%s/%s:%s", role.Name, a.SubjectType, a.Subject), "create")
+		if err := imp.tx.Create(&model.RoleAssignment{RoleID: role.ID, SubjectType: a.SubjectType, Subject: a.Subject}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (imp *configImporter) oauthProviders(items []bundleOAuthProvider) error {
+	var existing []model.OAuthProvider
+	if err := imp.tx.Where("app_id = ?", model.CurrentApp.ID).Find(&existing).Error; err != nil {
+		return err
+	}
+	byName := make(map[string]model.OAuthProvider, len(existing))
+	for _, p := range existing {
+		byName[string(p.Name)] = p
+	}
+
+	seen := map[string]bool{}
+	for _, item := range items {
+		name := strings.ToLower(item.Name)
+		seen[name] = true
+		secret, err := imp.decrypt("oauthProvider", name, item.ClientSecret)
+		if err != nil {
+			return err
+		}
+		plain := item
+		plain.Name = name
+		plain.ClientSecret = string(secret)
+
+		cur, ok := byName[name]
+		if ok && cur.ManagedBy == model.ManagedByConfig {
+			imp.skipManaged("oauthProvider", name)
+			continue
+		}
+		if ok && sameJSON(toBundleOAuthProvider(cur, plainSecret), plain) {
+			continue
+		}
+		values := map[string]interface{}{
+			"client_id":     item.ClientID,
+			"client_secret": secret,
+			"auth_url":      item.AuthURL,
+			"token_url":     item.TokenURL,
+			"user_info_url": item.UserInfoURL,
+			"scopes":        item.Scopes,
+			"issuer":        item.Issuer,
+			"enabled":       item.Enabled,
+		}
+		if !ok {
+			imp.record("oauthProvider", name, "create")
+			cur = model.OAuthProvider{AppID: model.CurrentApp.ID, Name: model.LowerCaseString(name), ClientID: item.ClientID, ClientSecret: secret}
+			if err := imp.tx.Create(&cur).Error; err != nil {
+				return fmt.Errorf("oauth provider %q: %w", name, err)
+			}
+		} else {
+			imp.record("oauthProvider", name, "update")
+		}
+		if err := imp.tx.Model(&cur).Updates(values).Error; err != nil {
+			return fmt.Errorf("oauth provider %q: %w", name, err)
+		}
+	}
+
+	if !imp.replace {
+		return nil
+	}
+	for _, cur := range existing {
+		if seen[string(cur.Name)] || cur.ManagedBy == model.ManagedByConfig {
+			continue
+		}
+		imp.record("oauthProvider", string(cur.Name), "delete")
+		if err := imp.tx.Delete(&cur).Error; err != nil {
+			return fmt.Errorf("oauth provider %q: %w", cur.Name, err)
+		}
+	}
+	return nil
+}
+
+func (imp *configImporter) aiProfiles(items []bundleAIProfile) error {
+	var existing []model.AIProviderProfile
+	if err := imp.tx.Find(&existing).Error; err != nil {
+		return err
+	}
+	byName := make(map[string]model.AIProviderProfile, len(existing))
+	for _, p := range existing {
+		byName[p.Name] = p
+	}
+
+	seen := map[string]bool{}
+	for _, item := range items {
+		seen[item.Name] = true
+		apiKey, err := imp.decrypt("aiProfile", item.Name, item.APIKey)
+		if err != nil {
+			return err
+		}
+		plain := item
+		plain.APIKey = string(apiKey)
+
+		cur, ok := byName[item.Name]
+		if ok && cur.ManagedBy == model.ManagedByConfig {
+			imp.skipManaged("aiProfile", item.Name)
+			continue
+		}
+		if ok && sameJSON(toBundleAIProfile(cur, plainSecret), plain) {
+			continue
+		}
+		if item.IsSystem {
+			if err := imp.tx.Model(&model.AIProviderProfile{}).Where("is_system = ? AND name <> ?", true, item.Name).Update("is_system", false).Error; err != nil {
+				return err
+			}
+		}
+		values := map[string]interface{}{
+			"provider":            item.Provider,
+			"base_url":            item.BaseURL,
+			"default_model":       item.DefaultModel,
+			"api_key":             apiKey,
+			"is_system":           item.IsSystem,
+			"is_enabled":          item.IsEnabled,
+			"allow_user_override": item.AllowUserOverride,
+			"allowed_models":      model.SliceString(item.AllowedModels),
+		}
+		if !ok {
+			imp.record("aiProfile", item.Name, "create")
+			cur = model.AIProviderProfile{Name: item.Name}
+			if err := imp.tx.Create(&cur).Error; err != nil {
+				return fmt.Errorf("ai profile %q: %w", item.Name, err)
+			}
+		} else {
+			imp.record("aiProfile", item.Name, "update")
+		}
+		if err := imp.tx.Model(&cur).Updates(values).Error; err != nil {
+			return fmt.Errorf("ai profile %q: %w", item.Name, err)
+		}
+	}
+
+	if !imp.replace {
+		return nil
+	}
+	for _, cur := range existing {
+		if seen[cur.Name] || cur.ManagedBy == model.ManagedByConfig {
+			continue
+		}
+		imp.record("aiProfile", cur.Name, "delete")
+		if err := imp.tx.Delete(&cur).Error; err != nil {
+			return fmt.Errorf("ai profile %q: %w", cur.Name, err)
+		}
+	}
+	return nil
+}
+
+func (imp *configImporter) templates(items []bundleTemplate) error {
+	var existing []model.ResourceTemplate
+	if err := imp.tx.Find(&existing).Error; err != nil {
+		return err
+	}
+	byName := make(map[string]model.ResourceTemplate, len(existing))
+	for _, t := range existing {
+		byName[t.Name] = t
+	}
+
+	seen := map[string]bool{}
+	for _, item := range items {
+		seen[item.Name] = true
+		cur, ok := byName[item.Name]
+		switch {
+		case !ok:
+			imp.record("template", item.Name, "create")
+			cur = model.ResourceTemplate{Name: item.Name, Description: item.Description, YAML: item.YAML}
+		case cur.Description != item.Description || cur.YAML != item.YAML:
+			imp.record("template", item.Name, "update")
+			cur.Description = item.Description
+			cur.YAML = item.YAML
+		default:
+			continue
+		}
+		if err := imp.tx.Save(&cur).Error; err != nil {
+			return fmt.Errorf("template %q: %w", item.Name, err)
+		}
+	}
+
+	if !imp.replace {
+		return nil
+	}
+	for _, cur := range existing {
+		if seen[cur.Name] {
+			continue
+		}
+		imp.record("template", cur.Name, "delete")
+		if err := imp.tx.Delete(&cur).Error; err != nil {
+			return fmt.Errorf("template %q: %w", cur.Name, err)
+		}
+	}
+	return nil
+}
+
+// knowledge entries have no name; they are matched by cluster and content.
+func (imp *configImporter) knowledge(items []bundleKnowledge) error {
+	var clusters []model.Cluster
+	if err := imp.tx.Select("id", "name").Find(&clusters).Error; err != nil {
+		return err
+	}
+	clusterIDs := make(map[string]uint, len(clusters))
+	clusterNames := make(map[uint]string, len(clusters))
+	for _, c := range clusters {
+		clusterIDs[c.Name] = c.ID
+		clusterNames[c.ID] = c.Name
+	}
+
+	var existing []model.ClusterKnowledgeBase
+	if err := imp.tx.Find(&existing).Error; err != nil {
+		return err
+	}
+	type entryKey struct {
+		cluster uint
+		content string
+	}
+	byKey := make(map[entryKey]model.ClusterKnowledgeBase, len(existing))
+	for _, k := range existing {
+		byKey[entryKey{k.ClusterID, k.Content}] = k
+	}
+
+	seen := map[entryKey]bool{}
+	for _, item := range items {
+		clusterID, ok := clusterIDs[item.Cluster]
+		if !ok {
+			imp.changes = append(imp.changes, ConfigImportChange{Kind: "knowledge", Name: item.Cluster, Action: "skip", Reason: "cluster not found"})
+			continue
+		}
+		key := entryKey{clusterID, item.Content}
+		seen[key] = true
+		if _, ok := byKey[key]; ok {
+			continue
+		}
+		imp.record("knowledge", item.Cluster, "create")
+		entry := model.ClusterKnowledgeBase{ClusterID: clusterID, Content: item.Content, AddedBy: item.AddedBy, Metadata: []byte(item.Metadata)}
+		if err := imp.tx.Create(&entry).Error; err != nil {
+			return fmt.Errorf("knowledge for cluster %q: %w", item.Cluster, err)
+		}
+	}
+
+	if !imp.replace {
+		return nil
+	}
+	for key, cur := range byKey {
+		if seen[key] {
+			continue
+		}
+		imp.record("knowledge", clusterNames[cur.ClusterID], "delete")
+		if err := imp.tx.Delete(&cur).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appConfigs are never deleted, as missing keys fall back to their defaults
+// only on creation.
+func (imp *configImporter) appConfigs(items []bundleAppConfig) error {
+	var existing []model.AppConfig
+	if err := imp.tx.Where("app_id = ?", model.CurrentApp.ID).Find(&existing).Error; err != nil {
+		return err
+	}
+	byKey := make(map[string]model.AppConfig, len(existing))
+	for _, c := range existing {
+		byKey[c.Key] = c
+	}
+
+	for _, item := range items {
+		cur, ok := byKey[item.Key]
+		switch {
+		case ok && cur.ManagedBy == model.ManagedByConfig:
+			if cur.Value != item.Value {
+				imp.skipManaged("appConfig", item.Key)
+			}
+			continue
+		case !ok:
+			imp.record("appConfig", item.Key, "create")
+			cur = model.AppConfig{AppID: model.CurrentApp.ID, Key: item.Key}
+		case cur.Value != item.Value:
+			imp.record("appConfig", item.Key, "update")
+		default:
+			continue
+		}
+		cur.Value = item.Value
+		if err := imp.tx.Save(&cur).Error; err != nil {
+			return fmt.Errorf("app config %q: %w", item.Key, err)
+		}
+	}
+	return nil
+}
+
+// ExportConfig downloads the configuration stored in the database as a JSON
+// (default) or YAML bundle.
+func ExportConfig(c *gin.Context) {
+	key := common.KubeSentinelEncryptKey
+	if k := c.GetHeader(exportKeyHeader); k != "" {
+		key = k
+	}
+	bundle, err := exportConfigBundle(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export configuration: " + err.Error()})
+		return
+	}
+
+	filename := "kube-sentinel-config-" + bundle.ExportedAt.Format("20060102-150405")
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+		c.IndentedJSON(http.StatusOK, bundle)
+	case "yaml":
+		data, err := yaml.Marshal(bundle)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".yaml"))
+		c.Data(http.StatusOK, "application/yaml", data)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or yaml"})
+	}
+}
+
+// ImportConfig restores a bundle created by ExportConfig. Query parameters:
+// mode (merge or replace, default merge) and dryRun.
+func ImportConfig(c *gin.Context) {
+	mode := c.DefaultQuery("mode", configImportMerge)
+	if mode != configImportMerge && mode != configImportReplace {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be merge or replace"})
+		return
+	}
+	dryRun := c.Query("dryRun") == "true"
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, configBundleMaxSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(data) > configBundleMaxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "bundle is too large"})
+		return
+	}
+
+	// YAML is a superset of JSON, so both formats are accepted
+	var bundle ConfigBundle
+	if err := yaml.UnmarshalStrict(data, &bundle); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bundle: " + err.Error()})
+		return
+	}
+	if bundle.APIVersion != configBundleAPIVersion || bundle.Kind != configBundleKind {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported bundle %s/%s, expected %s/%s", bundle.APIVersion, bundle.Kind, configBundleAPIVersion, configBundleKind)})
+		return
+	}
+
+	key := common.KubeSentinelEncryptKey
+	if k := c.GetHeader(sourceKeyHeader); k != "" {
+		key = k
+	}
+	if bundle.KeyFingerprint != keyFingerprint(key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the bundle was encrypted with a different key, pass it in the " + sourceKeyHeader + " header"})
+		return
+	}
+
+	changes, err := importConfigBundle(&bundle, mode, dryRun, key)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "import failed, nothing was changed: " + err.Error(), "changes": changes})
+		return
+	}
+	if !dryRun && len(changes) > 0 {
+		cluster.TriggerSync()
+		rbac.TriggerSync()
+		model.RefreshAppConfigCache()
+		model.InvalidateClusterGroupSettings()
+	}
+	c.JSON(http.StatusOK, gin.H{"mode": mode, "dryRun": dryRun, "changes": changes})
+}
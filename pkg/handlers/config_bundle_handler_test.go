@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupConfigBundleDB(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:config_bundle?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	model.DB = db
+	require.NoError(t, db.AutoMigrate(
+		&model.App{}, &model.AppConfig{}, &model.Cluster{}, &model.Role{}, &model.RoleAssignment{},
+		&model.OAuthProvider{}, &model.AIProviderProfile{}, &model.ResourceTemplate{}, &model.ClusterKnowledgeBase{},
+	))
+	app := model.App{Name: "kube-sentinel-test"}
+	require.NoError(t, db.FirstOrCreate(&app, model.App{Name: app.Name}).Error)
+	model.CurrentApp = &app
+}
+
+func TestConfigBundleExportImport(t *testing.T) {
+	setupConfigBundleDB(t)
+
+	prod := model.Cluster{Name: "prod", Config: "kubeconfig-data", Enable: true, Labels: model.MapString{"env": "prod"},
+		PrometheusConfig: &model.PrometheusConfig{AuthType: model.PrometheusAuthBasic, Username: "prom", Password: "prom-pass"}}
+	require.NoError(t, model.DB.Create(&prod).Error)
+	role := model.Role{Name: "dev", Verbs: []string{"get"}, Assignments: []model.RoleAssignment{{SubjectType: model.SubjectTypeGroup, Subject: "devs"}}}
+	require.NoError(t, model.DB.Create(&role).Error)
+	require.NoError(t, model.DB.Create(&model.OAuthProvider{AppID: model.CurrentApp.ID, Name: "github", ClientID: "id", ClientSecret: "oauth-secret", Enabled: true}).Error)
+	require.NoError(t, model.DB.Create(&model.AIProviderProfile{Name: "openai", Provider: "openai", APIKey: "sk-test", IsEnabled: true}).Error)
+	require.NoError(t, model.DB.Create(&model.ResourceTemplate{Name: "pod", YAML: "kind: Pod"}).Error)
+	require.NoError(t, model.DB.Create(&model.ClusterKnowledgeBase{ClusterID: prod.ID, Content: "uses istio"}).Error)
+	require.NoError(t, model.DB.Create(&model.AppConfig{AppID: model.CurrentApp.ID, Key: "LOCAL_LOGIN_ENABLED", Value: "false"}).Error)
+
+	r := gin.New()
+	r.GET("/config/export", ExportConfig)
+	r.POST("/config/import", ImportConfig)
+
+	// Export with the key of another instance
+	req := httptest.NewRequest(http.MethodGet, "/config/export", nil)
+	req.Header.Set(exportKeyHeader, "target-key")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	exported := w.Body.Bytes()
+	assert.NotContains(t, string(exported), "kubeconfig-data")
+	assert.NotContains(t, string(exported), "sk-test")
+	assert.NotContains(t, string(exported), "prom-pass")
+
+	var bundle ConfigBundle
+	require.NoError(t, json.Unmarshal(exported, &bundle))
+	assert.Len(t, bundle.Clusters, 1)
+	assert.Equal(t, "prod", bundle.Knowledge[0].Cluster)
+
+	importBundle := func(query, key string) (int, []ConfigImportChange) {
+		req := httptest.NewRequest(http.MethodPost, "/config/import"+query, bytes.NewReader(exported))
+		if key != "" {
+			req.Header.Set(sourceKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp struct {
+			Changes []ConfigImportChange `json:"changes"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Changes
+	}
+
+	code, _ := importBundle("", "")
+	assert.Equal(t, http.StatusBadRequest, code, "the bundle key differs from the server key")
+
+	code, changes := importBundle("?dryRun=true", "target-key")
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, changes, "importing an unchanged database is a no-op")
+
+	// Local edits are reverted by a merge, extra records survive it
+	require.NoError(t, model.DB.Model(&model.ResourceTemplate{}).Where("name = ?", "pod").Update("yaml", "kind: Job").Error)
+	require.NoError(t, model.DB.Where("subject = ?", "devs").Delete(&model.RoleAssignment{}).Error)
+	require.NoError(t, model.DB.Create(&model.ResourceTemplate{Name: "extra", YAML: "kind: Service"}).Error)
+
+	code, changes = importBundle("?dryRun=true", "target-key")
+	require.Equal(t, http.StatusOK, code)
+	assert.ElementsMatch(t, []ConfigImportChange{
+		{Kind: "roleAssignment", Name: "dev/group:devs", Action: "create"},
+		{Kind: "template", Name: "pod", Action: "update"},
+	}, changes)
+	var tmpl model.ResourceTemplate
+	require.NoError(t, model.DB.Where("name = ?", "pod").First(&tmpl).Error)
+	assert.Equal(t, "kind: Job", tmpl.YAML, "dry run does not write")
+
+	code, _ = importBundle("", "target-key")
+	require.Equal(t, http.StatusOK, code)
+	require.NoError(t, model.DB.Where("name = ?", "pod").First(&tmpl).Error)
+	assert.Equal(t, "kind: Pod", tmpl.YAML)
+
+	code, changes = importBundle("?mode=replace", "target-key")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []ConfigImportChange{{Kind: "template", Name: "extra", Action: "delete"}}, changes)
+
+	// Secrets are re-encrypted with the server key
+	cluster, err := model.GetClusterByName("prod")
+	require.NoError(t, err)
+	assert.Equal(t, model.SecretString("kubeconfig-data"), cluster.Config)
+	require.NotNil(t, cluster.PrometheusConfig)
+	assert.Equal(t, "prom-pass", cluster.PrometheusConfig.Password)
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/cost"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/prometheus"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// defaultUsageWindow is the period usage is averaged over for the usage basis.
+const defaultUsageWindow = 7 * 24 * time.Hour
+
+// GetCostReport returns the cost of the cluster grouped by namespace,
+// workload or label (?groupBy=label&label=team), priced with the pricing of
+// the cluster. ?month=2006-01 charges the average requests and usage of that
+// month from Prometheus, up to now for the current month. Without it the
+// report projects what runs now over a month. CPU and memory are charged by
+// requests, or by the larger of requests and average usage when Prometheus
+// is available. ?format=csv returns the report as CSV. Users without the
+// admin role only see the namespaces they can access.
+func GetCostReport(c *gin.Context) {
+	ctx := c.Request.Context()
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+
+	clusterModel, err := model.GetClusterByName(cs.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if clusterModel.Pricing == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cost pricing is not configured for this cluster"})
+		return
+	}
+	pricing := *clusterModel.Pricing
+
+	groupBy := c.DefaultQuery("groupBy", cost.GroupByNamespace)
+	label := c.Query("label")
+	switch groupBy {
+	case cost.GroupByNamespace, cost.GroupByWorkload:
+	case cost.GroupByLabel:
+		if label == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "label is required to group by label"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "groupBy must be one of: namespace, workload, label"})
+		return
+	}
+
+	basis := c.Query("basis")
+	if basis == "" {
+		basis = cost.BasisRequests
+		if cs.PromClient != nil {
+			basis = cost.BasisUsage
+		}
+	}
+	if basis != cost.BasisRequests && basis != cost.BasisUsage {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "basis must be one of: requests, usage"})
+		return
+	}
+
+	report := gin.H{}
+	hours := float64(cost.HoursPerMonth)
+	var items []cost.Item
+	if month := c.Query("month"); month != "" {
+		start, err := time.Parse("2006-01", month)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "month must be in the form YYYY-MM"})
+			return
+		}
+		now := time.Now().UTC()
+		if !start.Before(now) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "month has not started yet"})
+			return
+		}
+		end := start.AddDate(0, 1, 0)
+		if end.After(now) {
+			end = now
+		}
+		if cs.PromClient == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Prometheus client not available"})
+			return
+		}
+		podLabel := ""
+		if groupBy == cost.GroupByLabel {
+			podLabel = label
+		}
+		usage, err := cs.PromClient.GetPeriodUsage(ctx, start, end, podLabel)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to query usage: %v", err)})
+			return
+		}
+		items = cost.PeriodItems(usage, basis)
+		hours = end.Sub(start).Hours()
+		report["month"] = month
+		report["start"] = start.Format(time.RFC3339)
+		report["end"] = end.Format(time.RFC3339)
+	} else {
+		window := defaultUsageWindow
+		if v := c.Query("window"); v != "" {
+			if window, err = parsePromDuration(v); err != nil || window <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid window"})
+				return
+			}
+		}
+
+		var usage map[string]prometheus.PodUsage
+		if basis == cost.BasisUsage {
+			if cs.PromClient == nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Prometheus client not available"})
+				return
+			}
+			if usage, err = cs.PromClient.GetPodUsageAverages(ctx, window); err != nil {
+				if c.Query("basis") != "" {
+					c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to query usage: %v", err)})
+					return
+				}
+				klog.Warningf("Falling back to requests for the cost report of cluster %s: %v", cs.Name, err)
+				basis = cost.BasisRequests
+			}
+		}
+
+		var pods corev1.PodList
+		if err := cs.K8sClient.List(ctx, &pods); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to list pods: %v", err)})
+			return
+		}
+		var pvcs corev1.PersistentVolumeClaimList
+		if err := cs.K8sClient.List(ctx, &pvcs); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to list persistent volume claims: %v", err)})
+			return
+		}
+		var services corev1.ServiceList
+		if err := cs.K8sClient.List(ctx, &services); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to list services: %v", err)})
+			return
+		}
+		items = cost.Items(pods.Items, pvcs.Items, services.Items, usage, basis)
+		report["projection"] = "monthly"
+	}
+
+	if !rbac.UserHasRole(user, model.DefaultAdminRole.Name) {
+		allowed := map[string]bool{}
+		visible := items[:0]
+		for _, item := range items {
+			ok, seen := allowed[item.Namespace]
+			if !seen {
+				ok = rbac.CanAccessNamespace(user, cs.Name, item.Namespace)
+				allowed[item.Namespace] = ok
+			}
+			if ok {
+				visible = append(visible, item)
+			}
+		}
+		items = visible
+	}
+
+	allocations := cost.Allocate(items, pricing, groupBy, label, hours)
+	total := cost.Total(allocations, pricing, hours)
+
+	if c.Query("format") == "csv" {
+		writeCostCSV(c, cs.Name, c.Query("month"), pricing, append(allocations, total))
+		return
+	}
+	report["cluster"] = cs.Name
+	report["currency"] = pricing.Currency
+	report["pricing"] = pricing
+	report["basis"] = basis
+	report["groupBy"] = groupBy
+	report["label"] = label
+	report["hours"] = hours
+	report["generatedAt"] = time.Now().UTC().Format(time.RFC3339)
+	report["allocations"] = allocations
+	report["total"] = total
+	c.JSON(http.StatusOK, report)
+}
+
+// writeCostCSV writes a report as CSV. The costs of a projection are named
+// projected_*.
+func writeCostCSV(c *gin.Context, clusterName, month string, pricing model.CostPricing, allocations []cost.Allocation) {
+	filename := fmt.Sprintf("cost-projection-%s-%s.csv", clusterName, time.Now().Format("2006-01-02"))
+	prefix := "projected_"
+	if month != "" {
+		filename = fmt.Sprintf("cost-%s-%s.csv", clusterName, month)
+		prefix = ""
+	}
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"name", "namespace", "cpu_cores", "memory_gib", "storage_gib", "load_balancers",
+		prefix + "cpu_cost", prefix + "memory_cost", prefix + "storage_cost", prefix + "load_balancer_cost", prefix + "total_cost", "currency"})
+	for _, a := range allocations {
+		_ = w.Write([]string{a.Name, a.Namespace, f(a.CPUCores), f(a.MemoryGiB), f(a.StorageGiB), f(a.LoadBalancers),
+			f(a.CPUCost), f(a.MemoryCost), f(a.StorageCost), f(a.LoadBalancerCost), f(a.TotalCost), pricing.Currency})
+	}
+	w.Flush()
+}
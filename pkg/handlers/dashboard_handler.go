@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/prometheus"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// maxRenderPoints is the number of points per series a render aims for when
+// no step is given.
+const maxRenderPoints = 250
+
+type dashboardReq struct {
+	Name        string                    `json:"name" binding:"required"`
+	Description string                    `json:"description"`
+	SharedRoles []string                  `json:"sharedRoles"`
+	Variables   []model.DashboardVariable `json:"variables"`
+	Panels      []model.DashboardPanel    `json:"panels"`
+}
+
+// renderedPanel is a panel together with the result of its query.
+type renderedPanel struct {
+	model.DashboardPanel
+	Result *prometheus.QueryResult `json:"result,omitempty"`
+	Error  string                  `json:"error,omitempty"`
+}
+
+// canViewDashboard reports whether the user owns the dashboard, has one of
+// the roles it is shared with or is an admin.
+func canViewDashboard(user model.User, dashboard *model.Dashboard) bool {
+	if canEditDashboard(user, dashboard) {
+		return true
+	}
+	for _, role := range dashboard.SharedRoles {
+		if rbac.UserHasRole(user, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// canEditDashboard reports whether the user owns the dashboard or is an admin.
+func canEditDashboard(user model.User, dashboard *model.Dashboard) bool {
+	return dashboard.OwnerID == user.ID || rbac.UserHasRole(user, model.DefaultAdminRole.Name)
+}
+
+// getDashboard loads the dashboard of the :id parameter and checks that the
+// user can view it, writing the error response otherwise.
+func getDashboard(c *gin.Context, user model.User) (*model.Dashboard, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dashboard id"})
+		return nil, false
+	}
+	dashboard, err := model.GetDashboardByID(uint(id))
+	if err != nil || !canViewDashboard(user, dashboard) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "dashboard not found"})
+		return nil, false
+	}
+	return dashboard, true
+}
+
+// applyDashboardReq validates req and copies it into dashboard.
+func applyDashboardReq(c *gin.Context, dashboard *model.Dashboard) bool {
+	var req dashboardReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	for _, name := range req.SharedRoles {
+		if _, err := model.GetRoleByName(name); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("role %q not found", name)})
+			return false
+		}
+	}
+	dashboard.Name = req.Name
+	dashboard.Description = req.Description
+	dashboard.SharedRoles = req.SharedRoles
+	dashboard.Variables = req.Variables
+	dashboard.Panels = req.Panels
+	if err := dashboard.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// ListDashboards returns the dashboards the user can view.
+func ListDashboards(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+	dashboards, err := model.ListDashboards()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	result := make([]model.Dashboard, 0, len(dashboards))
+	for i := range dashboards {
+		if canViewDashboard(user, &dashboards[i]) {
+			result = append(result, dashboards[i])
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+func GetDashboard(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+	dashboard, ok := getDashboard(c, user)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": dashboard})
+}
+
+// CreateDashboard creates a dashboard owned by the user.
+func CreateDashboard(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+	dashboard := model.Dashboard{OwnerID: user.ID, Owner: user.Key()}
+	if !applyDashboardReq(c, &dashboard) {
+		return
+	}
+	if err := model.AddDashboard(&dashboard); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create dashboard: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": dashboard})
+}
+
+// UpdateDashboard replaces a dashboard. Only the owner and admins can update
+// a dashboard.
+func UpdateDashboard(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+	dashboard, ok := getDashboard(c, user)
+	if !ok {
+		return
+	}
+	if !canEditDashboard(user, dashboard) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the owner of a dashboard can update it"})
+		return
+	}
+	if !applyDashboardReq(c, dashboard) {
+		return
+	}
+	if err := model.UpdateDashboard(dashboard); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update dashboard: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": dashboard})
+}
+
+// DeleteDashboard deletes a dashboard. Only the owner and admins can delete
+// a dashboard.
+func DeleteDashboard(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+	dashboard, ok := getDashboard(c, user)
+	if !ok {
+		return
+	}
+	if !canEditDashboard(user, dashboard) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the owner of a dashboard can delete it"})
+		return
+	}
+	if err := model.DeleteDashboard(dashboard.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete dashboard: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "dashboard deleted successfully"})
+}
+
+// RenderDashboard runs the queries of all panels of a dashboard against the
+// Prometheus of the current cluster. The time range is given by start, end
+// and step, and variables are set with var-<name>=value. Line, area and bar
+// panels run range queries; the others run instant queries at end. For users
+// without the admin role the queries are restricted to the namespaces they can
+// access, like the query API does.
+func RenderDashboard(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+	dashboard, ok := getDashboard(c, user)
+	if !ok {
+		return
+	}
+	if cs.PromClient == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Prometheus client not available"})
+		return
+	}
+
+	r, err := renderRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	vars := map[string]string{
+		"__range": fmt.Sprintf("%ds", int64(r.End.Sub(r.Start).Seconds())),
+		"__step":  fmt.Sprintf("%ds", int64(r.Step.Seconds())),
+	}
+	for _, v := range dashboard.Variables {
+		vars[v.Name] = c.DefaultQuery("var-"+v.Name, v.Default)
+	}
+
+	var namespaces []string
+	isAdmin := rbac.UserHasRole(user, model.DefaultAdminRole.Name)
+	if !isAdmin {
+		if namespaces, err = cs.AccessibleNamespaces(c.Request.Context(), user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to list namespaces: %v", err)})
+			return
+		}
+	}
+
+	panels := make([]renderedPanel, 0, len(dashboard.Panels))
+	for _, panel := range dashboard.Panels {
+		rendered := renderedPanel{DashboardPanel: panel}
+		query := prometheus.ExpandVariables(panel.Query, vars)
+		var err error
+		if !isAdmin {
+			query, err = prometheus.EnforceNamespaces(query, namespaces)
+		}
+		if err == nil {
+			rendered.Query = query
+			switch panel.Type {
+			case model.PanelLine, model.PanelArea, model.PanelBar:
+				rendered.Result, err = cs.PromClient.QueryRange(c.Request.Context(), query, r, prometheus.DefaultQueryTimeout)
+			default:
+				rendered.Result, err = cs.PromClient.Query(c.Request.Context(), query, r.End, prometheus.DefaultQueryTimeout)
+			}
+		}
+		if err != nil {
+			rendered.Error = err.Error()
+		}
+		panels = append(panels, rendered)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dashboard": dashboard,
+		"start":     r.Start,
+		"end":       r.End,
+		"step":      r.Step.Seconds(),
+		"variables": vars,
+		"panels":    panels,
+	})
+}
+
+// renderRange reads start, end and step of a render. end defaults to now,
+// start to one hour before end and step to the range divided into
+// maxRenderPoints, at least 15 seconds.
+func renderRange(c *gin.Context) (v1.Range, error) {
+	r := v1.Range{End: time.Now()}
+	var err error
+	if v := c.Query("end"); v != "" {
+		if r.End, err = parsePromTime(v); err != nil {
+			return r, fmt.Errorf("invalid end: %v", err)
+		}
+	}
+	r.Start = r.End.Add(-time.Hour)
+	if v := c.Query("start"); v != "" {
+		if r.Start, err = parsePromTime(v); err != nil {
+			return r, fmt.Errorf("invalid start: %v", err)
+		}
+	}
+	if !r.End.After(r.Start) {
+		return r, fmt.Errorf("end must be after start")
+	}
+	if v := strings.TrimSpace(c.Query("step")); v != "" {
+		if r.Step, err = parsePromDuration(v); err != nil || r.Step <= 0 {
+			return r, fmt.Errorf("invalid step, must be a positive duration")
+		}
+	} else {
+		r.Step = max(r.End.Sub(r.Start)/maxRenderPoints, 15*time.Second).Truncate(time.Second)
+	}
+	return r, nil
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const diffTimeout = 30 * time.Second
+
+// defaultDiffResources are compared when a namespace diff does not list kinds.
+var defaultDiffResources = []string{
+	"deployments", "statefulsets", "daemonsets", "cronjobs",
+	"services", "ingresses", "configmaps", "secrets", "serviceaccounts",
+	"persistentvolumeclaims", "horizontalpodautoscalers", "poddisruptionbudgets",
+	"networkpolicies", "roles", "rolebindings",
+}
+
+type DiffHandler struct {
+	cm *cluster.ClusterManager
+}
+
+// DiffRef points at a single object, or at a whole namespace when Name is empty.
+type DiffRef struct {
+	Cluster   string `json:"cluster" binding:"required"`
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+}
+
+type DiffRequest struct {
+	Left  DiffRef `json:"left" binding:"required"`
+	Right DiffRef `json:"right" binding:"required"`
+	// Kinds limits a namespace diff to these resources.
+	Kinds []string `json:"kinds"`
+}
+
+type ResourceDiff struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Identical bool   `json:"identical"`
+	Diff      string `json:"diff,omitempty"`
+}
+
+type NamespaceDiff struct {
+	Items       []ResourceDiff `json:"items"`
+	OnlyInLeft  []DiffRef      `json:"onlyInLeft"`
+	OnlyInRight []DiffRef      `json:"onlyInRight"`
+	Errors      []string       `json:"errors,omitempty"`
+}
+
+func NewDiffHandler(cm *cluster.ClusterManager) *DiffHandler {
+	return &DiffHandler{cm: cm}
+}
+
+// Diff compares two objects, or two namespaces, possibly in different clusters.
+// Volatile fields are stripped and the result is a unified diff of the YAML.
+func (h *DiffHandler) Diff(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+
+	var req DiffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), diffTimeout)
+	defer cancel()
+
+	if req.Left.Name == "" && req.Right.Name == "" {
+		if req.Left.Namespace == "" || req.Right.Namespace == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required on both sides for a namespace diff"})
+			return
+		}
+		kinds := req.Kinds
+		if len(kinds) == 0 {
+			kinds = defaultDiffResources
+		}
+		c.JSON(http.StatusOK, h.diffNamespaces(ctx, user, req.Left, req.Right, kinds))
+		return
+	}
+
+	if req.Left.Name == "" || req.Right.Name == "" || req.Left.Kind == "" || req.Right.Kind == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind and name are required on both sides for a resource diff"})
+		return
+	}
+	left, err := h.getObject(ctx, user, req.Left)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "left: " + err.Error()})
+		return
+	}
+	right, err := h.getObject(ctx, user, req.Right)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "right: " + err.Error()})
+		return
+	}
+	diff, err := kube.DiffObjects(left, right, refLabel(req.Left), refLabel(req.Right))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ResourceDiff{
+		Kind:      left.GetKind(),
+		Name:      left.GetName(),
+		Identical: diff == "",
+		Diff:      diff,
+	})
+}
+
+func (h *DiffHandler) getObject(ctx context.Context, user model.User, ref DiffRef) (*unstructured.Unstructured, error) {
+	cs, err := h.cm.GetClientSet(ref.Cluster, &user)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := resolveFederatedResource(cs.K8sClient.RESTMapper(), ref.Kind)
+	if err != nil {
+		return nil, err
+	}
+	namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+	namespace := "_all"
+	key := client.ObjectKey{Name: ref.Name}
+	if namespaced {
+		if ref.Namespace == "" {
+			return nil, fmt.Errorf("namespace is required for %s", mapping.Resource.Resource)
+		}
+		namespace = ref.Namespace
+		key.Namespace = ref.Namespace
+	}
+	if !rbac.CanAccess(user, mapping.Resource.Resource, string(common.VerbGet), cs.Name, namespace) {
+		return nil, fmt.Errorf("%s", rbac.NoAccess(user.Key(), string(common.VerbGet), mapping.Resource.Resource, namespace, cs.Name))
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(mapping.GroupVersionKind)
+	if err := cs.K8sClient.Get(ctx, key, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// listNamespace returns the objects of a kind in a namespace keyed by name.
+func (h *DiffHandler) listNamespace(ctx context.Context, user model.User, ref DiffRef, kind string) (map[string]*unstructured.Unstructured, error) {
+	cs, err := h.cm.GetClientSet(ref.Cluster, &user)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := resolveFederatedResource(cs.K8sClient.RESTMapper(), kind)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return nil, fmt.Errorf("%s is not namespaced", kind)
+	}
+	if !rbac.CanAccess(user, mapping.Resource.Resource, string(common.VerbGet), cs.Name, ref.Namespace) {
+		return nil, fmt.Errorf("%s", rbac.NoAccess(user.Key(), string(common.VerbGet), mapping.Resource.Resource, ref.Namespace, cs.Name))
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(mapping.GroupVersionKind.GroupVersion().WithKind(mapping.GroupVersionKind.Kind + "List"))
+	if err := cs.K8sClient.List(ctx, list, client.InNamespace(ref.Namespace)); err != nil {
+		return nil, err
+	}
+	objects := make(map[string]*unstructured.Unstructured, len(list.Items))
+	for i := range list.Items {
+		objects[list.Items[i].GetName()] = &list.Items[i]
+	}
+	return objects, nil
+}
+
+func (h *DiffHandler) diffNamespaces(ctx context.Context, user model.User, left, right DiffRef, kinds []string) *NamespaceDiff {
+	result := &NamespaceDiff{
+		Items:       []ResourceDiff{},
+		OnlyInLeft:  []DiffRef{},
+		OnlyInRight: []DiffRef{},
+	}
+	for _, kind := range kinds {
+		leftObjs, err := h.listNamespace(ctx, user, left, kind)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s in %s: %v", kind, refLabel(left), err))
+			continue
+		}
+		rightObjs, err := h.listNamespace(ctx, user, right, kind)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s in %s: %v", kind, refLabel(right), err))
+			continue
+		}
+
+		names := make([]string, 0, len(leftObjs))
+		for name := range leftObjs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if isGeneratedObject(kind, leftObjs[name]) {
+				continue
+			}
+			r, ok := rightObjs[name]
+			if !ok {
+				result.OnlyInLeft = append(result.OnlyInLeft, DiffRef{Cluster: left.Cluster, Namespace: left.Namespace, Kind: kind, Name: name})
+				continue
+			}
+			diff, err := kube.DiffObjects(leftObjs[name], r, refLabel(left), refLabel(right))
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s/%s: %v", kind, name, err))
+				continue
+			}
+			result.Items = append(result.Items, ResourceDiff{Kind: kind, Name: name, Identical: diff == "", Diff: diff})
+		}
+
+		rightNames := make([]string, 0, len(rightObjs))
+		for name := range rightObjs {
+			if _, ok := leftObjs[name]; !ok && !isGeneratedObject(kind, rightObjs[name]) {
+				rightNames = append(rightNames, name)
+			}
+		}
+		sort.Strings(rightNames)
+		for _, name := range rightNames {
+			result.OnlyInRight = append(result.OnlyInRight, DiffRef{Cluster: right.Cluster, Namespace: right.Namespace, Kind: kind, Name: name})
+		}
+	}
+	return result
+}
+
+// isGeneratedObject reports objects that Kubernetes creates in every
+// namespace, which would only add noise to a namespace diff.
+func isGeneratedObject(kind string, obj *unstructured.Unstructured) bool {
+	switch kind {
+	case "configmaps":
+		return obj.GetName() == "kube-root-ca.crt"
+	case "serviceaccounts":
+		return obj.GetName() == "default"
+	case "secrets":
+		t, _, _ := unstructured.NestedString(obj.Object, "type")
+		return t == "kubernetes.io/service-account-token" || t == "helm.sh/release.v1"
+	}
+	return false
+}
+
+func refLabel(ref DiffRef) string {
+	parts := []string{ref.Cluster}
+	if ref.Namespace != "" {
+		parts = append(parts, ref.Namespace)
+	}
+	if ref.Kind != "" && ref.Name != "" {
+		parts = append(parts, ref.Kind, ref.Name)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultFederatedTimeout = 10 * time.Second
+	maxFederatedTimeout     = 60 * time.Second
+	// federatedConcurrency bounds how many clusters are listed at once.
+	federatedConcurrency = 10
+)
+
+type FederatedHandler struct {
+	cm *cluster.ClusterManager
+}
+
+// FederatedItem is a listed object together with the cluster it came from.
+type FederatedItem struct {
+	Cluster string      `json:"cluster"`
+	Object  interface{} `json:"object"`
+}
+
+// FederatedClusterResult reports the outcome of the list in a single cluster.
+type FederatedClusterResult struct {
+	Cluster    string `json:"cluster"`
+	Count      int    `json:"count"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+type FederatedListResponse struct {
+	Items    []FederatedItem          `json:"items"`
+	Clusters []FederatedClusterResult `json:"clusters"`
+	Partial  bool                     `json:"partial"`
+}
+
+// federatedQuery holds the parsed filters of a federated list request.
+type federatedQuery struct {
+	resource      string
+	namespaces    []string
+	labelSelector labels.Selector
+	fieldSelector fields.Selector
+	image         string
+	reason        string
+}
+
+func NewFederatedHandler(cm *cluster.ClusterManager) *FederatedHandler {
+	return &FederatedHandler{cm: cm}
+}
+
+// List fans a list request out to every accessible cluster, or to the subset
+// chosen with the clusters/clusterSelector parameters, and merges the results.
+//
+// Query parameters:
+//   - clusters: comma separated cluster names
+//   - clusterSelector: label selector matched against cluster labels
+//   - namespaces: comma separated namespaces (default all)
+//   - labelSelector, fieldSelector: passed to the list call
+//   - image: only objects with a container image containing this value
+//   - reason: only objects with a container waiting/terminated reason equal to this value, e.g. CrashLoopBackOff
+//   - timeout: per-cluster timeout in seconds (default 10, max 60)
+func (h *FederatedHandler) List(c *gin.Context) {
+	user := c.MustGet("user").(model.User)
+
+	q := federatedQuery{
+		resource: c.Param("resource"),
+		image:    c.Query("image"),
+		reason:   c.Query("reason"),
+	}
+	if ns := c.Query("namespaces"); ns != "" && ns != "_all" {
+		q.namespaces = strings.Split(ns, ",")
+	}
+	if ls := c.Query("labelSelector"); ls != "" {
+		selector, err := labels.Parse(ls)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid labelSelector parameter: " + err.Error()})
+			return
+		}
+		q.labelSelector = selector
+	}
+	if fs := c.Query("fieldSelector"); fs != "" {
+		selector, err := fields.ParseSelector(fs)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fieldSelector parameter: " + err.Error()})
+			return
+		}
+		q.fieldSelector = selector
+	}
+
+	timeout := defaultFederatedTimeout
+	if t := c.Query("timeout"); t != "" {
+		seconds, err := strconv.Atoi(t)
+		if err != nil || seconds <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid timeout parameter"})
+			return
+		}
+		timeout = min(time.Duration(seconds)*time.Second, maxFederatedTimeout)
+	}
+
+	clusterNames, err := selectFederatedClusters(user, c.Query("clusters"), c.Query("clusterSelector"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := FederatedListResponse{
+		Items:    []FederatedItem{},
+		Clusters: make([]FederatedClusterResult, len(clusterNames)),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, federatedConcurrency)
+	for i, name := range clusterNames {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+			defer cancel()
+
+			items, err := h.listCluster(ctx, user, name, q)
+			result := FederatedClusterResult{
+				Cluster:    name,
+				Count:      len(items),
+				DurationMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				if ctx.Err() == context.DeadlineExceeded {
+					err = fmt.Errorf("timed out after %s", timeout)
+				}
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			resp.Clusters[i] = result
+			resp.Items = append(resp.Items, items...)
+			mu.Unlock()
+		}(i, name)
+	}
+	wg.Wait()
+
+	for _, r := range resp.Clusters {
+		if r.Error != "" {
+			resp.Partial = true
+			break
+		}
+	}
+	sort.SliceStable(resp.Items, func(i, j int) bool {
+		if resp.Items[i].Cluster != resp.Items[j].Cluster {
+			return resp.Items[i].Cluster < resp.Items[j].Cluster
+		}
+		o1, _ := meta.Accessor(resp.Items[i].Object)
+		o2, _ := meta.Accessor(resp.Items[j].Object)
+		if o1 == nil || o2 == nil {
+			return false
+		}
+		if o1.GetNamespace() != o2.GetNamespace() {
+			return o1.GetNamespace() < o2.GetNamespace()
+		}
+		return o1.GetName() < o2.GetName()
+	})
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// selectFederatedClusters returns the enabled clusters the user can access,
+// narrowed down by an optional list of names and cluster label selector.
+func selectFederatedClusters(user model.User, names, selector string) ([]string, error) {
+	clusterSelector := labels.Everything()
+	if selector != "" {
+		var err error
+		clusterSelector, err = labels.Parse(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid clusterSelector parameter: %w", err)
+		}
+	}
+	var wanted map[string]bool
+	if names != "" {
+		wanted = map[string]bool{}
+		for _, name := range strings.Split(names, ",") {
+			wanted[strings.TrimSpace(name)] = true
+		}
+	}
+
+	clusters, err := model.ListClusters()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(clusters))
+	for _, cluster := range clusters {
+		if !cluster.Enable || !rbac.CanAccessCluster(user, cluster.Name) {
+			continue
+		}
+		if wanted != nil && !wanted[cluster.Name] {
+			continue
+		}
+		if !clusterSelector.Matches(labels.Set(cluster.Labels)) {
+			continue
+		}
+		result = append(result, cluster.Name)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func (h *FederatedHandler) listCluster(ctx context.Context, user model.User, clusterName string, q federatedQuery) ([]FederatedItem, error) {
+	cs, err := h.cm.GetClientSet(clusterName, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping, err := resolveFederatedResource(cs.K8sClient.RESTMapper(), q.resource)
+	if err != nil {
+		return nil, err
+	}
+	gvk := mapping.GroupVersionKind
+	namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+
+	listOpts := []client.ListOption{}
+	if namespaced && len(q.namespaces) == 1 {
+		listOpts = append(listOpts, client.InNamespace(q.namespaces[0]))
+	}
+	if q.labelSelector != nil {
+		listOpts = append(listOpts, client.MatchingLabelsSelector{Selector: q.labelSelector})
+	}
+	if q.fieldSelector != nil {
+		listOpts = append(listOpts, client.MatchingFieldsSelector{Selector: q.fieldSelector})
+	}
+
+	// Built-in types go through the typed (cached) client, everything else is
+	// listed as unstructured. The cache cannot evaluate arbitrary field
+	// selectors, so those are always sent to the API server.
+	listGVK := gvk.GroupVersion().WithKind(gvk.Kind + "List")
+	var list client.ObjectList
+	if obj, err := kube.GetScheme().New(listGVK); err == nil && q.fieldSelector == nil {
+		list = obj.(client.ObjectList)
+	} else {
+		ul := &unstructured.UnstructuredList{}
+		ul.SetGroupVersionKind(listGVK)
+		list = ul
+	}
+	if err := cs.K8sClient.List(ctx, list, listOpts...); err != nil {
+		return nil, err
+	}
+	objects, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]FederatedItem, 0, len(objects))
+	for _, o := range objects {
+		obj, err := meta.Accessor(o)
+		if err != nil {
+			continue
+		}
+		namespace := "_all"
+		if namespaced {
+			namespace = obj.GetNamespace()
+			if len(q.namespaces) > 1 && !slices.Contains(q.namespaces, namespace) {
+				continue
+			}
+		}
+		if !rbac.CanAccess(user, mapping.Resource.Resource, string(common.VerbGet), clusterName, namespace) {
+			continue
+		}
+		if q.image != "" || q.reason != "" {
+			content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+			if err != nil {
+				continue
+			}
+			if q.image != "" && !matchesContainerImage(content, q.image) {
+				continue
+			}
+			if q.reason != "" && !matchesContainerReason(content, q.reason) {
+				continue
+			}
+		}
+		obj.SetManagedFields(nil)
+		if anno := obj.GetAnnotations(); anno != nil {
+			delete(anno, common.KubectlAnnotation)
+		}
+		items = append(items, FederatedItem{Cluster: clusterName, Object: o})
+	}
+	return items, nil
+}
+
+// resolveFederatedResource maps a resource name such as "pods" or
+// "deployments.apps", or a kind such as "Pod", to its preferred REST mapping.
+func resolveFederatedResource(mapper meta.RESTMapper, resource string) (*meta.RESTMapping, error) {
+	gr := kube.ParseResourceName(resource)
+	gvk, err := mapper.KindFor(gr.WithVersion(""))
+	if err != nil {
+		mapping, mErr := mapper.RESTMapping(schema.GroupKind{Group: gr.Group, Kind: gr.Resource})
+		if mErr != nil {
+			return nil, fmt.Errorf("unknown resource %s: %w", resource, err)
+		}
+		return mapping, nil
+	}
+	return mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
+
+// podSpecPaths are the locations of a pod spec in pods, workloads and cronjobs.
+var podSpecPaths = [][]string{
+	{"spec"},
+	{"spec", "template", "spec"},
+	{"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+func matchesContainerImage(obj map[string]interface{}, image string) bool {
+	for _, path := range podSpecPaths {
+		for _, field := range []string{"containers", "initContainers", "ephemeralContainers"} {
+			containers, _, _ := unstructured.NestedSlice(obj, append(path, field)...)
+			for _, container := range containers {
+				m, ok := container.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if img, ok := m["image"].(string); ok && strings.Contains(img, image) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func matchesContainerReason(obj map[string]interface{}, reason string) bool {
+	for _, field := range []string{"containerStatuses", "initContainerStatuses"} {
+		statuses, _, _ := unstructured.NestedSlice(obj, "status", field)
+		for _, status := range statuses {
+			m, ok := status.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, state := range []string{"waiting", "terminated"} {
+				r, _, _ := unstructured.NestedString(m, "state", state, "reason")
+				if strings.EqualFold(r, reason) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
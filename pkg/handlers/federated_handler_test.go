@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestResolveFederatedResource(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Node"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}, meta.RESTScopeRoot)
+
+	tests := []struct {
+		resource   string
+		kind       string
+		plural     string
+		namespaced bool
+	}{
+		{"pods", "Pod", "pods", true},
+		{"deployments.apps", "Deployment", "deployments", true},
+		{"Deployment.apps", "Deployment", "deployments", true},
+		{"nodes", "Node", "nodes", false},
+		{"crds", "CustomResourceDefinition", "customresourcedefinitions", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.resource, func(t *testing.T) {
+			mapping, err := resolveFederatedResource(mapper, tc.resource)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.kind, mapping.GroupVersionKind.Kind)
+			assert.Equal(t, tc.plural, mapping.Resource.Resource)
+			assert.Equal(t, tc.namespaced, mapping.Scope.Name() == meta.RESTScopeNameNamespace)
+		})
+	}
+
+	mapping, err := resolveFederatedResource(mapper, "crds")
+	assert.NoError(t, err)
+	assert.Equal(t, "crds", kube.ResourceName(mapping.Resource), "CRDs are named crds in routes and RBAC rules")
+
+	_, err = resolveFederatedResource(mapper, "widgets")
+	assert.Error(t, err)
+}
+
+func TestMatchesContainerFilters(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "registry.example.com/shop/api:1.4.2"}},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "app",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			}},
+		},
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+	assert.NoError(t, err)
+
+	assert.True(t, matchesContainerImage(content, "shop/api"))
+	assert.False(t, matchesContainerImage(content, "nginx"))
+	assert.True(t, matchesContainerReason(content, "CrashLoopBackOff"))
+	assert.True(t, matchesContainerReason(content, "crashloopbackoff"))
+	assert.False(t, matchesContainerReason(content, "OOMKilled"))
+
+	workload := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{"image": "nginx:1.27"}},
+				},
+			},
+		},
+	}
+	assert.True(t, matchesContainerImage(workload, "nginx"))
+}
@@ -173,7 +173,8 @@ func (h *PromHandler) fetchPodMetricsFromMetricsServer(c *gin.Context, namespace
 		}
 	}
 
-	if labelSelector != "" {
+	// a label selector or no pod name aggregates over several pods
+	if labelSelector != "" || podName == "" {
 		listOpts := metav1.ListOptions{LabelSelector: labelSelector}
 		podMetricsList, err := cs.K8sClient.MetricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, listOpts)
 		if err != nil {
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/prometheus"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	prommodel "github.com/prometheus/common/model"
+)
+
+// Query handles PromQL instant queries (GET or POST /prometheus/query).
+func (h *PromHandler) Query(c *gin.Context) {
+	cs, query, timeout, ok := h.prepareQuery(c)
+	if !ok {
+		return
+	}
+	ts := time.Now()
+	if v := queryParam(c, "time"); v != "" {
+		var err error
+		if ts, err = parsePromTime(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid time: %v", err)})
+			return
+		}
+	}
+
+	result, err := cs.PromClient.Query(c.Request.Context(), query, ts, timeout)
+	if err != nil {
+		c.JSON(promQueryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// QueryRange handles PromQL range queries (GET or POST /prometheus/query_range).
+func (h *PromHandler) QueryRange(c *gin.Context) {
+	cs, query, timeout, ok := h.prepareQuery(c)
+	if !ok {
+		return
+	}
+	var r v1.Range
+	var err error
+	if r.Start, err = parsePromTime(queryParam(c, "start")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid start: %v", err)})
+		return
+	}
+	if r.End, err = parsePromTime(queryParam(c, "end")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid end: %v", err)})
+		return
+	}
+	if !r.End.After(r.Start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end must be after start"})
+		return
+	}
+	if r.Step, err = parsePromDuration(queryParam(c, "step")); err != nil || r.Step <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid step, must be a positive duration"})
+		return
+	}
+
+	result, err := cs.PromClient.QueryRange(c.Request.Context(), query, r, timeout)
+	if err != nil {
+		c.JSON(promQueryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// prepareQuery validates the common query parameters and restricts the query
+// to the namespaces the user can access. Admins query without restriction.
+func (h *PromHandler) prepareQuery(c *gin.Context) (*cluster.ClientSet, string, time.Duration, bool) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+	if cs.PromClient == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Prometheus client not available"})
+		return nil, "", 0, false
+	}
+
+	query := queryParam(c, "query")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query is required"})
+		return nil, "", 0, false
+	}
+
+	timeout := prometheus.DefaultQueryTimeout
+	if v := queryParam(c, "timeout"); v != "" {
+		d, err := parsePromDuration(v)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid timeout"})
+			return nil, "", 0, false
+		}
+		timeout = min(d, prometheus.MaxQueryTimeout)
+	}
+
+	if !rbac.UserHasRole(user, model.DefaultAdminRole.Name) {
+		namespaces, err := cs.AccessibleNamespaces(c.Request.Context(), user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to list namespaces: %v", err)})
+			return nil, "", 0, false
+		}
+		query, err = prometheus.EnforceNamespaces(query, namespaces)
+		if err != nil {
+			c.JSON(promQueryErrorStatus(err), gin.H{"error": err.Error()})
+			return nil, "", 0, false
+		}
+	}
+	return cs, query, timeout, true
+}
+
+// queryParam reads a parameter from the form body or the URL, like the
+// Prometheus HTTP API does.
+func queryParam(c *gin.Context, key string) string {
+	if v := c.PostForm(key); v != "" {
+		return v
+	}
+	return c.Query(key)
+}
+
+// parsePromTime parses a RFC 3339 or Unix timestamp.
+func parsePromTime(s string) (time.Time, error) {
+	if t, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, frac := math.Modf(t)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// parsePromDuration parses a Prometheus duration ("30s", "5m") or a number of
+// seconds.
+func parsePromDuration(s string) (time.Duration, error) {
+	if d, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(d * float64(time.Second)), nil
+	}
+	d, err := prommodel.ParseDuration(s)
+	return time.Duration(d), err
+}
+
+func promQueryErrorStatus(err error) int {
+	var apiErr *v1.Error
+	switch {
+	case errors.Is(err, prometheus.ErrNamespaceForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, prometheus.ErrResultTooLarge):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, &apiErr) && (apiErr.Type == v1.ErrBadData || apiErr.Type == v1.ErrExec):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, &apiErr) && (apiErr.Type == v1.ErrTimeout || apiErr.Type == v1.ErrCanceled):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, prometheus.ErrInvalidQuery):
+		return http.StatusBadRequest
+	}
+	return http.StatusBadGateway
+}
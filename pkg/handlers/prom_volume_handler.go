@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/analyzer"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/prometheus"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+)
+
+// volumeReport is a volume with the anomaly of the report, if any.
+type volumeReport struct {
+	prometheus.VolumeUsage
+	Anomaly *analyzer.Anomaly `json:"anomaly,omitempty"`
+}
+
+// GetVolumeReport returns the usage and fill-up forecast of the persistent
+// volume claims of the cluster, or of ?namespace=. Volumes predicted to fill
+// up within ?days= (default 7) are flagged; ?atRisk=true returns only those.
+// The forecast is fitted to the usage over ?window= (default 7d).
+func (h *PromHandler) GetVolumeReport(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+	if cs.PromClient == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Prometheus client not available"})
+		return
+	}
+
+	namespace := c.Query("namespace")
+	if namespace != "" && !rbac.CanAccess(user, "persistentvolumeclaims", string(common.VerbList), cs.Name, namespace) {
+		c.JSON(http.StatusForbidden, gin.H{"error": rbac.NoAccess(user.Key(), string(common.VerbList), "persistentvolumeclaims", namespace, cs.Name)})
+		return
+	}
+	days := float64(analyzer.VolumeFillWarningDays)
+	if v := c.Query("days"); v != "" {
+		d, err := strconv.ParseFloat(v, 64)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid days"})
+			return
+		}
+		days = d
+	}
+	window := prometheus.DefaultForecastWindow
+	if v := c.Query("window"); v != "" {
+		d, err := parsePromDuration(v)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid window"})
+			return
+		}
+		window = d
+	}
+	atRisk := c.Query("atRisk") == "true"
+
+	usages, err := cs.PromClient.GetVolumeUsages(c.Request.Context(), namespace, window)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to get volume usage: %v", err)})
+		return
+	}
+
+	result := make([]volumeReport, 0, len(usages))
+	for _, usage := range usages {
+		if !rbac.CanAccess(user, "persistentvolumeclaims", string(common.VerbList), cs.Name, usage.Namespace) {
+			continue
+		}
+		report := volumeReport{VolumeUsage: usage, Anomaly: analyzer.VolumeAtRisk(usage, days)}
+		if atRisk && report.Anomaly == nil {
+			continue
+		}
+		result = append(result, report)
+	}
+	c.JSON(http.StatusOK, gin.H{"days": days, "window": window.String(), "volumes": result})
+}
+
+// GetVolumeMetrics returns the usage history and forecast of a persistent
+// volume claim (/prometheus/volumes/:namespace/:name/metrics).
+func (h *PromHandler) GetVolumeMetrics(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+	namespace, name := c.Param("namespace"), c.Param("name")
+	if !rbac.CanAccess(user, "persistentvolumeclaims", string(common.VerbGet), cs.Name, namespace) {
+		c.JSON(http.StatusForbidden, gin.H{"error": rbac.NoAccess(user.Key(), string(common.VerbGet), "persistentvolumeclaims", namespace, cs.Name)})
+		return
+	}
+	if cs.PromClient == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Prometheus client not available"})
+		return
+	}
+
+	duration := c.DefaultQuery("duration", "24h")
+	if duration != "30m" && duration != "1h" && duration != "24h" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration. Must be one of: 30m, 1h, 24h"})
+		return
+	}
+	metrics, err := cs.PromClient.GetVolumeMetrics(c.Request.Context(), namespace, name, duration)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to get volume metrics: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, metrics)
+}
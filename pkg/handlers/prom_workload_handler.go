@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/prometheus"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// workloadKinds maps the resource names of the workload metrics route to the
+// workload kinds of the prometheus package.
+var workloadKinds = map[string]string{
+	"deployments":  prometheus.WorkloadDeployment,
+	"statefulsets": prometheus.WorkloadStatefulSet,
+	"daemonsets":   prometheus.WorkloadDaemonSet,
+}
+
+// GetWorkloadMetrics returns the metrics of a deployment, statefulset or
+// daemonset aggregated over its pods
+// (/prometheus/workloads/:kind/:namespace/:name/metrics).
+func (h *PromHandler) GetWorkloadMetrics(c *gin.Context) {
+	resource := c.Param("kind")
+	kind, ok := workloadKinds[resource]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be one of: deployments, statefulsets, daemonsets"})
+		return
+	}
+	h.getWorkloadMetrics(c, resource, kind, c.Param("namespace"), c.Param("name"))
+}
+
+// GetNamespaceMetrics returns the metrics of all pods of a namespace
+// (/prometheus/namespaces/:namespace/metrics).
+func (h *PromHandler) GetNamespaceMetrics(c *gin.Context) {
+	h.getWorkloadMetrics(c, "pods", prometheus.WorkloadNamespace, c.Param("namespace"), "")
+}
+
+func (h *PromHandler) getWorkloadMetrics(c *gin.Context, resource, kind, namespace, name string) {
+	ctx := c.Request.Context()
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+
+	if !rbac.CanAccess(user, resource, string(common.VerbGet), cs.Name, namespace) {
+		c.JSON(http.StatusForbidden, gin.H{"error": rbac.NoAccess(user.Key(), string(common.VerbGet), resource, namespace, cs.Name)})
+		return
+	}
+
+	duration := c.DefaultQuery("duration", "1h")
+	if duration != "30m" && duration != "1h" && duration != "24h" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration. Must be one of: 30m, 1h, 24h"})
+		return
+	}
+
+	// Try Prometheus first
+	if cs.PromClient != nil {
+		metrics, err := cs.PromClient.GetWorkloadMetrics(ctx, namespace, kind, name, duration)
+		if err == nil {
+			c.JSON(http.StatusOK, metrics)
+			return
+		}
+	}
+
+	// Fallback: metrics-server, with the current requests and limits of the pods
+	selector, err := workloadSelector(ctx, cs, kind, namespace, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get %s %s: %v", kind, name, err)})
+		return
+	}
+	podMetrics, err := h.fetchPodMetricsFromMetricsServer(c, namespace, "", "", selector)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get metrics from both Prometheus and metrics-server: %v", err)})
+		return
+	}
+	metrics := &prometheus.WorkloadMetrics{
+		CPU:      podMetrics.CPU,
+		Memory:   podMetrics.Memory,
+		Fallback: true,
+	}
+	if err := fillPodResources(ctx, cs, namespace, selector, metrics); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list pods: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, metrics)
+}
+
+// workloadSelector returns the pod label selector of a workload, or "" for a
+// namespace.
+func workloadSelector(ctx context.Context, cs *cluster.ClientSet, kind, namespace, name string) (string, error) {
+	var obj client.Object
+	var podSelector func() *metav1.LabelSelector
+	switch kind {
+	case prometheus.WorkloadNamespace:
+		return "", nil
+	case prometheus.WorkloadDeployment:
+		d := &appsv1.Deployment{}
+		obj, podSelector = d, func() *metav1.LabelSelector { return d.Spec.Selector }
+	case prometheus.WorkloadStatefulSet:
+		s := &appsv1.StatefulSet{}
+		obj, podSelector = s, func() *metav1.LabelSelector { return s.Spec.Selector }
+	case prometheus.WorkloadDaemonSet:
+		d := &appsv1.DaemonSet{}
+		obj, podSelector = d, func() *metav1.LabelSelector { return d.Spec.Selector }
+	default:
+		return "", fmt.Errorf("unsupported workload kind: %s", kind)
+	}
+	if err := cs.K8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, obj); err != nil {
+		return "", err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(podSelector())
+	if err != nil {
+		return "", err
+	}
+	if selector.Empty() {
+		return "", fmt.Errorf("%s %s has an empty selector", kind, name)
+	}
+	return selector.String(), nil
+}
+
+// fillPodResources sets the requests and limits of metrics to the current sum
+// over the running pods matching selector. CPU is in cores and memory in MB.
+func fillPodResources(ctx context.Context, cs *cluster.ClientSet, namespace, selector string, metrics *prometheus.WorkloadMetrics) error {
+	s, err := labels.Parse(selector)
+	if err != nil {
+		return err
+	}
+	var pods corev1.PodList
+	if err := cs.K8sClient.List(ctx, &pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: s}); err != nil {
+		return err
+	}
+
+	var cpuRequest, cpuLimit, memRequest, memLimit float64
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			cpuRequest += float64(container.Resources.Requests.Cpu().MilliValue()) / 1000.0
+			cpuLimit += float64(container.Resources.Limits.Cpu().MilliValue()) / 1000.0
+			memRequest += float64(container.Resources.Requests.Memory().Value()) / 1024.0 / 1024.0
+			memLimit += float64(container.Resources.Limits.Memory().Value()) / 1024.0 / 1024.0
+		}
+	}
+
+	now := time.Now()
+	point := func(v float64) []prometheus.UsageDataPoint {
+		return []prometheus.UsageDataPoint{{Timestamp: now, Value: v}}
+	}
+	metrics.CPURequest = point(cpuRequest)
+	metrics.CPULimit = point(cpuLimit)
+	metrics.MemoryRequest = point(memRequest)
+	metrics.MemoryLimit = point(memLimit)
+	return nil
+}
@@ -1,21 +1,23 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pixelvide/kube-sentinel/pkg/cluster"
 	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/handlers/resources"
+	"github.com/pixelvide/kube-sentinel/pkg/kube"
 	"github.com/pixelvide/kube-sentinel/pkg/model"
 	"github.com/pixelvide/kube-sentinel/pkg/rbac"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	syaml "sigs.k8s.io/yaml"
 )
 
 type ResourceApplyHandler struct {
@@ -26,10 +28,48 @@ func NewResourceApplyHandler() *ResourceApplyHandler {
 }
 
 type ApplyResourceRequest struct {
+	// YAML holds one or more documents, which may be List kinds
 	YAML string `json:"yaml" binding:"required"`
+	// Force takes ownership of fields managed by other field managers
+	Force bool `json:"force"`
+	// DryRun returns the changes the apply would make without applying them
+	DryRun bool `json:"dryRun"`
+	// Atomic stops at the first failure and deletes the objects created by
+	// the request
+	Atomic bool `json:"atomic"`
 }
 
-// ApplyResource applies a YAML resource to the cluster
+// ApplyResult is the outcome of applying one object.
+type ApplyResult struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	Resource   string `json:"resource,omitempty"`
+	// Operation is "create" or "update", or "unchanged" for dry runs
+	Operation  string             `json:"operation,omitempty"`
+	Success    bool               `json:"success"`
+	Error      string             `json:"error,omitempty"`
+	Conflicts  []kube.Conflict    `json:"conflicts,omitempty"`
+	Preview    *kube.ApplyPreview `json:"preview,omitempty"`
+	Skipped    bool               `json:"skipped,omitempty"`
+	RolledBack bool               `json:"rolledBack,omitempty"`
+
+	status int
+	obj    *unstructured.Unstructured
+}
+
+func (r *ApplyResult) fail(status int, err error) {
+	r.status = status
+	r.Error = err.Error()
+}
+
+// ApplyResource applies YAML documents to the cluster with server-side apply.
+// Objects are applied in dependency order, Namespaces and CRDs first, and the
+// result of each is reported. Without atomic, a failing object does not stop
+// the others. With dryRun the server computes the results without persisting
+// them and the diff of each object to the live one is returned, with the
+// fields of other managers the apply conflicts with.
 func (h *ResourceApplyHandler) ApplyResource(c *gin.Context) {
 	cs := c.MustGet("cluster").(*cluster.ClientSet)
 	user := c.MustGet("user").(model.User)
@@ -40,99 +80,199 @@ func (h *ResourceApplyHandler) ApplyResource(c *gin.Context) {
 		return
 	}
 
-	// Decode YAML into unstructured object
-	decodeUniversal := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
-	obj := &unstructured.Unstructured{}
-
-	_, _, err := decodeUniversal.Decode([]byte(req.YAML), nil, obj)
+	objects, err := kube.DecodeObjects([]byte(req.YAML))
 	if err != nil {
 		klog.Errorf("Failed to decode YAML: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid YAML format: " + err.Error()})
 		return
 	}
+	if len(objects) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no objects found in YAML"})
+		return
+	}
+	kube.SortForApply(objects)
+
+	opts := kube.ApplyOptions{Force: req.Force, DryRun: req.DryRun}
+	results := make([]*ApplyResult, 0, len(objects))
+	failed := 0
+	for _, obj := range objects {
+		if req.Atomic && failed > 0 {
+			results = append(results, &ApplyResult{
+				APIVersion: obj.GetAPIVersion(),
+				Kind:       obj.GetKind(),
+				Name:       obj.GetName(),
+				Namespace:  obj.GetNamespace(),
+				Error:      "skipped after an earlier object failed",
+				Skipped:    true,
+			})
+			continue
+		}
+		result := h.applyObject(c, cs, user, obj, opts)
+		if !result.Success {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	rolledBack := false
+	if req.Atomic && failed > 0 && !req.DryRun {
+		h.rollback(c, cs, results)
+		rolledBack = true
+	}
 
-	resource := strings.ToLower(obj.GetKind()) + "s"
-	if !rbac.CanAccess(user, resource, "create", cs.Name, obj.GetNamespace()) {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error": rbac.NoAccess(user.Key(), string(common.VerbCreate), resource, obj.GetNamespace(), cs.Name)})
+	if len(results) == 1 && failed == 1 {
+		// Keep the response of a single object apply
+		c.JSON(results[0].status, gin.H{"error": results[0].Error, "conflicts": results[0].Conflicts, "results": results})
 		return
 	}
+	status := http.StatusOK
+	message := "Resource applied successfully"
+	if len(results) > 1 {
+		message = fmt.Sprintf("%d resources applied successfully", len(results))
+	}
+	if req.DryRun {
+		message = "Dry run completed"
+	}
+	response := gin.H{
+		"message":    message,
+		"dryRun":     req.DryRun,
+		"succeeded":  len(results) - failed,
+		"failed":     failed,
+		"rolledBack": rolledBack,
+		"results":    results,
+	}
+	if len(results) == 1 {
+		response["kind"] = results[0].Kind
+		response["name"] = results[0].Name
+		response["namespace"] = results[0].Namespace
+	}
+	if failed > 0 {
+		// Not a 2xx status, so clients do not mistake a partial apply for success
+		status = http.StatusUnprocessableEntity
+		response["error"] = fmt.Sprintf("%d of %d resources failed to apply", failed, len(results))
+		delete(response, "message")
+	}
+	c.JSON(status, response)
+}
 
+// applyObject resolves the resource of obj, checks access and applies or,
+// with opts.DryRun, previews it.
+func (h *ResourceApplyHandler) applyObject(c *gin.Context, cs *cluster.ClientSet, user model.User, obj *unstructured.Unstructured, opts kube.ApplyOptions) *ApplyResult {
 	ctx := c.Request.Context()
+	gvk := obj.GroupVersionKind()
+	result := &ApplyResult{APIVersion: obj.GetAPIVersion(), Kind: obj.GetKind(), Name: obj.GetName(), obj: obj}
+	if obj.GetName() == "" {
+		result.fail(http.StatusBadRequest, fmt.Errorf("metadata.name is required"))
+		return result
+	}
 
-	existingObj := &unstructured.Unstructured{}
-	existingObj.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
-	existingObj.SetName(obj.GetName())
-	existingObj.SetNamespace(obj.GetNamespace())
-
-	err = cs.K8sClient.Get(ctx, client.ObjectKey{
-		Name:      obj.GetName(),
-		Namespace: obj.GetNamespace(),
-	}, existingObj)
-
-	defer func() {
-		previousYAML := []byte{}
-		if existingObj.GetResourceVersion() != "" {
-			existingObj.SetManagedFields(nil)
-			previousYAML, _ = syaml.Marshal(existingObj)
+	mapping, err := cs.K8sClient.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		result.fail(http.StatusBadRequest, fmt.Errorf("unknown kind %s: %w", gvk.String(), err))
+		return result
+	}
+	result.Resource = kube.ResourceName(mapping.Resource)
+	rbacNamespace := "_all"
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(metav1.NamespaceDefault)
 		}
-		errMessage := ""
+		rbacNamespace = obj.GetNamespace()
+	} else {
+		obj.SetNamespace("")
+	}
+	result.Namespace = obj.GetNamespace()
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(gvk)
+	var live *unstructured.Unstructured
+	err = cs.K8sClient.Live.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	switch {
+	case apierrors.IsNotFound(err):
+		result.Operation = "create"
+	case err == nil:
+		live = existing
+		result.Operation = "update"
+	default:
+		result.fail(http.StatusInternalServerError, fmt.Errorf("failed to get resource: %w", err))
+		return result
+	}
+
+	// Applying an existing object updates it
+	verb := common.VerbCreate
+	if live != nil {
+		verb = common.VerbUpdate
+	}
+	if !rbac.CanAccess(user, result.Resource, string(verb), cs.Name, rbacNamespace) {
+		result.fail(http.StatusForbidden, fmt.Errorf("%s", rbac.NoAccess(user.Key(), string(verb), result.Resource, rbacNamespace, cs.Name)))
+		return result
+	}
+
+	if opts.DryRun {
+		preview, err := kube.PreviewApply(ctx, cs.K8sClient, obj, live, opts)
 		if err != nil {
-			errMessage = err.Error()
+			result.fail(http.StatusInternalServerError, fmt.Errorf("failed to preview resource: %w", err))
+			return result
 		}
+		result.Operation = preview.Operation
+		result.Conflicts, preview.Conflicts = preview.Conflicts, nil
+		result.Preview = preview
+		result.Success = true
+		return result
+	}
 
-		payloadData := map[string]interface{}{
-			"clusterName":  cs.Name,
-			"resourceType": resource,
-			"resourceName": obj.GetName(),
-			"namespace":    obj.GetNamespace(),
-			"resourceYaml": req.YAML,
-			"previousYaml": string(previousYAML),
-		}
-		payloadBytes, err := json.Marshal(payloadData)
-		if err != nil {
-			klog.Errorf("Failed to marshal audit payload: %v", err)
+	// The audit log records the object as applied, before the apply fills
+	// in obj with the result
+	applied := obj.DeepCopy()
+	err = kube.ServerSideApply(ctx, cs.K8sClient, obj, opts)
+	var prev client.Object
+	if live != nil {
+		prev = live
+	}
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	resources.RecordAudit(c, "apply", result.Resource, prev, applied, err == nil, errMsg)
+	if err != nil {
+		if conflicts := kube.ApplyConflicts(err); len(conflicts) > 0 {
+			result.Conflicts = conflicts
+			result.fail(http.StatusConflict, fmt.Errorf("apply conflicts with fields managed by other field managers, apply with force to take ownership: %w", err))
+		} else if apierrors.IsConflict(err) {
+			result.fail(http.StatusConflict, fmt.Errorf("failed to apply resource: %w", err))
+		} else {
+			result.fail(http.StatusInternalServerError, fmt.Errorf("failed to apply resource: %w", err))
 		}
+		klog.Errorf("Failed to apply resource %s %s/%s: %v", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+		return result
+	}
 
-		model.DB.Create(&model.AuditLog{
-			AppID:        model.CurrentApp.ID,
-			Action:       "apply",
-			ActorID:      user.ID,
-			Payload:      string(payloadBytes),
-			Success:      err == nil,
-			ErrorMessage: errMessage,
-			IPAddress:    c.ClientIP(),
-			UserAgent:    c.Request.UserAgent(),
-		})
-	}()
+	klog.Infof("Successfully applied resource: %s/%s", obj.GetKind(), obj.GetName())
+	result.Success = true
+	return result
+}
 
-	switch {
-	case apierrors.IsNotFound(err):
-		err = cs.K8sClient.Create(ctx, obj)
-		if err != nil {
-			klog.Errorf("Failed to create resource: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create resource: " + err.Error()})
-			return
+// rollback deletes the objects created by a failed atomic apply, newest
+// first. Objects that were updated are left as they are.
+func (h *ResourceApplyHandler) rollback(c *gin.Context, cs *cluster.ClientSet, results []*ApplyResult) {
+	// Deletions must not be cancelled with the request
+	ctx := context.WithoutCancel(c.Request.Context())
+	for i := len(results) - 1; i >= 0; i-- {
+		r := results[i]
+		if !r.Success || r.Operation != "create" {
+			continue
 		}
-	case err == nil:
-		obj.SetResourceVersion(existingObj.GetResourceVersion())
-		err = cs.K8sClient.Update(ctx, obj)
+		err := cs.K8sClient.Delete(ctx, r.obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		errMsg := ""
 		if err != nil {
-			klog.Errorf("Failed to update resource: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update resource: " + err.Error()})
-			return
+			errMsg = err.Error()
 		}
-	default:
-		klog.Errorf("Failed to get resource: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get resource: " + err.Error()})
-		return
+		resources.RecordAudit(c, "delete", r.Resource, r.obj, nil, err == nil, errMsg)
+		if err != nil && !apierrors.IsNotFound(err) {
+			klog.Errorf("Failed to roll back %s %s/%s: %v", r.Kind, r.Namespace, r.Name, err)
+			r.Error = "rollback failed: " + err.Error()
+			continue
+		}
+		r.RolledBack = true
 	}
-
-	klog.Infof("Successfully applied resource: %s/%s", obj.GetKind(), obj.GetName())
-	c.JSON(http.StatusOK, gin.H{
-		"message":   "Resource applied successfully",
-		"kind":      obj.GetKind(),
-		"name":      obj.GetName(),
-		"namespace": obj.GetNamespace(),
-	})
 }
@@ -0,0 +1,60 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/alertmanager"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+)
+
+// errAlertmanagerUnavailable is returned when the cluster has no Alertmanager.
+var errAlertmanagerUnavailable = errors.New("alertmanager client not available")
+
+// includeAlerts reports whether a detail request asks for the alerts of a
+// resource with ?include=alerts. They are opt-in so the object returned to the
+// YAML editor stays a plain Kubernetes object.
+func includeAlerts(c *gin.Context, resource string) bool {
+	return c.Query("include") == "alerts" && slices.Contains(alertmanager.SupportedResources(), resource)
+}
+
+// listResourceAlerts returns the Alertmanager alerts of a resource and its
+// pods that pass the alert filters of the groups of the cluster.
+func listResourceAlerts(ctx context.Context, cs *cluster.ClientSet, resource, namespace, name string) ([]alertmanager.Alert, error) {
+	if cs.AlertmanagerClient == nil {
+		return nil, errAlertmanagerUnavailable
+	}
+	filters := append([]string{fmt.Sprintf("%s=%q", alertmanager.NamespaceLabel, namespace)}, model.GetClusterGroupSettings(cs.Name).AlertFilters...)
+	alerts, err := cs.AlertmanagerClient.ListAlerts(ctx, filters...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	result := make([]alertmanager.Alert, 0)
+	for _, alert := range alerts {
+		if alertmanager.MatchResource(alert.Labels, resource, namespace, name) {
+			result = append(result, alert)
+		}
+	}
+	return result, nil
+}
+
+// GetResourceAlerts returns the Alertmanager alerts of a resource and its
+// pods. Access is checked by the RBAC middleware on the resource itself.
+func GetResourceAlerts(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	alerts, err := listResourceAlerts(c.Request.Context(), cs, c.GetString("resource"), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, errAlertmanagerUnavailable) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, alerts)
+}
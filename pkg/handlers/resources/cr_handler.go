@@ -10,6 +10,7 @@ import (
 	"github.com/pixelvide/kube-sentinel/pkg/cluster"
 	"github.com/pixelvide/kube-sentinel/pkg/common"
 	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -116,7 +117,7 @@ func (h *CRHandler) List(c *gin.Context) {
 					allItems = append(allItems, nsList.Items...)
 				}
 				crList.Items = allItems
-				c.JSON(http.StatusOK, crList)
+				h.writeList(c, crd, gvr, crList)
 				return
 			}
 			opts.Namespace = namespaces[0]
@@ -128,6 +129,16 @@ func (h *CRHandler) List(c *gin.Context) {
 		return
 	}
 
+	h.writeList(c, crd, gvr, crList)
+}
+
+// writeList writes the custom resources, as a Table with the printer columns
+// of the CRD for ?view=table.
+func (h *CRHandler) writeList(c *gin.Context, crd *apiextensionsv1.CustomResourceDefinition, gvr schema.GroupVersionResource, crList *unstructured.UnstructuredList) {
+	if c.Query("view") == "table" {
+		writeCRTable(c, crd, gvr.Version, crList.Items)
+		return
+	}
 	c.JSON(http.StatusOK, crList)
 }
 
@@ -252,11 +263,19 @@ func (h *CRHandler) Create(c *gin.Context) {
 		cr.SetNamespace(namespace)
 	}
 
+	var success bool
+	var errMsg string
+	defer func() {
+		RecordAudit(c, "create", crdName, nil, &cr, success, errMsg)
+	}()
+
 	if err := cs.K8sClient.Create(ctx, &cr); err != nil {
+		errMsg = err.Error()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	success = true
 	c.JSON(http.StatusCreated, cr)
 }
 
@@ -310,7 +329,7 @@ func (h *CRHandler) Update(c *gin.Context) {
 		namespacedName = types.NamespacedName{Name: name}
 	}
 
-	if err := cs.K8sClient.Get(ctx, namespacedName, existingCR); err != nil {
+	if err := cs.K8sClient.Live.Get(ctx, namespacedName, existingCR); err != nil {
 		if errors.IsNotFound(err) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
 			return
@@ -336,11 +355,33 @@ func (h *CRHandler) Update(c *gin.Context) {
 		updatedCR.SetNamespace(existingCR.GetNamespace())
 	}
 
-	if err := cs.K8sClient.Update(ctx, &updatedCR); err != nil {
+	if c.Query("dryRun") == "true" {
+		if err := cs.K8sClient.Update(ctx, &updatedCR, client.DryRunAll, client.FieldOwner(kube.FieldManager)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		preview, err := kube.NewApplyPreview(existingCR, &updatedCR, nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, preview)
+		return
+	}
+
+	var success bool
+	var errMsg string
+	defer func() {
+		RecordAudit(c, "update", crdName, existingCR, &updatedCR, success, errMsg)
+	}()
+
+	if err := cs.K8sClient.Update(ctx, &updatedCR, client.FieldOwner(kube.FieldManager)); err != nil {
+		errMsg = err.Error()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	success = true
 	c.JSON(http.StatusOK, updatedCR)
 }
 
@@ -415,9 +456,11 @@ func (h *CRHandler) Delete(c *gin.Context) {
 		opts.GracePeriodSeconds = &gracePeriodSeconds
 	}
 	if err := cs.K8sClient.Delete(ctx, cr, opts); err != nil {
+		RecordAudit(c, "delete", crdName, cr, nil, false, err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	RecordAudit(c, "delete", crdName, cr.DeepCopy(), nil, true, "")
 
 	if wait := c.Query("wait") != "false"; wait {
 		timeout := 1 * time.Minute
@@ -489,3 +532,102 @@ func (h *CRHandler) Describe(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"result": out})
 }
+
+// Patch patches a custom resource with a JSON merge patch, or a JSON patch
+// with ?patchType=json. Strategic merge patches do not apply to custom
+// resources.
+func (h *CRHandler) Patch(c *gin.Context) {
+	crdName := c.Param("crd")
+	patchBytes, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read patch data"})
+		return
+	}
+	patchType := types.MergePatchType
+	switch c.Query("patchType") {
+	case "", "merge":
+	case "json":
+		patchType = types.JSONPatchType
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "patchType must be merge or json for custom resources"})
+		return
+	}
+
+	obj, err := GetResource(c, crdName, c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	cr := obj.(*unstructured.Unstructured)
+	prev := cr.DeepCopy()
+
+	success := false
+	var errMsg string
+	defer func() {
+		RecordAudit(c, "patch", crdName, prev, cr, success, errMsg)
+	}()
+
+	if err := cs.K8sClient.Patch(c.Request.Context(), cr, client.RawPatch(patchType, patchBytes), client.FieldOwner(kube.FieldManager)); err != nil {
+		errMsg = err.Error()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	success = true
+	c.JSON(http.StatusOK, cr)
+}
+
+// ListHistory returns the audit log of a custom resource.
+func (h *CRHandler) ListHistory(c *gin.Context) {
+	listHistory(c, c.Param("crd"))
+}
+
+// GetAnalysis runs the analyzers against a custom resource.
+func (h *CRHandler) GetAnalysis(c *gin.Context) {
+	obj, err := GetResource(c, c.Param("crd"), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	analyze(c, obj.(*unstructured.Unstructured))
+}
+
+// GetRelatedResources returns the owners of a custom resource and the
+// objects it owns, found by their owner references.
+func (h *CRHandler) GetRelatedResources(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	ctx := c.Request.Context()
+	crd, err := h.getCRDByName(ctx, cs.K8sClient, c.Param("crd"))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	obj, err := GetResource(c, crd.Name, c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	cr := obj.(*unstructured.Unstructured)
+
+	result := make([]common.RelatedResource, 0)
+	result = append(result, discoverOwners(ctx, cs, cr)...)
+	result = append(result, discoverDependents(ctx, cs, c.MustGet("user").(model.User), cr, crd.Spec.Group)...)
+	c.JSON(http.StatusOK, result)
+}
@@ -1,12 +1,7 @@
 package resources
 
 import (
-	"time"
-
-	"github.com/gin-gonic/gin"
-	"github.com/pixelvide/kube-sentinel/pkg/cluster"
 	appsv1 "k8s.io/api/apps/v1"
-	"k8s.io/apimachinery/pkg/types"
 )
 
 type DeploymentHandler struct {
@@ -22,16 +17,3 @@ func NewDeploymentHandler() *DeploymentHandler {
 		),
 	}
 }
-
-func (h *DeploymentHandler) Restart(c *gin.Context, namespace, name string) error {
-	var deployment appsv1.Deployment
-	cs := c.MustGet("cluster").(*cluster.ClientSet)
-	if err := cs.K8sClient.Get(c.Request.Context(), types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
-		return err
-	}
-	if deployment.Spec.Template.Annotations == nil {
-		deployment.Spec.Template.Annotations = make(map[string]string)
-	}
-	deployment.Spec.Template.Annotations["kube-sentinel.kubernetes.io/restartedAt"] = time.Now().Format(time.RFC3339)
-	return cs.K8sClient.Update(c.Request.Context(), &deployment)
-}
@@ -22,6 +22,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -29,6 +30,7 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/kubectl/pkg/describe"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/yaml"
 )
 
@@ -78,27 +80,54 @@ func (h *GenericResourceHandler[T, V]) getGroupKind() schema.GroupKind {
 	return gvks[0].GroupKind()
 }
 
+// groupVersionResource returns the API resource of the handler.
+func (h *GenericResourceHandler[T, V]) groupVersionResource(cs *cluster.ClientSet) (schema.GroupVersionResource, error) {
+	obj := reflect.New(h.objectType).Interface().(T)
+	gvk, err := apiutil.GVKForObject(obj, kube.GetScheme())
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+	mapping, err := cs.K8sClient.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+	return mapping.Resource, nil
+}
+
 func (h *GenericResourceHandler[T, V]) recordHistory(c *gin.Context, opType string, prev, curr T, success bool, errMsg string) {
+	var prevObj, currObj client.Object
+	if !reflect.ValueOf(prev).IsNil() {
+		prevObj = prev
+	}
+	if !reflect.ValueOf(curr).IsNil() {
+		currObj = curr
+	}
+	RecordAudit(c, opType, h.name, prevObj, currObj, success, errMsg)
+}
+
+// RecordAudit records an operation on an object of resourceType in the audit
+// log, which is the history of the resource. prev or curr may be nil.
+func RecordAudit(c *gin.Context, opType, resourceType string, prev, curr client.Object, success bool, errMsg string) {
 	cs := c.MustGet("cluster").(*cluster.ClientSet)
 	user := c.MustGet("user").(model.User)
 
 	var name, namespace string
 	// Safely get name and namespace from either prev or curr
-	if !reflect.ValueOf(curr).IsNil() {
+	if curr != nil {
 		name = curr.GetName()
 		namespace = curr.GetNamespace()
-	} else if !reflect.ValueOf(prev).IsNil() {
+	} else if prev != nil {
 		name = prev.GetName()
 		namespace = prev.GetNamespace()
 	}
 
 	payloadData := map[string]interface{}{
 		"clusterName":  cs.Name,
-		"resourceType": h.name,
+		"resourceType": resourceType,
 		"resourceName": name,
 		"namespace":    namespace,
-		"resourceYaml": h.ToYAML(curr),
-		"previousYaml": h.ToYAML(prev),
+		"resourceYaml": objectYAML(curr),
+		"previousYaml": objectYAML(prev),
 	}
 	payloadBytes, err := json.Marshal(payloadData)
 	if err != nil {
@@ -121,6 +150,18 @@ func (h *GenericResourceHandler[T, V]) recordHistory(c *gin.Context, opType stri
 	}
 }
 
+func objectYAML(obj client.Object) string {
+	if obj == nil {
+		return ""
+	}
+	obj.SetManagedFields(nil)
+	yamlBytes, err := yaml.Marshal(obj)
+	if err != nil {
+		return ""
+	}
+	return string(yamlBytes)
+}
+
 func (h *GenericResourceHandler[T, V]) IsClusterScoped() bool {
 	return h.isClusterScoped
 }
@@ -169,6 +210,25 @@ func (h *GenericResourceHandler[T, V]) Get(c *gin.Context) {
 		delete(anno, common.KubectlAnnotation)
 	}
 
+	if !h.isClusterScoped && includeAlerts(c, h.name) {
+		detail, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		cs := c.MustGet("cluster").(*cluster.ClientSet)
+		alerts, err := listResourceAlerts(c.Request.Context(), cs, h.name, obj.GetNamespace(), obj.GetName())
+		if err != nil {
+			// The object is still shown when Alertmanager cannot be reached
+			klog.Warningf("Failed to get alerts of %s %s/%s: %v", h.name, obj.GetNamespace(), obj.GetName(), err)
+			detail["alertsError"] = err.Error()
+		} else {
+			detail["alerts"] = alerts
+		}
+		c.JSON(http.StatusOK, detail)
+		return
+	}
+
 	c.JSON(http.StatusOK, object)
 }
 
@@ -183,9 +243,17 @@ func (h *GenericResourceHandler[T, V]) GetAnalysis(c *gin.Context) {
 		return
 	}
 
-	obj := object.(client.Object)
+	analyze(c, object.(client.Object))
+}
+
+// analyze serves the analysis of obj by the analyzers enabled for the cluster.
+func analyze(c *gin.Context, obj client.Object) {
 	cs := c.MustGet("cluster").(*cluster.ClientSet)
-	analysis := analyzer.Analyze(c.Request.Context(), cs.K8sClient, obj)
+	ctx := c.Request.Context()
+	if cs.PromClient != nil {
+		ctx = analyzer.WithVolumeForecaster(ctx, cs.PromClient)
+	}
+	analysis := analyzer.Analyze(ctx, cs.K8sClient, obj, model.GetDisabledAnalyzers(cs.Name)...)
 
 	c.JSON(http.StatusOK, analysis)
 }
@@ -209,7 +277,12 @@ func (h *GenericResourceHandler[T, V]) list(c *gin.Context) (V, error) {
 			listOpts = append(listOpts, client.InNamespace(namespaces[0]))
 		}
 	}
-	if c.Query("limit") != "" {
+	query, err := parseListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return zero, err
+	}
+	if query == nil && c.Query("limit") != "" {
 		limit, err := strconv.ParseInt(c.Query("limit"), 10, 64)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
@@ -218,7 +291,7 @@ func (h *GenericResourceHandler[T, V]) list(c *gin.Context) (V, error) {
 		listOpts = append(listOpts, client.Limit(limit))
 	}
 
-	if c.Query("continue") != "" {
+	if query == nil && c.Query("continue") != "" {
 		continueToken := c.Query("continue")
 		listOpts = append(listOpts, client.Continue(continueToken))
 	}
@@ -249,12 +322,24 @@ func (h *GenericResourceHandler[T, V]) list(c *gin.Context) (V, error) {
 		listOpts = append(listOpts, client.MatchingFieldsSelector{Selector: fieldSelectorOption})
 	}
 
-	if err := cs.K8sClient.List(ctx, objectList, listOpts...); err != nil {
+	user := c.MustGet("user").(model.User)
+	// Lists in the default order that drop nothing for the user can be paged
+	// by the API server.
+	paged := false
+	if query != nil {
+		serverPaging := c.Query("sortBy") == "" && c.Query("sortOrder") == "" && query.Filter == "" &&
+			!h.filtersNamespaces(user, cs.Name, namespaces)
+		paged, err = cs.K8sClient.ListForQuery(ctx, objectList, query, serverPaging, listOpts...)
+	} else {
+		err = cs.K8sClient.List(ctx, objectList, listOpts...)
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return zero, err
 	}
 
-	// Sort by creation timestamp in descending order (newest first)
+	// Sort by creation timestamp in descending order (newest first), unless
+	// a query sorts them itself
 	// Extract items using reflection and sort them directly
 
 	items, err := meta.ExtractList(objectList)
@@ -262,23 +347,24 @@ func (h *GenericResourceHandler[T, V]) list(c *gin.Context) (V, error) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to extract items from list"})
 		return zero, err
 	}
-	sort.Slice(items, func(i, j int) bool {
-		o1, _ := meta.Accessor(items[i])
-		o2, _ := meta.Accessor(items[j])
-		if o1 == nil || o2 == nil {
-			return false // Handle nil cases gracefully
-		}
+	if query == nil {
+		sort.Slice(items, func(i, j int) bool {
+			o1, _ := meta.Accessor(items[i])
+			o2, _ := meta.Accessor(items[j])
+			if o1 == nil || o2 == nil {
+				return false // Handle nil cases gracefully
+			}
 
-		t1 := o1.GetCreationTimestamp()
-		t2 := o2.GetCreationTimestamp()
-		if t1.Equal(&t2) {
-			return o1.GetName() < o2.GetName()
-		}
+			t1 := o1.GetCreationTimestamp()
+			t2 := o2.GetCreationTimestamp()
+			if t1.Equal(&t2) {
+				return o1.GetName() < o2.GetName()
+			}
 
-		return t1.After(t2.Time)
-	})
+			return t1.After(t2.Time)
+		})
+	}
 
-	user := c.MustGet("user").(model.User)
 	filterItems := make([]runtime.Object, 0, len(items))
 	for i := range items {
 		obj, err := meta.Accessor(items[i])
@@ -315,16 +401,52 @@ func (h *GenericResourceHandler[T, V]) list(c *gin.Context) (V, error) {
 		}
 		filterItems = append(filterItems, items[i])
 	}
+	if query != nil && !paged {
+		page, next, remaining, err := query.Apply(filterItems)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return zero, err
+		}
+		filterItems = page
+		objectList.SetContinue(next)
+		if next != "" {
+			objectList.SetRemainingItemCount(&remaining)
+		}
+	}
 	_ = meta.SetList(objectList, filterItems)
 
 	return objectList, nil
 }
 
+// filtersNamespaces reports whether a list of namespaces for user drops
+// objects in namespaces the user cannot access.
+func (h *GenericResourceHandler[T, V]) filtersNamespaces(user model.User, cluster string, namespaces []string) bool {
+	switch {
+	case h.Name() == "namespaces":
+		return !rbac.CanAccessAllNamespaces(user, cluster)
+	case h.isClusterScoped:
+		return false
+	case len(namespaces) == 1:
+		return !rbac.CanAccessNamespace(user, cluster, namespaces[0])
+	case len(namespaces) > 1:
+		return true
+	}
+	return !rbac.CanAccessAllNamespaces(user, cluster)
+}
+
 func (h *GenericResourceHandler[T, V]) List(c *gin.Context) {
+	if c.Query("view") == "table" {
+		h.ListTable(c)
+		return
+	}
 	object, err := h.list(c)
 	if err != nil {
 		return
 	}
+	if c.Query("view") == "summary" {
+		writeSummaryList(c, object)
+		return
+	}
 	c.JSON(http.StatusOK, object)
 }
 
@@ -371,17 +493,12 @@ func (h *GenericResourceHandler[T, V]) Update(c *gin.Context) {
 	if h.isClusterScoped {
 		namespacedName = types.NamespacedName{Name: name}
 	}
-	if err := cs.K8sClient.Get(c.Request.Context(), namespacedName, oldObj); err != nil {
+	// The preview and the history compare with the object on the server
+	if err := cs.K8sClient.Live.Get(c.Request.Context(), namespacedName, oldObj); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	var success bool
-	var errMsg string
-	defer func() {
-		h.recordHistory(c, "update", oldObj, resource, success, errMsg)
-	}()
-
 	resource.SetName(name)
 	if !h.isClusterScoped {
 		namespace := c.Param("namespace")
@@ -391,7 +508,18 @@ func (h *GenericResourceHandler[T, V]) Update(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	if err := cs.K8sClient.Update(ctx, resource); err != nil {
+	if c.Query("dryRun") == "true" {
+		h.previewUpdate(c, cs, oldObj, resource)
+		return
+	}
+
+	var success bool
+	var errMsg string
+	defer func() {
+		h.recordHistory(c, "update", oldObj, resource, success, errMsg)
+	}()
+
+	if err := cs.K8sClient.Update(ctx, resource, client.FieldOwner(kube.FieldManager)); err != nil {
 		errMsg = err.Error()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -401,6 +529,43 @@ func (h *GenericResourceHandler[T, V]) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, resource)
 }
 
+// previewUpdate dry runs the update of live to resource and returns the
+// changes the server would make, for the YAML editor to preview.
+func (h *GenericResourceHandler[T, V]) previewUpdate(c *gin.Context, cs *cluster.ClientSet, live, resource T) {
+	gvk, err := cs.K8sClient.GroupVersionKindFor(live)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := cs.K8sClient.Update(c.Request.Context(), resource, client.DryRunAll, client.FieldOwner(kube.FieldManager)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsConflict(err) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	objects := make([]*unstructured.Unstructured, 0, 2)
+	for _, obj := range []T{live, resource} {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// Typed objects may come back without their type meta
+		u := &unstructured.Unstructured{Object: content}
+		u.SetGroupVersionKind(gvk)
+		objects = append(objects, u)
+	}
+	preview, err := kube.NewApplyPreview(objects[0], objects[1], nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
 func (h *GenericResourceHandler[T, V]) Patch(c *gin.Context) {
 	name := c.Param("name")
 	cs := c.MustGet("cluster").(*cluster.ClientSet)
@@ -590,6 +755,12 @@ func (h *GenericResourceHandler[T, V]) Search(c *gin.Context, q string, limit in
 func (h *GenericResourceHandler[T, V]) registerCustomRoutes(group *gin.RouterGroup) {}
 
 func (h *GenericResourceHandler[T, V]) ListHistory(c *gin.Context) {
+	listHistory(c, h.name)
+}
+
+// listHistory serves the audit log entries of the resource in the route as
+// its history, newest first.
+func listHistory(c *gin.Context, resourceType string) {
 	cs := c.MustGet("cluster").(*cluster.ClientSet)
 	namespace := c.Param("namespace")
 	resourceName := c.Param("name")
@@ -607,9 +778,9 @@ func (h *GenericResourceHandler[T, V]) ListHistory(c *gin.Context) {
 	// Get total count
 	var total int64
 	query := model.DB.Model(&model.AuditLog{}).
-		Where("action IN (?)", []string{"create", "update", "patch", "delete", "apply"}).
+		Where("action IN (?)", []string{"create", "update", "patch", "delete", "apply", "rollback", "pause", "resume", "restart", "restore"}).
 		Where("payload LIKE ?", "%"+cs.Name+"%").
-		Where("payload LIKE ?", "%"+h.name+"%").
+		Where("payload LIKE ?", "%"+resourceType+"%").
 		Where("payload LIKE ?", "%"+resourceName+"%")
 
 	if namespace != "" {
@@ -638,7 +809,7 @@ func (h *GenericResourceHandler[T, V]) ListHistory(c *gin.Context) {
 		}
 
 		// Check if it's actually for this specific resource (naive LIKE check above might match others)
-		if p["clusterName"] == cs.Name && p["resourceType"] == h.name && p["resourceName"] == resourceName {
+		if p["clusterName"] == cs.Name && p["resourceType"] == resourceType && p["resourceName"] == resourceName {
 			if namespace != "" && p["namespace"] != namespace {
 				continue
 			}
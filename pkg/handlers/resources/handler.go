@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/alertmanager"
 	"github.com/pixelvide/kube-sentinel/pkg/cluster"
 	"github.com/pixelvide/kube-sentinel/pkg/common"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
@@ -108,6 +109,13 @@ func RegisterRoutes(group *gin.RouterGroup) {
 		} else {
 			registerNamespaceScopeRoutes(g, handler)
 		}
+		if watcher, ok := handler.(watchHandler); ok {
+			if handler.IsClusterScoped() {
+				g.GET("/_all/watch", watcher.Watch)
+			} else {
+				g.GET("/:namespace/watch", watcher.Watch)
+			}
+		}
 
 		if handler.Searchable() {
 			RegisterSearchFunc(name, handler.Search)
@@ -127,6 +135,36 @@ func RegisterRoutes(group *gin.RouterGroup) {
 		}
 	}
 
+	// Register alerts route for resource types that alerts can be matched to
+	for _, resourceType := range alertmanager.SupportedResources() {
+		if handler, exists := handlers[resourceType]; exists && !handler.IsClusterScoped() {
+			g := group.Group("/" + resourceType)
+			g.GET("/:namespace/:name/alerts", func(c *gin.Context) {
+				c.Set("resource", resourceType)
+				GetResourceAlerts(c)
+			})
+		}
+	}
+
+	// Register SLOs route for the resource types SLOs can target
+	for resourceType := range sloTargetKinds {
+		g := group.Group("/" + resourceType)
+		g.GET("/:namespace/:name/slos", func(c *gin.Context) {
+			c.Set("resource", resourceType)
+			GetResourceSLOs(c)
+		})
+	}
+
+	for _, resourceType := range rolloutResources {
+		g := group.Group("/" + resourceType)
+		if handler, ok := handlers[resourceType].(rolloutHandler); ok {
+			registerRolloutRoutes(g, handler)
+		}
+		if handler, ok := handlers[resourceType].(Restartable); ok {
+			g.PUT("/:namespace/:name/restart", restartRoute(handler))
+		}
+	}
+
 	crHandler := NewCRHandler()
 	otherGroup := group.Group("/:crd")
 	{
@@ -134,13 +172,26 @@ func RegisterRoutes(group *gin.RouterGroup) {
 		otherGroup.GET("/_all", crHandler.List)
 		otherGroup.GET("/_all/:name", crHandler.Get)
 		otherGroup.GET("/_all/:name/describe", crHandler.Describe)
+		otherGroup.GET("/_all/:name/history", crHandler.ListHistory)
+		otherGroup.GET("/_all/:name/analysis", crHandler.GetAnalysis)
+		otherGroup.GET("/_all/:name/related", crHandler.GetRelatedResources)
+		otherGroup.GET("/_all/watch", crHandler.Watch)
+		otherGroup.POST("/_all", crHandler.Create)
 		otherGroup.PUT("/_all/:name", crHandler.Update)
+		otherGroup.PATCH("/_all/:name", crHandler.Patch)
 		otherGroup.DELETE("/_all/:name", crHandler.Delete)
 
 		otherGroup.GET("/:namespace", crHandler.List)
+		otherGroup.GET("/:namespace/watch", crHandler.Watch)
 		otherGroup.GET("/:namespace/:name", crHandler.Get)
 		otherGroup.GET("/:namespace/:name/describe", crHandler.Describe)
+		otherGroup.GET("/:namespace/:name/history", crHandler.ListHistory)
+		otherGroup.GET("/:namespace/:name/analysis", crHandler.GetAnalysis)
+		otherGroup.GET("/:namespace/:name/related", crHandler.GetRelatedResources)
+		otherGroup.POST("/:namespace", crHandler.Create)
 		otherGroup.PUT("/:namespace/:name", crHandler.Update)
+		otherGroup.PATCH("/:namespace/:name", crHandler.Patch)
+		otherGroup.PUT("/:namespace/:name/restart", crHandler.Restart)
 		otherGroup.DELETE("/:namespace/:name", crHandler.Delete)
 	}
 
@@ -0,0 +1,93 @@
+package resources
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// maxPageSize is the largest page a list query returns.
+const maxPageSize = 1000
+
+// parseListQuery returns the query of ?pageSize=, ?continue=, ?sortBy=,
+// ?sortOrder= and ?q=, or nil when the request only uses the API server
+// ?limit= and ?continue=. With a query, continue is the token of the next
+// page returned in metadata.continue.
+func parseListQuery(c *gin.Context) (*kube.ListQuery, error) {
+	pageSize, sortBy, sortOrder, filter := c.Query("pageSize"), c.Query("sortBy"), c.Query("sortOrder"), c.Query("q")
+	if pageSize == "" && sortBy == "" && sortOrder == "" && filter == "" {
+		return nil, nil
+	}
+
+	query := &kube.ListQuery{
+		Filter:    filter,
+		SortBy:    sortBy,
+		PageToken: c.Query("continue"),
+	}
+	switch sortOrder {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return nil, fmt.Errorf("sortOrder must be asc or desc")
+	}
+	if pageSize != "" {
+		size, err := strconv.Atoi(pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pageSize parameter")
+		}
+		query.PageSize = min(size, maxPageSize)
+	}
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+// summaryList is a list of object summaries.
+type summaryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []kube.ObjectSummary `json:"items"`
+}
+
+// writeSummaryList writes the summaries of the objects of list, for
+// ?view=summary.
+func writeSummaryList(c *gin.Context, list runtime.Object) {
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to extract items from list"})
+		return
+	}
+	listMeta, err := meta.ListAccessor(list)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := summaryList{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "List"},
+		ListMeta: metav1.ListMeta{
+			ResourceVersion:    listMeta.GetResourceVersion(),
+			Continue:           listMeta.GetContinue(),
+			RemainingItemCount: listMeta.GetRemainingItemCount(),
+		},
+		Items: make([]kube.ObjectSummary, 0, len(items)),
+	}
+	for _, item := range items {
+		if item.GetObjectKind().GroupVersionKind().Empty() {
+			if gvk, err := apiutil.GVKForObject(item, kube.GetScheme()); err == nil {
+				item.GetObjectKind().SetGroupVersionKind(gvk)
+			}
+		}
+		result.Items = append(result.Items, kube.Summarize(item))
+	}
+	c.JSON(http.StatusOK, result)
+}
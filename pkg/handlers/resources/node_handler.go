@@ -249,6 +249,10 @@ func (h *NodeHandler) UntaintNode(c *gin.Context) {
 }
 
 func (h *NodeHandler) List(c *gin.Context) {
+	if c.Query("view") == "table" {
+		h.ListTable(c)
+		return
+	}
 	cs := c.MustGet("cluster").(*cluster.ClientSet)
 	var nodeMetrics metricsv1.NodeMetricsList
 
@@ -133,10 +133,18 @@ func (h *PodHandler) ListMetrics(c *gin.Context) (map[string]metricsv1.PodMetric
 }
 
 func (h *PodHandler) List(c *gin.Context) {
+	if c.Query("view") == "table" {
+		h.ListTable(c)
+		return
+	}
 	objlist, err := h.list(c)
 	if err != nil {
 		return
 	}
+	if c.Query("view") == "summary" {
+		writeSummaryList(c, objlist)
+		return
+	}
 	reduce := c.Query("reduce") == "true"
 	metricsMap, err := h.ListMetrics(c)
 	if err != nil {
@@ -250,10 +258,8 @@ func parseKubeSemver(version string) (semver.Version, error) {
 	return semver.Parse(trimmed)
 }
 
-// registerCustomRoutes adds pod-specific extra routes (SSE watch)
+// registerCustomRoutes adds pod-specific extra routes
 func (h *PodHandler) registerCustomRoutes(group *gin.RouterGroup) {
-	// watch pods in namespace (or _all)
-	group.GET("/:namespace/watch", h.Watch)
 	group.PATCH("/:namespace/:name/resize", h.Resize)
 	filesGroup := group.Group("/:namespace/:name/files")
 	filesGroup.Use(func(c *gin.Context) {
@@ -491,6 +497,12 @@ func (h *PodHandler) UploadFile(c *gin.Context) {
 }
 
 func writeSSE(c *gin.Context, event string, payload any) error {
+	return writeSSEWithID(c, "", event, payload)
+}
+
+// writeSSEWithID writes an event with an id, which EventSource sends back in
+// the Last-Event-ID header when it reconnects.
+func writeSSEWithID(c *gin.Context, id, event string, payload any) error {
 	c.Writer.Header().Set("Content-Type", "text/event-stream")
 	c.Writer.Header().Set("Cache-Control", "no-cache, no-transform")
 	c.Writer.Header().Set("Connection", "keep-alive")
@@ -507,6 +519,11 @@ func writeSSE(c *gin.Context, event string, payload any) error {
 	if err != nil {
 		return err
 	}
+	if id != "" {
+		if _, err := fmt.Fprintf(c.Writer, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
 	if _, err := fmt.Fprintf(c.Writer, "event: %s\n", event); err != nil {
 		return err
 	}
@@ -10,12 +10,21 @@ import (
 	"github.com/pixelvide/kube-sentinel/pkg/cluster"
 	"github.com/pixelvide/kube-sentinel/pkg/common"
 	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
 	appsv1 "k8s.io/api/apps/v1"
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/networking/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
@@ -398,17 +407,134 @@ func discoverOwners(ctx context.Context, cs *cluster.ClientSet, resource client.
 		}
 
 		if !isDup {
-			result = append(result, common.RelatedResource{
-				Type:       strings.ToLower(owner.Kind) + "s",
+			resourceType, namespaced := ownerResourceType(cs, owner)
+			related := common.RelatedResource{
+				Type:       resourceType,
 				Name:       owner.Name,
-				Namespace:  resource.GetNamespace(),
 				APIVersion: owner.APIVersion,
+			}
+			if namespaced {
+				related.Namespace = resource.GetNamespace()
+			}
+			result = append(result, related)
+		}
+	}
+	return result
+}
+
+// ownerResourceType returns the resource type of an owner, which is the CRD
+// name for custom resources, and whether it is namespaced.
+func ownerResourceType(cs *cluster.ClientSet, owner metav1.OwnerReference) (string, bool) {
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err == nil {
+		mapping, err := cs.K8sClient.RESTMapper().RESTMapping(gv.WithKind(owner.Kind).GroupKind(), gv.Version)
+		if err == nil {
+			return kube.ResourceName(mapping.Resource), mapping.Scope.Name() == meta.RESTScopeNameNamespace
+		}
+	}
+	return strings.ToLower(owner.Kind) + "s", true
+}
+
+// dependentKinds are the built-in kinds searched for objects owned by a
+// custom resource. Secrets are left out: they are not cached, so every
+// request would read all Secrets of the namespace from the API server.
+var dependentKinds = []struct {
+	resource string
+	newList  func() client.ObjectList
+}{
+	{"deployments", func() client.ObjectList { return &appsv1.DeploymentList{} }},
+	{"statefulsets", func() client.ObjectList { return &appsv1.StatefulSetList{} }},
+	{"daemonsets", func() client.ObjectList { return &appsv1.DaemonSetList{} }},
+	{"replicasets", func() client.ObjectList { return &appsv1.ReplicaSetList{} }},
+	{"jobs", func() client.ObjectList { return &batchv1.JobList{} }},
+	{"cronjobs", func() client.ObjectList { return &batchv1.CronJobList{} }},
+	{"pods", func() client.ObjectList { return &corev1.PodList{} }},
+	{"services", func() client.ObjectList { return &corev1.ServiceList{} }},
+	{"configmaps", func() client.ObjectList { return &corev1.ConfigMapList{} }},
+	{"persistentvolumeclaims", func() client.ObjectList { return &corev1.PersistentVolumeClaimList{} }},
+	{"ingresses", func() client.ObjectList { return &v1.IngressList{} }},
+}
+
+// discoverDependents returns the objects with an owner reference to owner:
+// objects of common built-in kinds, and custom resources of the API group of
+// owner or its subgroups, such as the Orders of acme.cert-manager.io owned by
+// a cert-manager.io CertificateRequest. Kinds the client cannot list are
+// skipped, as are objects the user may not get, which matters for
+// cluster-scoped owners whose dependents are searched in all namespaces.
+func discoverDependents(ctx context.Context, cs *cluster.ClientSet, user model.User, owner client.Object, group string) []common.RelatedResource {
+	var result []common.RelatedResource
+	collect := func(resourceType string, list client.ObjectList) {
+		_ = meta.EachListItem(list, func(item runtime.Object) error {
+			obj, ok := item.(client.Object)
+			if !ok || !ownedBy(obj, owner) {
+				return nil
+			}
+			namespace := obj.GetNamespace()
+			if namespace == "" {
+				// Cluster-scoped objects are checked like their routes
+				namespace = "_all"
+			}
+			if !rbac.CanAccess(user, resourceType, string(common.VerbGet), cs.Name, namespace) {
+				return nil
+			}
+			result = append(result, common.RelatedResource{
+				Type:       resourceType,
+				Name:       obj.GetName(),
+				Namespace:  obj.GetNamespace(),
+				APIVersion: obj.GetObjectKind().GroupVersionKind().GroupVersion().String(),
 			})
+			return nil
+		})
+	}
+
+	namespace := owner.GetNamespace()
+	for _, kind := range dependentKinds {
+		list := kind.newList()
+		if err := cs.K8sClient.List(ctx, list, client.InNamespace(namespace)); err != nil {
+			klog.Warningf("Failed to list %s for owned resources: %v", kind.resource, err)
+			continue
 		}
+		collect(kind.resource, list)
+	}
+
+	var crds apiextensionsv1.CustomResourceDefinitionList
+	if err := cs.K8sClient.List(ctx, &crds); err != nil {
+		klog.Warningf("Failed to list custom resource definitions for owned resources: %v", err)
+		return result
+	}
+	for _, crd := range crds.Items {
+		if crd.Spec.Group != group && !strings.HasSuffix(crd.Spec.Group, "."+group) && !strings.HasSuffix(group, "."+crd.Spec.Group) {
+			continue
+		}
+		list := &unstructured.UnstructuredList{}
+		for _, version := range crd.Spec.Versions {
+			if version.Served {
+				list.SetGroupVersionKind(schema.GroupVersionKind{Group: crd.Spec.Group, Version: version.Name, Kind: crd.Spec.Names.ListKind})
+				break
+			}
+		}
+		var opts []client.ListOption
+		if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
+			opts = append(opts, client.InNamespace(namespace))
+		}
+		if err := cs.K8sClient.List(ctx, list, opts...); err != nil {
+			klog.Warningf("Failed to list %s for owned resources: %v", crd.Name, err)
+			continue
+		}
+		collect(crd.Name, list)
 	}
 	return result
 }
 
+func ownedBy(obj, owner client.Object) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
 func getHTTPRouteRelatedResouces(res *gatewayapiv1.HTTPRoute, namespace string) []common.RelatedResource {
 	var result []common.RelatedResource
 	for _, parentRef := range res.Spec.ParentRefs {
@@ -0,0 +1,99 @@
+package resources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func newCR(group, kind, name string, uid types.UID, owner *unstructured.Unstructured) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{Group: group, Version: "v1", Kind: kind})
+	obj.SetNamespace("shop")
+	obj.SetName(name)
+	obj.SetUID(uid)
+	if owner != nil {
+		obj.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: owner.GetAPIVersion(), Kind: owner.GetKind(), Name: owner.GetName(), UID: owner.GetUID()}})
+	}
+	return obj
+}
+
+func newCRD(group, kind, plural string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: plural + "." + group},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group:    group,
+			Scope:    apiextensionsv1.NamespaceScoped,
+			Names:    apiextensionsv1.CustomResourceDefinitionNames{Kind: kind, ListKind: kind + "List", Plural: plural},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{Name: "v1", Served: true}},
+		},
+	}
+}
+
+func TestDiscoverDependents(t *testing.T) {
+	request := newCR("cert-manager.io", "CertificateRequest", "shop-tls-1", "request", nil)
+	order := newCR("acme.cert-manager.io", "Order", "shop-tls-1-order", "order", request)
+	other := newCR("acme.cert-manager.io", "Order", "web-tls-1-order", "other", nil)
+	ownerRefs := []metav1.OwnerReference{{APIVersion: "cert-manager.io/v1", Kind: "CertificateRequest", Name: "shop-tls-1", UID: "request"}}
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "shop-tls-ca", OwnerReferences: ownerRefs}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "shop-tls-solver", OwnerReferences: ownerRefs}}
+
+	newBuilder := func() *fake.ClientBuilder {
+		return fake.NewClientBuilder().WithScheme(kube.GetScheme()).WithObjects(
+			newCRD("cert-manager.io", "CertificateRequest", "certificaterequests"),
+			newCRD("acme.cert-manager.io", "Order", "orders"),
+			newCRD("argoproj.io", "Application", "applications"),
+			request, order, other, configMap, pod,
+		)
+	}
+	cs := &cluster.ClientSet{Name: "prod", K8sClient: &kube.K8sClient{Client: newBuilder().Build()}}
+	user := model.User{Roles: []common.Role{{
+		Name: "all", Clusters: []string{"*"}, Namespaces: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"get"},
+	}}}
+
+	related := discoverDependents(context.Background(), cs, user, request, "cert-manager.io")
+	assert.ElementsMatch(t, []common.RelatedResource{
+		{Type: "configmaps", Name: "shop-tls-ca", Namespace: "shop"},
+		{Type: "pods", Name: "shop-tls-solver", Namespace: "shop"},
+		{Type: "orders.acme.cert-manager.io", Name: "shop-tls-1-order", Namespace: "shop", APIVersion: "acme.cert-manager.io/v1"},
+	}, related)
+
+	// Kinds the client may not list are skipped
+	forbidden := newBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if _, ok := list.(*corev1.PodList); ok {
+				return apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", nil)
+			}
+			return c.List(ctx, list, opts...)
+		},
+	}).Build()
+	cs = &cluster.ClientSet{Name: "prod", K8sClient: &kube.K8sClient{Client: forbidden}}
+	related = discoverDependents(context.Background(), cs, user, request, "cert-manager.io")
+	assert.ElementsMatch(t, []common.RelatedResource{
+		{Type: "configmaps", Name: "shop-tls-ca", Namespace: "shop"},
+		{Type: "orders.acme.cert-manager.io", Name: "shop-tls-1-order", Namespace: "shop", APIVersion: "acme.cert-manager.io/v1"},
+	}, related)
+
+	// Objects the user may not get are left out
+	podsOnly := model.User{Roles: []common.Role{{
+		Name: "pods", Clusters: []string{"*"}, Namespaces: []string{"shop"}, Resources: []string{"pods"}, Verbs: []string{"get"},
+	}}}
+	cs = &cluster.ClientSet{Name: "prod", K8sClient: &kube.K8sClient{Client: newBuilder().Build()}}
+	related = discoverDependents(context.Background(), cs, podsOnly, request, "cert-manager.io")
+	assert.Equal(t, []common.RelatedResource{{Type: "pods", Name: "shop-tls-solver", Namespace: "shop"}}, related)
+}
@@ -0,0 +1,80 @@
+package resources
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Restart triggers a rolling restart of a workload and records it in its
+// history.
+func (h *GenericResourceHandler[T, V]) Restart(c *gin.Context, namespace, name string) error {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	obj := reflect.New(h.objectType).Interface().(T)
+	if err := cs.K8sClient.Get(c.Request.Context(), types.NamespacedName{Namespace: namespace, Name: name}, obj); err != nil {
+		return err
+	}
+	prev := obj.DeepCopyObject().(T)
+	err := kube.Restart(c.Request.Context(), cs.K8sClient, obj)
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	h.recordHistory(c, "restart", prev, obj, err == nil, errMsg)
+	return err
+}
+
+// restartRoute serves the restart of a Restartable resource.
+func restartRoute(handler Restartable) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := handler.Restart(c, c.Param("namespace"), c.Param("name")); err != nil {
+			if errors.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "restart triggered"})
+	}
+}
+
+// Restart triggers a rolling restart of a custom resource with a pod template
+// at spec.template, such as an Argo Rollout.
+func (h *CRHandler) Restart(c *gin.Context) {
+	crdName := c.Param("crd")
+	obj, err := GetResource(c, crdName, c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	cr, ok := obj.(*unstructured.Unstructured)
+	if !ok || !kube.Restartable(cr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resource has no pod template at spec.template to restart"})
+		return
+	}
+
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	prev := cr.DeepCopy()
+	err = kube.Restart(c.Request.Context(), cs.K8sClient, cr)
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	RecordAudit(c, "restart", crdName, prev, cr, err == nil, errMsg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "restart triggered"})
+}
@@ -0,0 +1,232 @@
+package resources
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// rolloutResources are the resource types with a rollout history.
+var rolloutResources = []string{"deployments", "statefulsets", "daemonsets"}
+
+type rolloutHandler interface {
+	ListRevisions(c *gin.Context)
+	GetRevisionDiff(c *gin.Context)
+	Rollback(c *gin.Context)
+	PauseRollout(c *gin.Context)
+	ResumeRollout(c *gin.Context)
+}
+
+// registerRolloutRoutes adds the rollout routes. Reads need the get verb and
+// changes the update verb on the workload, checked by the RBAC middleware.
+func registerRolloutRoutes(group *gin.RouterGroup, handler rolloutHandler) {
+	group.GET("/:namespace/:name/revisions", handler.ListRevisions)
+	group.GET("/:namespace/:name/revisions/:revision/diff", handler.GetRevisionDiff)
+	group.PUT("/:namespace/:name/rollback", handler.Rollback)
+	group.PUT("/:namespace/:name/pause", handler.PauseRollout)
+	group.PUT("/:namespace/:name/resume", handler.ResumeRollout)
+}
+
+// getWorkload loads the workload of the request, writing the error response
+// when it fails.
+func (h *GenericResourceHandler[T, V]) getWorkload(c *gin.Context) (T, bool) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	obj := reflect.New(h.objectType).Interface().(T)
+	key := types.NamespacedName{Namespace: c.Param("namespace"), Name: c.Param("name")}
+	if err := cs.K8sClient.Get(c.Request.Context(), key, obj); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return obj, false
+	}
+	return obj, true
+}
+
+func (h *GenericResourceHandler[T, V]) listRevisions(c *gin.Context, obj T) ([]kube.Revision, bool) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	revisions, err := kube.ListRevisions(c.Request.Context(), cs.K8sClient, obj)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return revisions, true
+}
+
+// ListRevisions returns the revisions of a workload from its ReplicaSets or
+// ControllerRevisions, newest first. Unlike the history, it includes changes
+// made outside of Kube Sentinel.
+func (h *GenericResourceHandler[T, V]) ListRevisions(c *gin.Context) {
+	obj, ok := h.getWorkload(c)
+	if !ok {
+		return
+	}
+	revisions, ok := h.listRevisions(c, obj)
+	if !ok {
+		return
+	}
+	if revisions == nil {
+		revisions = []kube.Revision{}
+	}
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetRevisionDiff returns the diff of the pod template of a revision against
+// the ?against= revision, by default the current one.
+func (h *GenericResourceHandler[T, V]) GetRevisionDiff(c *gin.Context) {
+	revision, err := strconv.ParseInt(c.Param("revision"), 10, 64)
+	if err != nil || revision <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return
+	}
+	obj, ok := h.getWorkload(c)
+	if !ok {
+		return
+	}
+	revisions, ok := h.listRevisions(c, obj)
+	if !ok {
+		return
+	}
+	to, err := kube.FindRevision(revisions, revision)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var from *kube.Revision
+	if v := c.Query("against"); v != "" {
+		against, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || against <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid against revision"})
+			return
+		}
+		if from, err = kube.FindRevision(revisions, against); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		for i := range revisions {
+			if revisions[i].Current {
+				from = &revisions[i]
+			}
+		}
+		if from == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "current revision not found"})
+			return
+		}
+	}
+
+	diff, err := kube.RevisionDiff(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":      from,
+		"to":        to,
+		"identical": diff == "",
+		"diff":      diff,
+	})
+}
+
+// Rollback rolls a workload back to a revision, the previous one when the
+// body has no revision, like kubectl rollout undo.
+func (h *GenericResourceHandler[T, V]) Rollback(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	var req struct {
+		Revision int64 `json:"revision"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	obj, ok := h.getWorkload(c)
+	if !ok {
+		return
+	}
+	revisions, ok := h.listRevisions(c, obj)
+	if !ok {
+		return
+	}
+	target, err := kube.FindRevision(revisions, req.Revision)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	prev := obj.DeepCopyObject().(T)
+	changed, err := kube.Rollback(c.Request.Context(), cs.K8sClient, obj, target)
+	if err != nil {
+		h.recordHistory(c, "rollback", prev, obj, false, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !changed {
+		c.JSON(http.StatusOK, gin.H{
+			"message":  fmt.Sprintf("skipped rollback, the current template already matches revision %d", target.Revision),
+			"revision": target.Revision,
+			"changed":  false,
+		})
+		return
+	}
+	h.recordHistory(c, "rollback", prev, obj, true, "")
+	c.JSON(http.StatusOK, gin.H{
+		"message":  fmt.Sprintf("rolled back to revision %d", target.Revision),
+		"revision": target.Revision,
+		"changed":  true,
+	})
+}
+
+// PauseRollout pauses the rollout of a Deployment.
+func (h *GenericResourceHandler[T, V]) PauseRollout(c *gin.Context) {
+	h.setPaused(c, true)
+}
+
+// ResumeRollout resumes a paused Deployment.
+func (h *GenericResourceHandler[T, V]) ResumeRollout(c *gin.Context) {
+	h.setPaused(c, false)
+}
+
+func (h *GenericResourceHandler[T, V]) setPaused(c *gin.Context, paused bool) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	obj, ok := h.getWorkload(c)
+	if !ok {
+		return
+	}
+	deployment, ok := any(obj).(*appsv1.Deployment)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only deployments can be paused and resumed"})
+		return
+	}
+	action := "resume"
+	if paused {
+		action = "pause"
+	}
+	if deployment.Spec.Paused == paused {
+		c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("deployment is already %sd", action), "paused": paused})
+		return
+	}
+
+	prev := obj.DeepCopyObject().(T)
+	patch := client.MergeFrom(deployment.DeepCopy())
+	deployment.Spec.Paused = paused
+	if err := cs.K8sClient.Patch(c.Request.Context(), deployment, patch, client.FieldOwner(kube.FieldManager)); err != nil {
+		h.recordHistory(c, action, prev, obj, false, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordHistory(c, action, prev, obj, true, "")
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("deployment %sd", action), "paused": paused})
+}
@@ -0,0 +1,40 @@
+package resources
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	"github.com/pixelvide/kube-sentinel/pkg/slo"
+)
+
+// sloTargetKinds maps the resource types SLOs can target to their kinds.
+var sloTargetKinds = map[string]string{
+	"services":     "Service",
+	"deployments":  "Deployment",
+	"statefulsets": "StatefulSet",
+	"daemonsets":   "DaemonSet",
+}
+
+// GetResourceSLOs returns the SLOs of a Service or workload with their
+// status. Access to the resource is checked by the RBAC middleware, access to
+// the SLOs needs the get verb on "slos".
+func GetResourceSLOs(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+	namespace, name := c.Param("namespace"), c.Param("name")
+	if !rbac.CanAccess(user, "slos", string(common.VerbGet), cs.Name, namespace) {
+		c.JSON(http.StatusForbidden, gin.H{"error": rbac.NoAccess(user.Key(), string(common.VerbGet), "slos", namespace, cs.Name)})
+		return
+	}
+
+	slos, err := model.ListTargetSLOs(cs.Name, namespace, sloTargetKinds[c.GetString("resource")], name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, slo.EvaluateForUser(c.Request.Context(), cs, user, slos))
+}
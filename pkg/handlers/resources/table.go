@@ -0,0 +1,126 @@
+package resources
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ListTable returns the list as a Table from the API server for
+// ?view=table, with the columns of kubectl get.
+func (h *GenericResourceHandler[T, V]) ListTable(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+
+	gvr, err := h.groupVersionResource(cs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	namespace := ""
+	if ns := c.Param("namespace"); !h.isClusterScoped && ns != "_all" && !strings.Contains(ns, ",") {
+		namespace = ns
+	}
+	table, err := cs.K8sClient.ListTable(c.Request.Context(), gvr, namespace, metav1.ListOptions{
+		LabelSelector: c.Query("labelSelector"),
+		FieldSelector: c.Query("fieldSelector"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var allow func(obj metav1.Object) bool
+	switch {
+	case h.name == "namespaces":
+		allow = func(obj metav1.Object) bool { return rbac.CanAccessNamespace(user, cs.Name, obj.GetName()) }
+	case !h.isClusterScoped:
+		allow = func(obj metav1.Object) bool { return rbac.CanAccessNamespace(user, cs.Name, obj.GetNamespace()) }
+	}
+	writeTable(c, table, allow)
+}
+
+// writeCRTable writes custom resources as a Table with the printer columns
+// of their CRD.
+func writeCRTable(c *gin.Context, crd *apiextensionsv1.CustomResourceDefinition, version string, items []unstructured.Unstructured) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+
+	table, err := kube.CustomResourceTable(crd, version, items)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var allow func(obj metav1.Object) bool
+	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
+		allow = func(obj metav1.Object) bool { return rbac.CanAccessNamespace(user, cs.Name, obj.GetNamespace()) }
+	}
+	writeTable(c, table, allow)
+}
+
+// writeTable writes the rows of table in the requested namespaces that allow
+// accepts, filtered, sorted and paged by the list query like other lists.
+// Status and restarts sort by the cells of the table.
+func writeTable(c *gin.Context, table *metav1.Table, allow func(obj metav1.Object) bool) {
+	query, err := parseListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query == nil {
+		query = &kube.ListQuery{}
+		_ = query.Validate()
+	}
+	objects, err := kube.TableRowObjects(table)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := query.SortByTable(table, objects); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var namespaces []string
+	if ns := c.Param("namespace"); strings.Contains(ns, ",") {
+		namespaces = strings.Split(ns, ",")
+	}
+	rows := make(map[runtime.Object]metav1.TableRow, len(objects))
+	visible := make([]runtime.Object, 0, len(objects))
+	for i, obj := range objects {
+		if len(namespaces) > 0 && !slices.Contains(namespaces, obj.GetNamespace()) {
+			continue
+		}
+		if allow != nil && !allow(obj) {
+			continue
+		}
+		rows[obj] = table.Rows[i]
+		visible = append(visible, obj)
+	}
+
+	page, next, remaining, err := query.Apply(visible)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	table.Rows = make([]metav1.TableRow, 0, len(page))
+	for _, obj := range page {
+		table.Rows = append(table.Rows, rows[obj])
+	}
+	table.Continue = next
+	table.RemainingItemCount = nil
+	if next != "" {
+		table.RemainingItemCount = &remaining
+	}
+	c.JSON(http.StatusOK, table)
+}
@@ -0,0 +1,168 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// watchBookmarkInterval is how often a watch sends a bookmark, which also
+// keeps the connection alive.
+const watchBookmarkInterval = 15 * time.Second
+
+type watchHandler interface {
+	Watch(c *gin.Context)
+}
+
+// Watch streams the added, modified and deleted objects of the resource over
+// SSE. See streamWatch.
+func (h *GenericResourceHandler[T, V]) Watch(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+
+	gvr, err := h.groupVersionResource(cs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var allow func(obj *unstructured.Unstructured) bool
+	switch {
+	case h.name == "namespaces":
+		allow = func(obj *unstructured.Unstructured) bool {
+			return rbac.CanAccessNamespace(user, cs.Name, obj.GetName())
+		}
+	case !h.isClusterScoped:
+		allow = func(obj *unstructured.Unstructured) bool {
+			return rbac.CanAccessNamespace(user, cs.Name, obj.GetNamespace())
+		}
+	}
+	streamWatch(c, gvr, h.isClusterScoped, allow)
+}
+
+// Watch streams the changes to the custom resources of a CRD over SSE.
+func (h *CRHandler) Watch(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+
+	crd, err := h.getCRDByName(c.Request.Context(), cs.K8sClient, c.Param("crd"))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	clusterScoped := crd.Spec.Scope != apiextensionsv1.NamespaceScoped
+	var allow func(obj *unstructured.Unstructured) bool
+	if !clusterScoped {
+		allow = func(obj *unstructured.Unstructured) bool {
+			return rbac.CanAccessNamespace(user, cs.Name, obj.GetNamespace())
+		}
+	}
+	streamWatch(c, h.getGVRFromCRD(crd), clusterScoped, allow)
+}
+
+// streamWatch streams the changes to gvr over SSE from the shared watch of
+// the cluster. The namespace parameter may list several namespaces, except
+// for kinds the cache policy keeps uncached, which are watched per request in
+// a single namespace. ?labelSelector= and ?fieldSelector= filter the objects.
+// The first events are a snapshot of the matching objects ending with a
+// bookmark. Each event has the resource version as its id, and a client
+// resumes with ?resourceVersion= or the Last-Event-ID header; when that
+// version is too old it receives a reset and a new snapshot.
+func streamWatch(c *gin.Context, gvr schema.GroupVersionResource, clusterScoped bool, allow func(obj *unstructured.Unstructured) bool) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+
+	filter := kube.WatchFilter{Allow: allow}
+	if namespace := c.Param("namespace"); !clusterScoped && namespace != "" && namespace != "_all" {
+		filter.Namespaces = strings.Split(namespace, ",")
+	}
+	// Watching all namespaces would hold every object of the kind in memory
+	if !clusterScoped && len(filter.Namespaces) != 1 && !cs.K8sClient.Cacheable(gvr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s are not cached and can only be watched in a single namespace", gvr.Resource)})
+		return
+	}
+	if v := c.Query("labelSelector"); v != "" {
+		selector, err := labels.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid labelSelector parameter: " + err.Error()})
+			return
+		}
+		filter.Labels = selector
+	}
+	if v := c.Query("fieldSelector"); v != "" {
+		selector, err := fields.ParseSelector(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fieldSelector parameter: " + err.Error()})
+			return
+		}
+		filter.Fields = selector
+	}
+	resourceVersion := c.Query("resourceVersion")
+	if resourceVersion == "" {
+		resourceVersion = c.GetHeader("Last-Event-ID")
+	}
+
+	sub, err := cs.K8sClient.Watches.Subscribe(c.Request.Context(), gvr, resourceVersion, filter)
+	if err != nil {
+		if errors.Is(err, kube.ErrWatchUnsupported) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s does not support watch", gvr.Resource)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer sub.Close()
+
+	for _, event := range sub.Initial {
+		if err := writeWatchEvent(c, event); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(watchBookmarkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-ticker.C:
+			if err := writeWatchEvent(c, kube.WatchEvent{Type: kube.WatchBookmark, ResourceVersion: sub.ResourceVersion()}); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events:
+			if !ok {
+				_ = writeSSE(c, "error", gin.H{"error": "watch fell behind, reconnect to resume"})
+				return
+			}
+			if err := writeWatchEvent(c, event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeWatchEvent(c *gin.Context, event kube.WatchEvent) error {
+	switch event.Type {
+	case kube.WatchBookmark, kube.WatchReset:
+		return writeSSEWithID(c, event.ResourceVersion, event.Type, gin.H{"resourceVersion": event.ResourceVersion})
+	}
+	return writeSSEWithID(c, event.ResourceVersion, event.Type, event.Object)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/handlers/resources"
+	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultRestartResources are restarted when a bulk restart does not list
+// resources.
+var defaultRestartResources = []string{"deployments", "statefulsets", "daemonsets"}
+
+type BulkRestartRequest struct {
+	Namespace string `json:"namespace" binding:"required"`
+	// Selector is a label selector, e.g. "app=api" or "tier in (web,api)"
+	Selector string `json:"selector" binding:"required"`
+	// Resources are the resource types to restart, custom resources by their
+	// CRD name, e.g. rollouts.argoproj.io
+	Resources []string `json:"resources"`
+}
+
+// RestartResult is the outcome of restarting one workload, or of listing a
+// resource type when Name is empty.
+type RestartResult struct {
+	Resource  string `json:"resource"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BulkRestart triggers a rolling restart of every workload in a namespace
+// matching a label selector, e.g. after rotating a Secret they share. Only
+// Deployments, StatefulSets, DaemonSets and custom resources are restarted.
+// Each resource type needs the update verb and the result of each workload
+// is reported.
+func BulkRestart(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+
+	var req BulkRestartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	selector, err := labels.Parse(req.Selector)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid selector: " + err.Error()})
+		return
+	}
+	if selector.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "selector must not be empty"})
+		return
+	}
+	resourceTypes := req.Resources
+	if len(resourceTypes) == 0 {
+		resourceTypes = defaultRestartResources
+	}
+
+	ctx := c.Request.Context()
+	results := []RestartResult{}
+	failed := 0
+	for _, resourceType := range resourceTypes {
+		result := RestartResult{Resource: resourceType, Namespace: req.Namespace}
+		mapping, err := resolveFederatedResource(cs.K8sClient.RESTMapper(), resourceType)
+		if err == nil && mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			err = fmt.Errorf("%s is not namespaced", resourceType)
+		}
+		if err == nil && !kube.RestartableKind(mapping.GroupVersionKind) {
+			err = fmt.Errorf("%s cannot be restarted, only deployments, statefulsets, daemonsets and custom resources can", resourceType)
+		}
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			failed++
+			continue
+		}
+		resource := kube.ResourceName(mapping.Resource)
+		result.Resource = resource
+		if !rbac.CanAccess(user, resource, string(common.VerbUpdate), cs.Name, req.Namespace) {
+			result.Error = rbac.NoAccess(user.Key(), string(common.VerbUpdate), resource, req.Namespace, cs.Name)
+			results = append(results, result)
+			failed++
+			continue
+		}
+
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(mapping.GroupVersionKind.GroupVersion().WithKind(mapping.GroupVersionKind.Kind + "List"))
+		if err := cs.K8sClient.List(ctx, list, client.InNamespace(req.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			failed++
+			continue
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			result := RestartResult{Resource: resource, Name: obj.GetName(), Namespace: req.Namespace}
+			prev := obj.DeepCopy()
+			if err := kube.Restart(ctx, cs.K8sClient, obj); err != nil {
+				result.Error = err.Error()
+				failed++
+				resources.RecordAudit(c, "restart", resource, prev, obj, false, err.Error())
+			} else {
+				result.Success = true
+				resources.RecordAudit(c, "restart", resource, prev, obj, true, "")
+			}
+			results = append(results, result)
+		}
+	}
+
+	response := gin.H{
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+		"results":   results,
+	}
+	if failed > 0 {
+		response["error"] = fmt.Sprintf("%d of %d restarts failed", failed, len(results))
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+	response["message"] = fmt.Sprintf("%d workloads restarted", len(results))
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/handlers/resources"
+	"github.com/pixelvide/kube-sentinel/pkg/kube"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	"gorm.io/gorm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RestoreRequest selects the audit log entry whose previous state is
+// restored.
+type RestoreRequest struct {
+	AuditID uint `json:"auditId" binding:"required"`
+	// DryRun returns the changes the restore would make without making them
+	DryRun bool `json:"dryRun"`
+	// Force restores even when the object changed after the audited change
+	Force bool `json:"force"`
+}
+
+// RestoreResult describes a restore: the diff against the live object, and
+// warnings about later changes it would overwrite.
+type RestoreResult struct {
+	AuditID   uint   `json:"auditId"`
+	Action    string `json:"action"`
+	Resource  string `json:"resource"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	// Operation is "create", "update" or "unchanged"
+	Operation string             `json:"operation"`
+	Diff      string             `json:"diff"`
+	Changes   []kube.FieldChange `json:"changes"`
+	Warnings  []string           `json:"warnings,omitempty"`
+	// Conflicts are the fields changed after the audited change, which the
+	// restore reverts
+	Conflicts []kube.FieldChange         `json:"conflicts,omitempty"`
+	DryRun    bool                       `json:"dryRun"`
+	Object    *unstructured.Unstructured `json:"object,omitempty"`
+	Message   string                     `json:"message,omitempty"`
+	Error     string                     `json:"error,omitempty"`
+}
+
+// auditPayload is the payload of the audit log entries of resource changes.
+type auditPayload struct {
+	ClusterName  string `json:"clusterName"`
+	ResourceType string `json:"resourceType"`
+	ResourceName string `json:"resourceName"`
+	Namespace    string `json:"namespace"`
+	ResourceYAML string `json:"resourceYaml"`
+	PreviousYAML string `json:"previousYaml"`
+}
+
+// RestoreResource restores the state of an object before the change recorded
+// in an audit log entry: a deleted object is re-created, and an updated one
+// is reverted. Server-managed fields are stripped. The result has the diff
+// against the live object, and when the object changed after the audited
+// change, warnings and the later changes; such a restore needs force.
+func RestoreResource(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+
+	var req RestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var auditLog model.AuditLog
+	if err := model.DB.Where("app_id = ?", model.CurrentApp.ID).First(&auditLog, req.AuditID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "audit log not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var payload auditPayload
+	if err := json.Unmarshal([]byte(auditLog.Payload), &payload); err != nil || payload.ResourceType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the audit log is not a resource change"})
+		return
+	}
+	if payload.ClusterName != cs.Name {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("the audit log belongs to cluster %s", payload.ClusterName)})
+		return
+	}
+	if !auditLog.Success {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the audited change failed, there is nothing to undo"})
+		return
+	}
+	previous, err := kube.DecodeAuditObject(payload.PreviousYAML)
+	if err == nil && previous == nil {
+		err = fmt.Errorf("the audit log has no previous state to restore")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	recorded, err := kube.DecodeAuditObject(payload.ResourceYAML)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	gvk, namespaced, err := auditObjectKind(cs, payload.ResourceType, previous)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	previous.SetGroupVersionKind(gvk)
+	previous.SetName(payload.ResourceName)
+	previous.SetNamespace("")
+	rbacNamespace := "_all"
+	if namespaced {
+		previous.SetNamespace(payload.Namespace)
+		rbacNamespace = payload.Namespace
+	}
+
+	ctx := c.Request.Context()
+	var live *unstructured.Unstructured
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(gvk)
+	err = cs.K8sClient.Live.Get(ctx, client.ObjectKeyFromObject(previous), existing)
+	switch {
+	case err == nil:
+		live = existing
+	case !apierrors.IsNotFound(err):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get resource: " + err.Error()})
+		return
+	}
+
+	verb := common.VerbUpdate
+	if live == nil {
+		verb = common.VerbCreate
+	}
+	if !rbac.CanAccess(user, payload.ResourceType, string(verb), cs.Name, rbacNamespace) {
+		c.JSON(http.StatusForbidden, gin.H{"error": rbac.NoAccess(user.Key(), string(verb), payload.ResourceType, rbacNamespace, cs.Name)})
+		return
+	}
+
+	obj := kube.RestoreObject(previous, live)
+	result := &RestoreResult{
+		AuditID:   auditLog.ID,
+		Action:    auditLog.Action,
+		Resource:  payload.ResourceType,
+		Kind:      gvk.Kind,
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+		DryRun:    req.DryRun,
+	}
+	result.Warnings, result.Conflicts = kube.RestoreConflicts(recorded, live)
+	result.Warnings = append(result.Warnings, dropMissingOwners(ctx, cs, obj)...)
+
+	// The preview is computed by the server without persisting the restore
+	dryRun := obj.DeepCopy()
+	if err := writeRestore(ctx, cs, dryRun, live == nil, true); err != nil {
+		c.JSON(restoreErrorStatus(err), gin.H{"error": "failed to restore resource: " + err.Error(), "warnings": result.Warnings})
+		return
+	}
+	preview, err := kube.NewApplyPreview(live, dryRun, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	result.Operation, result.Diff, result.Changes, result.Object = preview.Operation, preview.Diff, preview.Changes, preview.Result
+
+	if req.DryRun {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	if len(result.Warnings) > 0 && !req.Force {
+		result.Error = "the object changed after the audited change, restore with force to overwrite it"
+		c.JSON(http.StatusConflict, result)
+		return
+	}
+
+	err = writeRestore(ctx, cs, obj, live == nil, false)
+	var prev client.Object
+	if live != nil {
+		prev = live
+	}
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	resources.RecordAudit(c, "restore", payload.ResourceType, prev, obj, err == nil, errMsg)
+	if err != nil {
+		c.JSON(restoreErrorStatus(err), gin.H{"error": "failed to restore resource: " + err.Error()})
+		return
+	}
+	result.Object = obj.DeepCopy()
+	kube.RedactSecret(result.Object)
+	result.Message = "Resource restored successfully"
+	c.JSON(http.StatusOK, result)
+}
+
+// auditObjectKind returns the kind of an audited object, from its YAML or,
+// for objects recorded without their type, from its resource type, and
+// whether it is namespaced.
+func auditObjectKind(cs *cluster.ClientSet, resourceType string, obj *unstructured.Unstructured) (schema.GroupVersionKind, bool, error) {
+	mapper := cs.K8sClient.RESTMapper()
+	gvk := obj.GroupVersionKind()
+	var mapping *meta.RESTMapping
+	var err error
+	if gvk.Kind != "" && gvk.Version != "" {
+		mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	} else {
+		mapping, err = resolveFederatedResource(mapper, resourceType)
+	}
+	if err != nil {
+		return schema.GroupVersionKind{}, false, err
+	}
+	return mapping.GroupVersionKind, mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
+
+// dropMissingOwners removes the owner references of obj to owners that no
+// longer exist, which would make the garbage collector delete the restored
+// object, and returns a warning for each.
+func dropMissingOwners(ctx context.Context, cs *cluster.ClientSet, obj *unstructured.Unstructured) []string {
+	var warnings []string
+	var kept []metav1.OwnerReference
+	for _, ref := range obj.GetOwnerReferences() {
+		owner := &unstructured.Unstructured{}
+		owner.SetAPIVersion(ref.APIVersion)
+		owner.SetKind(ref.Kind)
+		key := client.ObjectKey{Name: ref.Name}
+		gvk := owner.GroupVersionKind()
+		if mapping, err := cs.K8sClient.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err == nil && mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			key.Namespace = obj.GetNamespace()
+		}
+		err := cs.K8sClient.Get(ctx, key, owner)
+		if apierrors.IsNotFound(err) || (err == nil && owner.GetUID() != ref.UID) {
+			warnings = append(warnings, fmt.Sprintf("The owner %s %s no longer exists and its owner reference is removed.", ref.Kind, ref.Name))
+			continue
+		}
+		kept = append(kept, ref)
+	}
+	if len(warnings) > 0 {
+		obj.SetOwnerReferences(kept)
+	}
+	return warnings
+}
+
+// writeRestore creates obj when it does not exist, or replaces it.
+func writeRestore(ctx context.Context, cs *cluster.ClientSet, obj *unstructured.Unstructured, create, dryRun bool) error {
+	if create {
+		opts := []client.CreateOption{client.FieldOwner(kube.FieldManager)}
+		if dryRun {
+			opts = append(opts, client.DryRunAll)
+		}
+		return cs.K8sClient.Create(ctx, obj, opts...)
+	}
+	opts := []client.UpdateOption{client.FieldOwner(kube.FieldManager)}
+	if dryRun {
+		opts = append(opts, client.DryRunAll)
+	}
+	return cs.K8sClient.Update(ctx, obj, opts...)
+}
+
+func restoreErrorStatus(err error) int {
+	switch {
+	case apierrors.IsConflict(err), apierrors.IsAlreadyExists(err):
+		return http.StatusConflict
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return http.StatusUnprocessableEntity
+	case apierrors.IsForbidden(err):
+		return http.StatusForbidden
+	case apierrors.IsNotFound(err):
+		// e.g. the namespace of the object was deleted too
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	"github.com/pixelvide/kube-sentinel/pkg/slo"
+	"gorm.io/gorm"
+)
+
+// sloResource is the RBAC resource name of SLO definitions.
+const sloResource = "slos"
+
+type sloReq struct {
+	Name        string  `json:"name" binding:"required"`
+	Namespace   string  `json:"namespace" binding:"required"`
+	Description string  `json:"description"`
+	TargetKind  string  `json:"targetKind"`
+	TargetName  string  `json:"targetName"`
+	Type        string  `json:"type" binding:"required"`
+	GoodQuery   string  `json:"goodQuery"`
+	TotalQuery  string  `json:"totalQuery"`
+	Metric      string  `json:"metric"`
+	Threshold   float64 `json:"threshold"`
+	Objective   float64 `json:"objective" binding:"required"`
+	Window      string  `json:"window"`
+}
+
+func canAccessSLO(c *gin.Context, user model.User, clusterName, namespace string, verb common.Verb) bool {
+	if !rbac.CanAccess(user, sloResource, string(verb), clusterName, namespace) {
+		c.JSON(http.StatusForbidden, gin.H{"error": rbac.NoAccess(user.Key(), string(verb), sloResource, namespace, clusterName)})
+		return false
+	}
+	return true
+}
+
+// ListSLOs returns the SLOs of the cluster the user can get, optionally of
+// one ?namespace=. ?status=true adds the current status of each SLO.
+func ListSLOs(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+	namespace := c.Query("namespace")
+	if namespace != "" && !canAccessSLO(c, user, cs.Name, namespace, common.VerbList) {
+		return
+	}
+
+	slos, err := model.ListSLOs(cs.Name, namespace)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	visible := make([]model.SLO, 0, len(slos))
+	for _, s := range slos {
+		if rbac.CanAccess(user, sloResource, string(common.VerbList), cs.Name, s.Namespace) {
+			visible = append(visible, s)
+		}
+	}
+	if c.Query("status") == "true" {
+		c.JSON(http.StatusOK, slo.EvaluateForUser(c.Request.Context(), cs, user, visible))
+		return
+	}
+	c.JSON(http.StatusOK, visible)
+}
+
+// GetSLO returns an SLO with its current SLI, error budget and burn rates.
+func GetSLO(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+	s, ok := getSLO(c, cs)
+	if !ok || !canAccessSLO(c, user, cs.Name, s.Namespace, common.VerbGet) {
+		return
+	}
+	c.JSON(http.StatusOK, slo.EvaluateForUser(c.Request.Context(), cs, user, []model.SLO{*s})[0])
+}
+
+// CreateSLO creates an SLO in the cluster of the request.
+func CreateSLO(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+	s := model.SLO{Cluster: cs.Name, CreatedBy: user.Key()}
+	if !applySLOReq(c, &s) || !canAccessSLO(c, user, cs.Name, s.Namespace, common.VerbCreate) {
+		return
+	}
+	if err := model.AddSLO(&s); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create SLO: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, s)
+}
+
+// UpdateSLO replaces an SLO. Moving it to another namespace needs the update
+// verb in both namespaces.
+func UpdateSLO(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+	s, ok := getSLO(c, cs)
+	if !ok || !canAccessSLO(c, user, cs.Name, s.Namespace, common.VerbUpdate) {
+		return
+	}
+	if !applySLOReq(c, s) || !canAccessSLO(c, user, cs.Name, s.Namespace, common.VerbUpdate) {
+		return
+	}
+	if err := model.UpdateSLO(s); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update SLO: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+func DeleteSLO(c *gin.Context) {
+	cs := c.MustGet("cluster").(*cluster.ClientSet)
+	user := c.MustGet("user").(model.User)
+	s, ok := getSLO(c, cs)
+	if !ok || !canAccessSLO(c, user, cs.Name, s.Namespace, common.VerbDelete) {
+		return
+	}
+	if err := model.DeleteSLO(s.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete SLO: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "SLO deleted successfully"})
+}
+
+// getSLO loads the SLO of the :id parameter in the cluster of the request.
+func getSLO(c *gin.Context, cs *cluster.ClientSet) (*model.SLO, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid SLO id"})
+		return nil, false
+	}
+	s, err := model.GetSLOByID(uint(id))
+	if err != nil || s.Cluster != cs.Name {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "SLO not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return nil, false
+	}
+	return s, true
+}
+
+// applySLOReq validates the request and copies it into s.
+func applySLOReq(c *gin.Context, s *model.SLO) bool {
+	var req sloReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	s.Name = req.Name
+	s.Namespace = req.Namespace
+	s.Description = req.Description
+	s.TargetKind = req.TargetKind
+	s.TargetName = req.TargetName
+	s.Type = req.Type
+	s.GoodQuery = req.GoodQuery
+	s.TotalQuery = req.TotalQuery
+	s.Metric = req.Metric
+	s.Threshold = req.Threshold
+	s.Objective = req.Objective
+	s.Window = req.Window
+	if s.Window == "" {
+		s.Window = "30d"
+	}
+	if err := s.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := slo.ValidateQueries(*s); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
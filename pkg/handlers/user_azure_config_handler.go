@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	"gorm.io/gorm"
+)
+
+type UpdateUserAzureConfigReq struct {
+	LoginMode    string `json:"login_mode" binding:"required"`
+	TenantID     string `json:"tenant_id"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+type AzureDeviceLoginReq struct {
+	Cluster string `json:"cluster" binding:"required"`
+}
+
+func GetUserAzureConfig(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := user.(model.User)
+
+	config, err := model.GetUserAzureConfig(u.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusOK, gin.H{"login_mode": model.AzureLoginModeSPN})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+func UpdateUserAzureConfig(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := user.(model.User)
+
+	var req UpdateUserAzureConfigReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	switch req.LoginMode {
+	case model.AzureLoginModeSPN:
+		if req.TenantID == "" || req.ClientID == "" || req.ClientSecret == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tenant_id, client_id and client_secret are required for service principal login"})
+			return
+		}
+	case model.AzureLoginModeDeviceCode:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "login_mode must be one of: spn, devicecode"})
+		return
+	}
+
+	var config model.UserAzureConfig
+	err := model.DB.Where("user_id = ?", u.ID).First(&config).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		config = model.UserAzureConfig{UserID: u.ID}
+	}
+	config.LoginMode = req.LoginMode
+	config.TenantID = req.TenantID
+	config.ClientID = req.ClientID
+	config.ClientSecret = model.SecretString(req.ClientSecret)
+	if err := model.DB.Save(&config).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+func DeleteUserAzureConfig(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := user.(model.User)
+
+	if err := model.DB.Where("user_id = ?", u.ID).Delete(&model.UserAzureConfig{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Azure configuration deleted"})
+}
+
+// AzureDeviceLogin starts a kubelogin device code login for a cluster the user
+// can access and returns the sign-in instructions. The token is cached once
+// the user finishes; only one login per user and cluster runs at a time.
+func AzureDeviceLogin(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := user.(model.User)
+
+	var req AzureDeviceLoginReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !rbac.CanAccessCluster(u, req.Cluster) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("user %s does not have access to cluster %s", u.Key(), req.Cluster)})
+		return
+	}
+
+	message, err := cluster.AzureDeviceLogin(req.Cluster, &u)
+	if errors.Is(err, cluster.ErrAzureDeviceLoginPending) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/utils"
+	"gorm.io/gorm"
+	"k8s.io/klog/v2"
+)
+
+type UpdateUserGCPConfigReq struct {
+	ServiceAccountJSON string `json:"service_account_json" binding:"required"`
+}
+
+func GetUserGCPConfig(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := user.(model.User)
+
+	config, err := model.GetUserGCPConfig(u.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusOK, gin.H{"service_account_json": ""})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+func UpdateUserGCPConfig(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := user.(model.User)
+
+	var req UpdateUserGCPConfigReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !json.Valid([]byte(req.ServiceAccountJSON)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "service account key must be valid JSON"})
+		return
+	}
+
+	userConfig, err := model.GetUserConfig(u.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user configuration"})
+		return
+	}
+
+	var config model.UserGCPConfig
+	err = model.DB.Where("user_id = ?", u.ID).First(&config).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		config = model.UserGCPConfig{UserID: u.ID}
+	}
+	config.ServiceAccountJSON = model.SecretString(req.ServiceAccountJSON)
+	if err := model.DB.Save(&config).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := utils.WriteUserGCPCredentials(userConfig.StorageNamespace, req.ServiceAccountJSON); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write credentials file: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+func DeleteUserGCPConfig(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := user.(model.User)
+
+	if err := model.DB.Where("user_id = ?", u.ID).Delete(&model.UserGCPConfig{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if userConfig, err := model.GetUserConfig(u.ID); err == nil {
+		if err := utils.WriteUserGCPCredentials(userConfig.StorageNamespace, ""); err != nil {
+			klog.Warningf("Failed to clear GCP credentials file for user %d: %v", u.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "GCP configuration deleted"})
+}
+
+func RestoreGCPConfigs() {
+	var configs []model.UserGCPConfig
+	if err := model.DB.Find(&configs).Error; err != nil {
+		klog.Errorf("Failed to fetch user GCP configs for restoration: %v", err)
+		return
+	}
+
+	for _, config := range configs {
+		userConfig, err := model.GetUserConfig(config.UserID)
+		if err != nil {
+			klog.Errorf("Failed to get user config for user %d during GCP config restoration: %v", config.UserID, err)
+			continue
+		}
+		if err := utils.WriteUserGCPCredentials(userConfig.StorageNamespace, string(config.ServiceAccountJSON)); err != nil {
+			klog.Errorf("Failed to restore GCP credentials for user %d: %v", config.UserID, err)
+			continue
+		}
+		klog.Infof("Restored GCP credentials for user %d", config.UserID)
+	}
+}
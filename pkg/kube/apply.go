@@ -0,0 +1,127 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// FieldManager is the field manager of the changes made by Kube Sentinel.
+const FieldManager = "kube-sentinel"
+
+// ApplyOptions configures a server-side apply.
+type ApplyOptions struct {
+	// Force takes ownership of fields managed by other field managers.
+	Force bool
+	// DryRun computes the result on the server without persisting it.
+	DryRun bool
+}
+
+// Conflict is a field owned by another field manager that an apply would
+// change.
+type Conflict struct {
+	Manager string `json:"manager,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ApplyPreview is the result of a dry run: the changes between the live
+// object and the object the server would persist.
+type ApplyPreview struct {
+	// Operation is "create", "update" or "unchanged"
+	Operation string                     `json:"operation"`
+	Diff      string                     `json:"diff"`
+	Changes   []FieldChange              `json:"changes"`
+	Conflicts []Conflict                 `json:"conflicts,omitempty"`
+	Result    *unstructured.Unstructured `json:"result,omitempty"`
+}
+
+var conflictManagerRe = regexp.MustCompile(`conflict with "([^"]*)"`)
+
+// ServerSideApply applies obj with server-side apply as FieldManager. obj is
+// updated with the object returned by the server.
+func ServerSideApply(ctx context.Context, c client.Client, obj *unstructured.Unstructured, opts ApplyOptions) error {
+	// The server rejects apply requests that set managedFields
+	obj.SetManagedFields(nil)
+	applyOpts := []client.ApplyOption{client.FieldOwner(FieldManager)}
+	if opts.Force {
+		applyOpts = append(applyOpts, client.ForceOwnership)
+	}
+	if opts.DryRun {
+		applyOpts = append(applyOpts, client.DryRunAll)
+	}
+	return c.Apply(ctx, client.ApplyConfigurationFromUnstructured(obj), applyOpts...)
+}
+
+// PreviewApply dry runs the server-side apply of obj and compares the result
+// with live, which is nil when the object does not exist. Conflicts with
+// other field managers are reported, and unless opts.Force is set the diff is
+// computed as if the conflicting fields were forced.
+func PreviewApply(ctx context.Context, c client.Client, obj, live *unstructured.Unstructured, opts ApplyOptions) (*ApplyPreview, error) {
+	opts.DryRun = true
+	preview := &ApplyPreview{}
+	result := obj.DeepCopy()
+	err := ServerSideApply(ctx, c, result, opts)
+	if conflicts := ApplyConflicts(err); len(conflicts) > 0 && !opts.Force {
+		preview.Conflicts = conflicts
+		opts.Force = true
+		result = obj.DeepCopy()
+		err = ServerSideApply(ctx, c, result, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewApplyPreview(live, result, preview.Conflicts)
+}
+
+// NewApplyPreview compares live, nil for a new object, with the dry run
+// result. The values of Secrets are redacted in the preview.
+func NewApplyPreview(live, result *unstructured.Unstructured, conflicts []Conflict) (*ApplyPreview, error) {
+	preview := &ApplyPreview{Operation: "update", Conflicts: conflicts, Result: result.DeepCopy()}
+	preview.Result.SetManagedFields(nil)
+	RedactSecret(preview.Result)
+	from := live
+	if live == nil {
+		preview.Operation = "create"
+		from = &unstructured.Unstructured{Object: map[string]interface{}{}}
+	}
+	diff, err := DiffObjects(from, result, "live", "applied")
+	if err != nil {
+		return nil, err
+	}
+	preview.Diff = diff
+	preview.Changes = FieldChanges(live, result)
+	if live != nil && diff == "" {
+		preview.Operation = "unchanged"
+	}
+	return preview, nil
+}
+
+// ApplyConflicts returns the field manager conflicts of a server-side apply
+// error, or nil when err is not a conflict.
+func ApplyConflicts(err error) []Conflict {
+	if !apierrors.IsConflict(err) {
+		return nil
+	}
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) || status.Status().Details == nil {
+		return nil
+	}
+	var conflicts []Conflict
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		conflict := Conflict{Field: cause.Field, Message: cause.Message}
+		if m := conflictManagerRe.FindStringSubmatch(cause.Message); m != nil {
+			conflict.Manager = m[1]
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts
+}
@@ -0,0 +1,99 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestApplyConflicts(t *testing.T) {
+	err := apierrors.NewApplyConflict([]metav1.StatusCause{
+		{
+			Type:    metav1.CauseTypeFieldManagerConflict,
+			Field:   ".spec.replicas",
+			Message: `conflict with "kube-controller-manager" using apps/v1`,
+		},
+		{
+			Type:    metav1.CauseTypeFieldManagerConflict,
+			Field:   ".spec.template.spec.containers[name=\"app\"].image",
+			Message: `conflict with "argocd-controller" with subresource "scale" using apps/v1`,
+		},
+	}, "Apply failed with 2 conflicts")
+
+	conflicts := ApplyConflicts(fmt.Errorf("apply: %w", err))
+	require.Len(t, conflicts, 2)
+	assert.Equal(t, Conflict{Manager: "kube-controller-manager", Field: ".spec.replicas", Message: `conflict with "kube-controller-manager" using apps/v1`}, conflicts[0])
+	assert.Equal(t, "argocd-controller", conflicts[1].Manager)
+
+	assert.Nil(t, ApplyConflicts(nil))
+	assert.Nil(t, ApplyConflicts(apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "app")))
+	assert.Empty(t, ApplyConflicts(apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "app", fmt.Errorf("stale resource version"))))
+}
+
+func TestNewApplyPreview(t *testing.T) {
+	live := newDiffTestConfigMap("staging", "info")
+
+	preview, err := NewApplyPreview(live, newDiffTestConfigMap("staging", "info"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "unchanged", preview.Operation)
+	assert.Empty(t, preview.Diff)
+	assert.Empty(t, preview.Changes)
+
+	applied := newDiffTestConfigMap("staging", "debug")
+	applied.Object["data"].(map[string]interface{})["NEW"] = "1"
+	preview, err = NewApplyPreview(live, applied, []Conflict{{Manager: "helm", Field: ".data.LOG_LEVEL"}})
+	require.NoError(t, err)
+	assert.Equal(t, "update", preview.Operation)
+	assert.Contains(t, preview.Diff, "+  LOG_LEVEL: debug")
+	assert.Equal(t, []FieldChange{
+		{Path: "data.LOG_LEVEL", Op: FieldReplaced, From: "info", To: "debug"},
+		{Path: "data.NEW", Op: FieldAdded, To: "1"},
+	}, preview.Changes)
+	assert.Len(t, preview.Conflicts, 1)
+	assert.Nil(t, preview.Result.GetManagedFields())
+	assert.NotNil(t, applied.GetManagedFields(), "the dry run result should not be modified")
+
+	preview, err = NewApplyPreview(nil, applied, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "create", preview.Operation)
+	assert.Contains(t, preview.Diff, "+kind: ConfigMap")
+	assert.Contains(t, preview.Changes, FieldChange{Path: "kind", Op: FieldAdded, To: "ConfigMap"})
+}
+
+func TestFieldChangesRemoved(t *testing.T) {
+	live := newDiffTestConfigMap("staging", "info")
+	applied := newDiffTestConfigMap("staging", "info")
+	delete(applied.Object, "data")
+	assert.Equal(t, []FieldChange{
+		{Path: "data", Op: FieldRemoved, From: map[string]interface{}{"LOG_LEVEL": "info"}},
+	}, FieldChanges(live, applied))
+}
+
+func TestNewApplyPreviewRedactsSecrets(t *testing.T) {
+	secret := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"name":        "db",
+			"annotations": map[string]interface{}{common.KubectlAnnotation: `{"data":{"password":"c2VjcmV0"}}`},
+		},
+		"data":       map[string]interface{}{"password": "c2VjcmV0"},
+		"stringData": map[string]interface{}{"user": "admin"},
+	}}
+
+	preview, err := NewApplyPreview(nil, secret, nil)
+	require.NoError(t, err)
+	out, err := json.Marshal(preview)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "c2VjcmV0")
+	assert.NotContains(t, string(out), "admin")
+	assert.Equal(t, "c2VjcmV0", secret.Object["data"].(map[string]interface{})["password"], "the dry run result should not be modified")
+}
@@ -0,0 +1,108 @@
+package kube
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// applyOrder is the order kinds are applied in, so that objects are created
+// after the objects they depend on. Kinds not listed are applied last.
+var applyOrder = []string{
+	"Namespace",
+	"CustomResourceDefinition",
+	"PriorityClass",
+	"ResourceQuota",
+	"LimitRange",
+	"NetworkPolicy",
+	"PodDisruptionBudget",
+	"ServiceAccount",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"IngressClass",
+	"Ingress",
+	"APIService",
+	"MutatingWebhookConfiguration",
+	"ValidatingWebhookConfiguration",
+}
+
+// DecodeObjects decodes a multi-document YAML or JSON stream. Empty documents
+// are skipped and the items of List kinds are returned as separate objects.
+func DecodeObjects(data []byte) ([]*unstructured.Unstructured, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	var objects []*unstructured.Unstructured
+	for i := 1; ; i++ {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		jsonData, err := utilyaml.ToJSON(doc)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		if len(bytes.TrimSpace(jsonData)) == 0 || bytes.Equal(bytes.TrimSpace(jsonData), []byte("null")) {
+			continue
+		}
+		decoded, err := runtime.Decode(unstructured.UnstructuredJSONScheme, jsonData)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		switch obj := decoded.(type) {
+		case *unstructured.Unstructured:
+			objects = append(objects, obj)
+		case *unstructured.UnstructuredList:
+			for j := range obj.Items {
+				item := &obj.Items[j]
+				if item.GetKind() == "" || item.GetAPIVersion() == "" {
+					return nil, fmt.Errorf("document %d: item %d: apiVersion and kind are required", i, j+1)
+				}
+				objects = append(objects, item)
+			}
+		default:
+			return nil, fmt.Errorf("document %d: unexpected object %T", i, decoded)
+		}
+	}
+	return objects, nil
+}
+
+// SortForApply orders objects by applyOrder, keeping the order of the input
+// for objects of the same kind.
+func SortForApply(objects []*unstructured.Unstructured) {
+	rank := func(obj *unstructured.Unstructured) int {
+		for i, kind := range applyOrder {
+			if obj.GetKind() == kind {
+				return i
+			}
+		}
+		return len(applyOrder)
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return rank(objects[i]) < rank(objects[j])
+	})
+}
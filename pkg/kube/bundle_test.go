@@ -0,0 +1,90 @@
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const testBundle = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: shop
+spec:
+  replicas: 2
+---
+# comment only
+---
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: Service
+    metadata:
+      name: api
+      namespace: shop
+  - apiVersion: networking.k8s.io/v1
+    kind: Ingress
+    metadata:
+      name: api
+      namespace: shop
+---
+{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "shop"}}
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: w
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+`
+
+func TestDecodeObjects(t *testing.T) {
+	objects, err := DecodeObjects([]byte(testBundle))
+	require.NoError(t, err)
+	kinds := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		kinds = append(kinds, obj.GetKind())
+	}
+	assert.Equal(t, []string{"Deployment", "Service", "Ingress", "Namespace", "Widget", "CustomResourceDefinition"}, kinds)
+
+	replicas, _, _ := unstructured.NestedInt64(objects[0].Object, "spec", "replicas")
+	assert.Equal(t, int64(2), replicas, "numbers should decode as integers")
+
+	_, err = DecodeObjects([]byte("apiVersion: v1\nkind: ConfigMap\n---\nmetadata:\n  name: x\n"))
+	assert.ErrorContains(t, err, "document 2")
+
+	objects, err = DecodeObjects([]byte("\n---\n"))
+	require.NoError(t, err)
+	assert.Empty(t, objects)
+}
+
+func TestSortForApply(t *testing.T) {
+	objects, err := DecodeObjects([]byte(testBundle))
+	require.NoError(t, err)
+	SortForApply(objects)
+	kinds := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		kinds = append(kinds, obj.GetKind())
+	}
+	assert.Equal(t, []string{"Namespace", "CustomResourceDefinition", "Service", "Deployment", "Ingress", "Widget"}, kinds)
+}
+
+func TestResourceName(t *testing.T) {
+	assert.Equal(t, "ingresses", ResourceName(schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}))
+	assert.Equal(t, "networkpolicies", ResourceName(schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"}))
+	assert.Equal(t, "configmaps", ResourceName(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}))
+	assert.Equal(t, "crds", ResourceName(schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}))
+	assert.Equal(t, "widgets.example.com", ResourceName(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}))
+
+	assert.Equal(t, schema.GroupResource{Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions"}, ParseResourceName("crds"))
+	assert.Equal(t, schema.GroupResource{Group: "example.com", Resource: "widgets"}, ParseResourceName("widgets.example.com"))
+}
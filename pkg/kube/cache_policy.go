@@ -0,0 +1,379 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const cacheStatsInterval = time.Minute
+
+// TrimmedAnnotationsAnnotation lists the annotations that were dropped from a
+// cached object because their values exceeded the MaxAnnotationBytes of the
+// cache policy.
+const TrimmedAnnotationsAnnotation = "kube-sentinel.kubernetes.io/trimmed-annotations"
+
+var (
+	cacheObjectsDesc = prometheus.NewDesc(
+		"kube_sentinel_informer_cache_objects",
+		"Number of objects held in the informer caches of a cluster, by client type and kind.",
+		[]string{"cluster", "client", "kind"}, nil)
+	cacheBytesDesc = prometheus.NewDesc(
+		"kube_sentinel_informer_cache_bytes",
+		"Approximate JSON size of the objects held in the informer caches of a cluster, by client type and kind.",
+		[]string{"cluster", "client", "kind"}, nil)
+
+	cacheCollector = &cacheStatsCollector{stats: map[*cacheStats]struct{}{}}
+)
+
+func init() {
+	prometheus.MustRegister(cacheCollector)
+}
+
+// kindMatcher matches "Kind" in any group or "Kind.group" in a single group.
+type kindMatcher struct {
+	kind     string
+	group    string
+	anyGroup bool
+}
+
+func parseKindMatcher(s string) kindMatcher {
+	kind, group, found := strings.Cut(strings.TrimSpace(s), ".")
+	return kindMatcher{kind: kind, group: group, anyGroup: !found}
+}
+
+func (m kindMatcher) matches(gvk schema.GroupVersionKind) bool {
+	return strings.EqualFold(m.kind, gvk.Kind) && (m.anyGroup || m.group == gvk.Group)
+}
+
+// cachePolicy is the compiled form of common.CachePolicy.
+type cachePolicy struct {
+	cached             []kindMatcher
+	uncached           []kindMatcher
+	stripManagedFields bool
+	maxAnnotationBytes int
+}
+
+func newCachePolicy(p common.CachePolicy) *cachePolicy {
+	policy := &cachePolicy{
+		stripManagedFields: p.StripManagedFields,
+		maxAnnotationBytes: p.MaxAnnotationBytes,
+	}
+	for _, k := range p.Cached {
+		policy.cached = append(policy.cached, parseKindMatcher(k))
+	}
+	for _, k := range p.Uncached {
+		policy.uncached = append(policy.uncached, parseKindMatcher(k))
+	}
+	return policy
+}
+
+// cacheable reports whether objects of the kind may be served from the cache.
+func (p *cachePolicy) cacheable(gvk schema.GroupVersionKind) bool {
+	for _, m := range p.uncached {
+		if m.matches(gvk) {
+			return false
+		}
+	}
+	if len(p.cached) == 0 {
+		return true
+	}
+	for _, m := range p.cached {
+		if m.matches(gvk) {
+			return true
+		}
+	}
+	return false
+}
+
+// transform trims objects before they are stored in the cache. Dropped
+// annotations are listed in TrimmedAnnotationsAnnotation, so that
+// policyClient never hands a trimmed object to a read-modify-write path.
+func (p *cachePolicy) transform(obj interface{}) (interface{}, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return obj, nil
+	}
+	if p.stripManagedFields {
+		accessor.SetManagedFields(nil)
+	}
+	if p.maxAnnotationBytes > 0 {
+		if anno := accessor.GetAnnotations(); anno != nil {
+			var trimmed []string
+			for k, v := range anno {
+				if len(v) > p.maxAnnotationBytes {
+					trimmed = append(trimmed, k)
+					delete(anno, k)
+				}
+			}
+			if len(trimmed) > 0 {
+				slices.Sort(trimmed)
+				anno[TrimmedAnnotationsAnnotation] = strings.Join(trimmed, ",")
+			}
+			accessor.SetAnnotations(anno)
+		}
+	}
+	return obj, nil
+}
+
+func (p *cachePolicy) cacheTransform() toolscache.TransformFunc {
+	if !p.stripManagedFields && p.maxAnnotationBytes <= 0 {
+		return nil
+	}
+	return p.transform
+}
+
+// trimmedAnnotations returns the annotations dropped from a cached object.
+func trimmedAnnotations(obj client.Object) []string {
+	value, ok := obj.GetAnnotations()[TrimmedAnnotationsAnnotation]
+	if !ok {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// policyClient serves reads of cacheable kinds from the informer cache and all
+// other reads live from the API server, so informers are only ever started
+// for kinds the policy allows. Writes always go through the embedded client.
+// Objects with trimmed annotations are read live by Get, and get their
+// annotations back before an Update, so they are only seen in lists.
+type policyClient struct {
+	client.Client
+	live   client.Reader
+	policy *cachePolicy
+	stats  *cacheStats
+}
+
+func (c *policyClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if c.useCache(obj, false) {
+		if err := c.Client.Get(ctx, key, obj, opts...); err != nil || trimmedAnnotations(obj) == nil {
+			return err
+		}
+		// Decoding merges maps, so the trimmed copy is cleared first
+		if v := reflect.ValueOf(obj); v.Kind() == reflect.Pointer {
+			v.Elem().Set(reflect.Zero(v.Elem().Type()))
+		}
+	}
+	return c.live.Get(ctx, key, obj, opts...)
+}
+
+func (c *policyClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if trimmed := trimmedAnnotations(obj); trimmed != nil {
+		if err := c.restoreAnnotations(ctx, obj, trimmed); err != nil {
+			return err
+		}
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// restoreAnnotations puts the trimmed annotations of an object read from a
+// list back from the live object, unless they were set again since.
+func (c *policyClient) restoreAnnotations(ctx context.Context, obj client.Object, trimmed []string) error {
+	gvk, err := apiutil.GVKForObject(obj, runtimeScheme)
+	if err != nil {
+		return err
+	}
+	newObj, err := runtimeScheme.New(gvk)
+	if err != nil {
+		return err
+	}
+	live, ok := newObj.(client.Object)
+	if !ok {
+		return fmt.Errorf("%s is not a client object", gvk.Kind)
+	}
+	if err := c.live.Get(ctx, client.ObjectKeyFromObject(obj), live); err != nil {
+		return err
+	}
+	anno := obj.GetAnnotations()
+	delete(anno, TrimmedAnnotationsAnnotation)
+	for _, k := range trimmed {
+		if _, ok := anno[k]; ok {
+			continue
+		}
+		if v, ok := live.GetAnnotations()[k]; ok {
+			anno[k] = v
+		}
+	}
+	obj.SetAnnotations(anno)
+	return nil
+}
+
+func (c *policyClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if c.useCache(list, true) {
+		return c.Client.List(ctx, list, opts...)
+	}
+	return c.live.List(ctx, list, opts...)
+}
+
+func (c *policyClient) useCache(obj runtime.Object, isList bool) bool {
+	if _, ok := obj.(runtime.Unstructured); ok {
+		// Unstructured objects are not cached by the manager client anyway
+		return true
+	}
+	gvk, err := apiutil.GVKForObject(obj, runtimeScheme)
+	if err != nil {
+		return true
+	}
+	if isList {
+		gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+	}
+	if !c.policy.cacheable(gvk) {
+		return false
+	}
+	c.stats.track(gvk)
+	return true
+}
+
+// Client types of the cache metrics. The caches of the user clients of a
+// cluster are summed so that the number of series does not grow with users.
+const (
+	cacheClientShared = "shared"
+	cacheClientUser   = "user"
+)
+
+// cacheStats periodically measures the size of the informer cache per kind.
+type cacheStats struct {
+	cluster string
+	client  string
+	cache   cache.Cache
+
+	mu      sync.Mutex
+	kinds   map[schema.GroupVersionKind]struct{}
+	objects map[string]int
+	bytes   map[string]int
+}
+
+func newCacheStats(cluster, client string, c cache.Cache) *cacheStats {
+	return &cacheStats{
+		cluster: cluster,
+		client:  client,
+		cache:   c,
+		kinds:   map[schema.GroupVersionKind]struct{}{},
+		objects: map[string]int{},
+		bytes:   map[string]int{},
+	}
+}
+
+func (s *cacheStats) track(gvk schema.GroupVersionKind) {
+	s.mu.Lock()
+	s.kinds[gvk] = struct{}{}
+	s.mu.Unlock()
+}
+
+func (s *cacheStats) run(ctx context.Context) {
+	cacheCollector.add(s)
+	defer cacheCollector.remove(s)
+
+	ticker := time.NewTicker(cacheStatsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.collect(ctx)
+		}
+	}
+}
+
+func (s *cacheStats) collect(ctx context.Context) {
+	s.mu.Lock()
+	kinds := make([]schema.GroupVersionKind, 0, len(s.kinds))
+	for gvk := range s.kinds {
+		kinds = append(kinds, gvk)
+	}
+	s.mu.Unlock()
+
+	for _, gvk := range kinds {
+		obj, err := runtimeScheme.New(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err != nil {
+			continue
+		}
+		list, ok := obj.(client.ObjectList)
+		if !ok {
+			continue
+		}
+		if err := s.cache.List(ctx, list); err != nil {
+			klog.V(2).Infof("Failed to list %s from cache of %s: %v", gvk.Kind, s.cluster, err)
+			continue
+		}
+		data, err := json.Marshal(list)
+		if err != nil {
+			continue
+		}
+		kind := gvk.Kind
+		if gvk.Group != "" {
+			kind += "." + gvk.Group
+		}
+		s.mu.Lock()
+		s.objects[kind] = meta.LenList(list)
+		s.bytes[kind] = len(data)
+		s.mu.Unlock()
+	}
+}
+
+// cacheStatsCollector exports the sizes measured by the running cacheStats,
+// summed per cluster, client type and kind.
+type cacheStatsCollector struct {
+	mu    sync.Mutex
+	stats map[*cacheStats]struct{}
+}
+
+func (c *cacheStatsCollector) add(s *cacheStats) {
+	c.mu.Lock()
+	c.stats[s] = struct{}{}
+	c.mu.Unlock()
+}
+
+func (c *cacheStatsCollector) remove(s *cacheStats) {
+	c.mu.Lock()
+	delete(c.stats, s)
+	c.mu.Unlock()
+}
+
+func (c *cacheStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheObjectsDesc
+	ch <- cacheBytesDesc
+}
+
+func (c *cacheStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	type key struct{ cluster, client, kind string }
+	objects := map[key]int{}
+	bytes := map[key]int{}
+
+	c.mu.Lock()
+	for s := range c.stats {
+		s.mu.Lock()
+		for kind, n := range s.objects {
+			objects[key{s.cluster, s.client, kind}] += n
+		}
+		for kind, n := range s.bytes {
+			bytes[key{s.cluster, s.client, kind}] += n
+		}
+		s.mu.Unlock()
+	}
+	c.mu.Unlock()
+
+	for k, n := range objects {
+		ch <- prometheus.MustNewConstMetric(cacheObjectsDesc, prometheus.GaugeValue, float64(n), k.cluster, k.client, k.kind)
+	}
+	for k, n := range bytes {
+		ch <- prometheus.MustNewConstMetric(cacheBytesDesc, prometheus.GaugeValue, float64(n), k.cluster, k.client, k.kind)
+	}
+}
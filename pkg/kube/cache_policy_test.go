@@ -0,0 +1,137 @@
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var (
+	podGVK        = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	secretGVK     = schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	eventGVK      = schema.GroupVersionKind{Version: "v1", Kind: "Event"}
+	eventV1GVK    = schema.GroupVersionKind{Group: "events.k8s.io", Version: "v1", Kind: "Event"}
+	deploymentGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+)
+
+func TestDefaultCachePolicy(t *testing.T) {
+	policy := newCachePolicy(common.DefaultCachePolicy())
+	assert.True(t, policy.cacheable(podGVK))
+	assert.True(t, policy.cacheable(deploymentGVK))
+	assert.False(t, policy.cacheable(secretGVK))
+	assert.False(t, policy.cacheable(eventGVK))
+	assert.False(t, policy.cacheable(eventV1GVK))
+}
+
+func TestCachePolicyAllowList(t *testing.T) {
+	policy := newCachePolicy(common.CachePolicy{
+		Cached:   []string{"Pod", "Deployment.apps", "Event.events.k8s.io"},
+		Uncached: []string{"Pod"},
+	})
+	assert.False(t, policy.cacheable(podGVK), "uncached wins over cached")
+	assert.True(t, policy.cacheable(deploymentGVK))
+	assert.True(t, policy.cacheable(eventV1GVK))
+	assert.False(t, policy.cacheable(eventGVK), "group qualified kinds only match their group")
+	assert.False(t, policy.cacheable(secretGVK))
+}
+
+func TestCachePolicyTransform(t *testing.T) {
+	policy := newCachePolicy(common.CachePolicy{StripManagedFields: true})
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:          "p",
+		ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		Annotations:   map[string]string{"small": "ok", "large": "0123456789"},
+	}}
+	_, err := policy.cacheTransform()(pod)
+	assert.NoError(t, err)
+	assert.Nil(t, pod.ManagedFields)
+	assert.Equal(t, map[string]string{"small": "ok", "large": "0123456789"}, pod.Annotations)
+
+	trim := newCachePolicy(common.CachePolicy{MaxAnnotationBytes: 5})
+	_, err = trim.cacheTransform()(pod)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"small": "ok", TrimmedAnnotationsAnnotation: "large"}, pod.Annotations)
+
+	assert.Nil(t, newCachePolicy(common.CachePolicy{}).cacheTransform())
+}
+
+func TestCachePolicyWithDefaults(t *testing.T) {
+	policy := newCachePolicy(common.CachePolicy{StripManagedFields: true}.WithDefaults())
+	assert.False(t, policy.cacheable(secretGVK), "default uncached kinds still apply")
+	assert.False(t, policy.cacheable(eventGVK))
+	assert.True(t, policy.cacheable(podGVK))
+
+	policy = newCachePolicy(common.CachePolicy{Cached: []string{"Pod", "Secret"}}.WithDefaults())
+	assert.True(t, policy.cacheable(secretGVK), "explicitly cached kinds are opted back in")
+	assert.False(t, policy.cacheable(eventGVK))
+}
+
+func TestPolicyClientTrimmedAnnotations(t *testing.T) {
+	full := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: "p", Namespace: "ns",
+		Annotations: map[string]string{"small": "ok", "large": "0123456789"},
+	}}
+	trimmed := full.DeepCopy()
+	trimmed.Annotations = map[string]string{"small": "ok", TrimmedAnnotationsAnnotation: "large"}
+	live := fake.NewClientBuilder().WithObjects(full).Build()
+	c := &policyClient{
+		Client: fake.NewClientBuilder().WithObjects(trimmed).Build(),
+		live:   live,
+		policy: newCachePolicy(common.CachePolicy{}),
+		stats:  newCacheStats("test", cacheClientShared, nil),
+	}
+	ctx := context.Background()
+
+	var pod corev1.Pod
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(full), &pod))
+	assert.Equal(t, full.Annotations, pod.Annotations, "trimmed objects are read live")
+
+	var list corev1.PodList
+	require.NoError(t, c.List(ctx, &list))
+	require.Len(t, list.Items, 1)
+	item := &list.Items[0]
+	item.Annotations["small"] = "changed"
+	require.NoError(t, c.Update(ctx, item))
+	assert.Equal(t, map[string]string{"small": "changed", "large": "0123456789"}, item.Annotations, "trimmed annotations are restored on update")
+}
+
+func TestCacheStatsCollector(t *testing.T) {
+	collector := &cacheStatsCollector{stats: map[*cacheStats]struct{}{}}
+	for _, objects := range []int{2, 3} {
+		s := newCacheStats("prod", cacheClientUser, nil)
+		s.objects["Pod"] = objects
+		collector.add(s)
+	}
+	shared := newCacheStats("prod", cacheClientShared, nil)
+	shared.objects["Pod"] = 7
+	collector.add(shared)
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	values := map[string]float64{}
+	for _, m := range families[0].GetMetric() {
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "client" {
+				values[label.GetValue()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	assert.Equal(t, map[string]float64{cacheClientUser: 5, cacheClientShared: 7}, values, "user clients are summed")
+
+	collector.remove(shared)
+	families, err = registry.Gather()
+	require.NoError(t, err)
+	assert.Len(t, families[0].GetMetric(), 1)
+}
@@ -6,11 +6,14 @@ import (
 	"os"
 	"time"
 
+	"github.com/pixelvide/kube-sentinel/pkg/common"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
@@ -37,20 +40,65 @@ func init() {
 	_ = metricsv1.AddToScheme(runtimeScheme)
 }
 
+// resourceAliases are the resources whose name in the API routes and RBAC
+// rules differs from their plural.
+var resourceAliases = map[schema.GroupResource]string{
+	{Group: apiextensionsv1.GroupName, Resource: "customresourcedefinitions"}: "crds",
+}
+
+// ResourceName returns the name of a resource in the API routes and RBAC
+// rules: the plural, or its alias, for built-in kinds and the CRD name,
+// plural.group, for custom resources.
+func ResourceName(gvr schema.GroupVersionResource) string {
+	if alias, ok := resourceAliases[gvr.GroupResource()]; ok {
+		return alias
+	}
+	if gvr.Group == "" || runtimeScheme.IsGroupRegistered(gvr.Group) {
+		return gvr.Resource
+	}
+	return gvr.Resource + "." + gvr.Group
+}
+
+// ParseResourceName is the inverse of ResourceName.
+func ParseResourceName(name string) schema.GroupResource {
+	for gr, alias := range resourceAliases {
+		if alias == name {
+			return gr
+		}
+	}
+	return schema.ParseGroupResource(name)
+}
+
 // K8sClient holds the Kubernetes client instances
 type K8sClient struct {
 	client.Client
+	// Live reads from the API server, bypassing the informer cache. Paths
+	// that diff against or write back an object read it with Live.
+	Live          client.Reader
 	ClientSet     *kubernetes.Clientset
 	Configuration *rest.Config
 	MetricsClient *metricsclient.Clientset
+	// Watches streams changes to the SSE watch routes
+	Watches *WatchHub
 
-	cancel context.CancelFunc
+	policy    *cachePolicy
+	snapshots listSnapshots
+	cancel    context.CancelFunc
 }
 
 // ClientOptions holds configuration for creating a K8sClient
 type ClientOptions struct {
 	Config       *rest.Config
 	DisableCache bool
+	// Name is the name of the cluster, used to label cache metrics.
+	Name string
+	// UserClient marks a client that uses the credentials of a user. The
+	// cache metrics of the user clients of a cluster are summed.
+	UserClient bool
+	// CachePolicy selects the kinds that may be cached. Nil means
+	// common.DefaultCachePolicy, whose uncached kinds also apply to any
+	// policy that does not cache them explicitly.
+	CachePolicy *common.CachePolicy
 }
 
 // NewClient creates a K8sClient from ClientOptions
@@ -65,10 +113,21 @@ func NewClient(opts ClientOptions) (*K8sClient, error) {
 		klog.Warningf("failed to create metrics client: %v", err)
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(opts.Config)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	var c client.Client
+	var live client.Reader
 	disableCache := opts.DisableCache || os.Getenv("DISABLE_CACHE") == "true"
+	policyConfig := common.DefaultCachePolicy()
+	if opts.CachePolicy != nil {
+		policyConfig = opts.CachePolicy.WithDefaults()
+	}
+	policy := newCachePolicy(policyConfig)
 
 	if disableCache {
 		c, err = client.New(opts.Config, client.Options{
@@ -78,6 +137,7 @@ func NewClient(opts ClientOptions) (*K8sClient, error) {
 			cancel()
 			return nil, fmt.Errorf("failed to create client: %w", err)
 		}
+		live = c
 	} else {
 		mgr, err := manager.New(opts.Config, manager.Options{
 			Scheme:         runtimeScheme,
@@ -88,6 +148,7 @@ func NewClient(opts ClientOptions) (*K8sClient, error) {
 			Cache: cache.Options{
 				DefaultWatchErrorHandler: func(ctx context.Context, r *toolscache.Reflector, err error) {
 				},
+				DefaultTransform: policy.cacheTransform(),
 			},
 		})
 		if err != nil {
@@ -115,16 +176,46 @@ func NewClient(opts ClientOptions) (*K8sClient, error) {
 			cancel()
 			return nil, fmt.Errorf("failed to wait for cache sync")
 		}
-		c = mgr.GetClient()
+		statsClient := cacheClientShared
+		if opts.UserClient {
+			statsClient = cacheClientUser
+		}
+		stats := newCacheStats(opts.Name, statsClient, mgr.GetCache())
+		go stats.run(ctx)
+		live = mgr.GetAPIReader()
+		c = &policyClient{
+			Client: mgr.GetClient(),
+			live:   live,
+			policy: policy,
+			stats:  stats,
+		}
 	}
 
-	return &K8sClient{
+	k8sClient := &K8sClient{
 		Client:        c,
+		Live:          live,
 		ClientSet:     clientset,
 		Configuration: opts.Config,
 		MetricsClient: metricsClient,
+		policy:        policy,
 		cancel:        cancel,
-	}, nil
+	}
+	k8sClient.Watches = NewWatchHub(ctx, dynamicClient, k8sClient.Cacheable)
+	return k8sClient, nil
+}
+
+// Cacheable reports whether the cache policy of the cluster lets objects of
+// gvr be held in memory. The policy also applies to watches when the cache
+// is disabled. Resources whose kind cannot be resolved are not cacheable.
+func (c *K8sClient) Cacheable(gvr schema.GroupVersionResource) bool {
+	if c.policy == nil {
+		return true
+	}
+	gvk, err := c.RESTMapper().KindFor(gvr)
+	if err != nil {
+		return false
+	}
+	return c.policy.cacheable(gvk)
 }
 
 func (c *K8sClient) Stop(name string) {
@@ -0,0 +1,181 @@
+package kube
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// volatileMetadataFields differ between otherwise identical objects.
+var volatileMetadataFields = []string{
+	"managedFields", "resourceVersion", "uid", "creationTimestamp",
+	"deletionTimestamp", "deletionGracePeriodSeconds", "generation", "selfLink",
+}
+
+// volatileAnnotations are maintained by controllers and tooling.
+var volatileAnnotations = []string{
+	common.KubectlAnnotation,
+	"deployment.kubernetes.io/revision",
+}
+
+// FieldChange is a field that differs between two objects. Path is the
+// dotted path of the field, From is nil for added and To for removed fields.
+type FieldChange struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// Field change operations
+const (
+	FieldAdded    = "add"
+	FieldRemoved  = "remove"
+	FieldReplaced = "replace"
+)
+
+// DiffObjects returns the unified diff of the normalized YAML of two objects,
+// or an empty string when they are identical.
+func DiffObjects(left, right *unstructured.Unstructured, leftLabel, rightLabel string) (string, error) {
+	leftYAML, err := NormalizedYAML(left)
+	if err != nil {
+		return "", err
+	}
+	rightYAML, err := NormalizedYAML(right)
+	if err != nil {
+		return "", err
+	}
+	return UnifiedDiff(leftYAML, rightYAML, leftLabel, rightLabel)
+}
+
+// UnifiedDiff returns the unified diff of two texts, or an empty string when
+// they are identical.
+func UnifiedDiff(left, right, leftLabel, rightLabel string) (string, error) {
+	if left == right {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(left),
+		B:        difflib.SplitLines(right),
+		FromFile: leftLabel,
+		ToFile:   rightLabel,
+		Context:  3,
+	})
+}
+
+// FieldChanges lists the fields of the normalized objects that differ, sorted
+// by path. Lists are compared as a whole. left may be nil for a new object.
+func FieldChanges(left, right *unstructured.Unstructured) []FieldChange {
+	var from map[string]interface{}
+	if left != nil {
+		from = normalize(left).Object
+	}
+	changes := []FieldChange{}
+	diffFields("", from, normalize(right).Object, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func diffFields(prefix string, from, to map[string]interface{}, changes *[]FieldChange) {
+	path := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	for key, v := range from {
+		w, ok := to[key]
+		if !ok {
+			*changes = append(*changes, FieldChange{Path: path(key), Op: FieldRemoved, From: v})
+			continue
+		}
+		vm, vok := v.(map[string]interface{})
+		wm, wok := w.(map[string]interface{})
+		if vok && wok {
+			diffFields(path(key), vm, wm, changes)
+		} else if !reflect.DeepEqual(v, w) {
+			*changes = append(*changes, FieldChange{Path: path(key), Op: FieldReplaced, From: v, To: w})
+		}
+	}
+	for key, w := range to {
+		if _, ok := from[key]; !ok {
+			*changes = append(*changes, FieldChange{Path: path(key), Op: FieldAdded, To: w})
+		}
+	}
+}
+
+// NormalizedYAML strips fields that are expected to differ between clusters
+// and namespaces and renders the rest as YAML with sorted keys.
+func NormalizedYAML(obj *unstructured.Unstructured) (string, error) {
+	out, err := yaml.Marshal(normalize(obj).Object)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// normalize returns a copy of obj without status, volatile metadata and
+// secret values.
+func normalize(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	o := obj.DeepCopy()
+	content := o.Object
+	delete(content, "status")
+	for _, field := range volatileMetadataFields {
+		unstructured.RemoveNestedField(content, "metadata", field)
+	}
+	// The namespace is part of the reference, not of the compared content
+	unstructured.RemoveNestedField(content, "metadata", "namespace")
+	if anno := o.GetAnnotations(); anno != nil {
+		for _, key := range volatileAnnotations {
+			delete(anno, key)
+		}
+		if len(anno) == 0 {
+			unstructured.RemoveNestedField(content, "metadata", "annotations")
+		} else {
+			o.SetAnnotations(anno)
+		}
+	}
+
+	switch o.GetKind() {
+	case "Service":
+		for _, field := range []string{"clusterIP", "clusterIPs"} {
+			unstructured.RemoveNestedField(content, "spec", field)
+		}
+	case "Secret":
+		RedactSecret(o)
+	}
+	return o
+}
+
+// RedactSecret replaces the values of a Secret with a short hash in place,
+// which shows that values differ without exposing them. Other kinds are not
+// changed.
+func RedactSecret(obj *unstructured.Unstructured) {
+	if obj == nil || obj.GetKind() != "Secret" {
+		return
+	}
+	for _, field := range []string{"data", "stringData"} {
+		data, found, _ := unstructured.NestedMap(obj.Object, field)
+		if !found {
+			continue
+		}
+		for k, v := range data {
+			sum := sha256.Sum256([]byte(fmt.Sprint(v)))
+			data[k] = fmt.Sprintf("<redacted sha256:%x>", sum[:8])
+		}
+		_ = unstructured.SetNestedMap(obj.Object, data, field)
+	}
+	// The last applied configuration holds the values too
+	if anno := obj.GetAnnotations(); anno != nil {
+		if _, ok := anno[common.KubectlAnnotation]; ok {
+			delete(anno, common.KubectlAnnotation)
+			obj.SetAnnotations(anno)
+		}
+	}
+}
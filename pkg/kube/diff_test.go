@@ -0,0 +1,62 @@
+package kube
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newDiffTestConfigMap(namespace, value string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":              "app",
+			"namespace":         namespace,
+			"uid":               namespace + "-uid",
+			"resourceVersion":   namespace + "-rv",
+			"creationTimestamp": "2024-01-01T00:00:00Z",
+			"managedFields":     []interface{}{map[string]interface{}{"manager": "kubectl"}},
+			"annotations": map[string]interface{}{
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+			},
+		},
+		"data": map[string]interface{}{"LOG_LEVEL": value},
+	}}
+}
+
+func TestNormalizedYAMLStripsVolatileFields(t *testing.T) {
+	out, err := NormalizedYAML(newDiffTestConfigMap("staging", "debug"))
+	assert.NoError(t, err)
+	for _, field := range []string{"uid", "resourceVersion", "creationTimestamp", "managedFields", "namespace", "annotations"} {
+		assert.NotContains(t, out, field)
+	}
+	assert.Contains(t, out, "LOG_LEVEL: debug")
+}
+
+func TestNormalizedYAMLRedactsSecrets(t *testing.T) {
+	secret := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "db"},
+		"data":       map[string]interface{}{"password": "c2VjcmV0"},
+	}}
+	out, err := NormalizedYAML(secret)
+	assert.NoError(t, err)
+	assert.NotContains(t, out, "c2VjcmV0")
+	assert.Contains(t, out, "<redacted sha256:")
+}
+
+func TestDiffObjects(t *testing.T) {
+	diff, err := DiffObjects(newDiffTestConfigMap("staging", "info"), newDiffTestConfigMap("prod", "info"), "a", "b")
+	assert.NoError(t, err)
+	assert.Empty(t, diff, "objects differing only in volatile fields should be identical")
+
+	diff, err = DiffObjects(newDiffTestConfigMap("staging", "debug"), newDiffTestConfigMap("prod", "info"), "staging", "prod")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(diff, "--- staging\n+++ prod\n"))
+	assert.Contains(t, diff, "-  LOG_LEVEL: debug")
+	assert.Contains(t, diff, "+  LOG_LEVEL: info")
+}
@@ -0,0 +1,634 @@
+package kube
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// listChunkSize is the page size used to list from the API server.
+const listChunkSize = 500
+
+// ListAll lists every object matching opts. Lists served live by the API
+// server are fetched in chunks; the informer cache does not support continue
+// tokens and is listed at once.
+func (c *K8sClient) ListAll(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if c.listsFromCache(list) {
+		return c.List(ctx, list, opts...)
+	}
+
+	var items []runtime.Object
+	var resourceVersion, continueToken string
+	for {
+		chunk := list.DeepCopyObject().(client.ObjectList)
+		chunkOpts := append(append([]client.ListOption{}, opts...), client.Limit(listChunkSize))
+		if continueToken != "" {
+			chunkOpts = append(chunkOpts, client.Continue(continueToken))
+		}
+		if err := c.List(ctx, chunk, chunkOpts...); err != nil {
+			return err
+		}
+		chunkItems, err := meta.ExtractList(chunk)
+		if err != nil {
+			return err
+		}
+		items = append(items, chunkItems...)
+		if resourceVersion == "" {
+			resourceVersion = chunk.GetResourceVersion()
+		}
+		continueToken = chunk.GetContinue()
+		if continueToken == "" {
+			break
+		}
+	}
+	if err := meta.SetList(list, items); err != nil {
+		return err
+	}
+	list.SetResourceVersion(resourceVersion)
+	list.SetContinue("")
+	return nil
+}
+
+func (c *K8sClient) listsFromCache(list client.ObjectList) bool {
+	pc, ok := c.Client.(*policyClient)
+	return ok && pc.useCache(list, true)
+}
+
+// ListForQuery lists the objects of q into list. With serverPaging, lists
+// that are not cached are paged by the API server in its namespace and name
+// order: list holds the page of q, its continue token is the page token of
+// the next page, and ListForQuery returns true. Otherwise list holds every
+// object for q.Apply. The later pages of a query of an uncached kind are cut
+// from a snapshot of the list of its first page, kept for
+// listSnapshotTTL, instead of listing every object again.
+func (c *K8sClient) ListForQuery(ctx context.Context, list client.ObjectList, q *ListQuery, serverPaging bool, opts ...client.ListOption) (bool, error) {
+	if c.listsFromCache(list) {
+		return false, c.List(ctx, list, opts...)
+	}
+	var token *pageToken
+	if q.PageToken != "" {
+		var err error
+		if token, err = q.token(); err != nil {
+			return false, err
+		}
+	}
+
+	if serverPaging {
+		pageOpts := append([]client.ListOption{}, opts...)
+		if q.PageSize > 0 {
+			pageOpts = append(pageOpts, client.Limit(q.PageSize))
+		}
+		if token != nil {
+			pageOpts = append(pageOpts, client.Continue(token.Continue))
+		}
+		if err := c.List(ctx, list, pageOpts...); err != nil {
+			return false, err
+		}
+		next := ""
+		if list.GetContinue() != "" {
+			var err error
+			if next, err = q.encodeToken(pageToken{Continue: list.GetContinue()}); err != nil {
+				return false, err
+			}
+		}
+		list.SetContinue(next)
+		return true, nil
+	}
+
+	key := snapshotKey(list, opts)
+	if token != nil && token.ResourceVersion != "" {
+		if items, ok := c.snapshots.get(key, token.ResourceVersion); ok {
+			if err := meta.SetList(list, items); err != nil {
+				return false, err
+			}
+			list.SetResourceVersion(token.ResourceVersion)
+			q.ResourceVersion = token.ResourceVersion
+			return false, nil
+		}
+	}
+	if err := c.ListAll(ctx, list, opts...); err != nil {
+		return false, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return false, err
+	}
+	c.snapshots.put(key, list.GetResourceVersion(), items)
+	q.ResourceVersion = list.GetResourceVersion()
+	return false, nil
+}
+
+const (
+	// listSnapshotTTL is how long the list of the first page of a query is
+	// kept for its later pages.
+	listSnapshotTTL = 2 * time.Minute
+	// maxListSnapshots is the number of lists kept per client.
+	maxListSnapshots = 8
+)
+
+// listSnapshots keeps the recent full lists of uncached kinds, keyed by
+// their kind, options and resourceVersion.
+type listSnapshots struct {
+	mu      sync.Mutex
+	entries map[string]*listSnapshot
+}
+
+type listSnapshot struct {
+	items   []runtime.Object
+	expires time.Time
+}
+
+// snapshotKey returns the key of the lists of the type of list with opts.
+func snapshotKey(list client.ObjectList, opts []client.ListOption) string {
+	listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+	key := fmt.Sprintf("%T/%s/%s", list, list.GetObjectKind().GroupVersionKind(), listOpts.Namespace)
+	if listOpts.LabelSelector != nil {
+		key += "/" + listOpts.LabelSelector.String()
+	}
+	if listOpts.FieldSelector != nil {
+		key += "/" + listOpts.FieldSelector.String()
+	}
+	return key
+}
+
+// get returns copies of the items of a snapshot, which the caller may
+// change.
+func (s *listSnapshots) get(key, resourceVersion string) ([]runtime.Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, ok := s.entries[key+"@"+resourceVersion]
+	if !ok || time.Now().After(snapshot.expires) {
+		return nil, false
+	}
+	items := make([]runtime.Object, 0, len(snapshot.items))
+	for _, item := range snapshot.items {
+		items = append(items, item.DeepCopyObject())
+	}
+	return items, true
+}
+
+func (s *listSnapshots) put(key, resourceVersion string, items []runtime.Object) {
+	if resourceVersion == "" {
+		return
+	}
+	copies := make([]runtime.Object, 0, len(items))
+	for _, item := range items {
+		copies = append(copies, item.DeepCopyObject())
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		s.entries = map[string]*listSnapshot{}
+	}
+	var oldest string
+	for k, snapshot := range s.entries {
+		if now.After(snapshot.expires) {
+			delete(s.entries, k)
+		} else if oldest == "" || snapshot.expires.Before(s.entries[oldest].expires) {
+			oldest = k
+		}
+	}
+	if len(s.entries) >= maxListSnapshots {
+		delete(s.entries, oldest)
+	}
+	s.entries[key+"@"+resourceVersion] = &listSnapshot{items: copies, expires: now.Add(listSnapshotTTL)}
+}
+
+// Sort fields of ListQuery
+const (
+	SortByName      = "name"
+	SortByNamespace = "namespace"
+	SortByAge       = "age"
+	SortByStatus    = "status"
+	SortByRestarts  = "restarts"
+)
+
+// ListQuery filters, sorts and pages a list after it is read, so that it
+// works the same for the cache and the API server and pages stay full after
+// objects the user cannot see are dropped.
+type ListQuery struct {
+	// Filter matches a substring of the name or of a "key=value" label
+	Filter string
+	// SortBy is one of the SortBy constants. Sorting by age puts the newest
+	// objects first, or the oldest with Desc.
+	SortBy string
+	Desc   bool
+	// PageSize is the number of objects per page, all when zero
+	PageSize int
+	// PageToken is the token of the next page returned for the previous one
+	PageToken string
+	// ResourceVersion is the version of the list the items of Apply come
+	// from. It is kept in the token of the next page.
+	ResourceVersion string
+
+	// cell returns the table cell an object is sorted by, see SortByTable.
+	cell func(obj runtime.Object) interface{}
+}
+
+// pageToken is the last object of a page. The next page starts after it in
+// the same order.
+type pageToken struct {
+	SortBy string  `json:"s"`
+	Desc   bool    `json:"d,omitempty"`
+	Filter string  `json:"f,omitempty"`
+	Last   sortKey `json:"k"`
+	// ResourceVersion is the version of the list the page was cut from.
+	ResourceVersion string `json:"rv,omitempty"`
+	// Continue is the API server token of the next page of a list the API
+	// server pages.
+	Continue string `json:"c,omitempty"`
+}
+
+type sortKey struct {
+	Text      string `json:"t,omitempty"`
+	Number    int64  `json:"n,omitempty"`
+	Namespace string `json:"ns,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (a sortKey) less(b sortKey) bool {
+	if a.Text != b.Text {
+		return a.Text < b.Text
+	}
+	if a.Number != b.Number {
+		return a.Number < b.Number
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+// Validate checks the sort field and page token of q.
+func (q *ListQuery) Validate() error {
+	switch q.SortBy {
+	case "":
+		q.SortBy = SortByAge
+	case SortByName, SortByNamespace, SortByAge, SortByStatus, SortByRestarts:
+	default:
+		return fmt.Errorf("sortBy must be one of: name, namespace, age, status, restarts")
+	}
+	if q.PageSize < 0 {
+		return fmt.Errorf("pageSize must not be negative")
+	}
+	if q.PageToken != "" {
+		if _, err := q.token(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *ListQuery) token() (*pageToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(q.PageToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+	var token pageToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+	if token.SortBy != q.SortBy || token.Desc != q.Desc || token.Filter != q.Filter {
+		return nil, fmt.Errorf("page token does not match the sort and filter of the query")
+	}
+	return &token, nil
+}
+
+// encodeToken returns token for the sort and filter of q.
+func (q *ListQuery) encodeToken(token pageToken) (string, error) {
+	token.SortBy, token.Desc, token.Filter = q.SortBy, q.Desc, q.Filter
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func (q *ListQuery) key(obj runtime.Object) sortKey {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return sortKey{}
+	}
+	key := sortKey{Namespace: accessor.GetNamespace(), Name: accessor.GetName()}
+	switch q.SortBy {
+	case SortByName:
+		key.Text = accessor.GetName()
+	case SortByNamespace:
+		key.Text = accessor.GetNamespace()
+	case SortByAge:
+		key.Number = -accessor.GetCreationTimestamp().UnixNano()
+	case SortByStatus:
+		if q.cell != nil {
+			key.Text = fmt.Sprint(q.cell(obj))
+		} else {
+			key.Text = ObjectStatus(obj)
+		}
+	case SortByRestarts:
+		if q.cell != nil {
+			key.Number = cellNumber(q.cell(obj))
+		} else {
+			key.Number = ObjectRestarts(obj)
+		}
+	}
+	return key
+}
+
+// matches reports whether the name or a label of obj contains the filter.
+func (q *ListQuery) matches(obj runtime.Object) bool {
+	if q.Filter == "" {
+		return true
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	filter := strings.ToLower(q.Filter)
+	if strings.Contains(strings.ToLower(accessor.GetName()), filter) {
+		return true
+	}
+	for k, v := range accessor.GetLabels() {
+		if strings.Contains(strings.ToLower(k+"="+v), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply filters and sorts items and returns the page of q, the token of the
+// next page and the number of objects after the page. q must be valid.
+func (q *ListQuery) Apply(items []runtime.Object) ([]runtime.Object, string, int64, error) {
+	type keyed struct {
+		obj runtime.Object
+		key sortKey
+	}
+	matched := make([]keyed, 0, len(items))
+	for _, item := range items {
+		if q.matches(item) {
+			matched = append(matched, keyed{obj: item, key: q.key(item)})
+		}
+	}
+	less := func(a, b sortKey) bool {
+		if q.Desc {
+			return b.less(a)
+		}
+		return a.less(b)
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return less(matched[i].key, matched[j].key) })
+
+	start := 0
+	if q.PageToken != "" {
+		token, err := q.token()
+		if err != nil {
+			return nil, "", 0, err
+		}
+		if token.Continue != "" {
+			return nil, "", 0, fmt.Errorf("page token is not valid for this list, start from the first page")
+		}
+		start = sort.Search(len(matched), func(i int) bool { return less(token.Last, matched[i].key) })
+	}
+	end := len(matched)
+	if q.PageSize > 0 && start+q.PageSize < end {
+		end = start + q.PageSize
+	}
+
+	page := make([]runtime.Object, 0, end-start)
+	for _, item := range matched[start:end] {
+		page = append(page, item.obj)
+	}
+	var next string
+	if end < len(matched) {
+		var err error
+		if next, err = q.encodeToken(pageToken{Last: matched[end-1].key, ResourceVersion: q.ResourceVersion}); err != nil {
+			return nil, "", 0, err
+		}
+	}
+	return page, next, int64(len(matched) - end), nil
+}
+
+// ObjectSummary is the slim form of an object in a list, without its spec.
+type ObjectSummary struct {
+	APIVersion string                  `json:"apiVersion,omitempty"`
+	Kind       string                  `json:"kind,omitempty"`
+	Metadata   ObjectSummaryMetadata   `json:"metadata"`
+	Status     string                  `json:"status,omitempty"`
+	Ready      string                  `json:"ready,omitempty"`
+	Restarts   int64                   `json:"restarts,omitempty"`
+	Owners     []metav1.OwnerReference `json:"ownerReferences,omitempty"`
+}
+
+type ObjectSummaryMetadata struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace,omitempty"`
+	UID               types.UID         `json:"uid"`
+	ResourceVersion   string            `json:"resourceVersion,omitempty"`
+	CreationTimestamp metav1.Time       `json:"creationTimestamp"`
+	DeletionTimestamp *metav1.Time      `json:"deletionTimestamp,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+}
+
+// Summarize returns the summary of obj.
+func Summarize(obj runtime.Object) ObjectSummary {
+	summary := ObjectSummary{
+		Status:   ObjectStatus(obj),
+		Ready:    objectReady(obj),
+		Restarts: ObjectRestarts(obj),
+	}
+	if gvk := obj.GetObjectKind().GroupVersionKind(); !gvk.Empty() {
+		summary.APIVersion, summary.Kind = gvk.GroupVersion().String(), gvk.Kind
+	}
+	if accessor, err := meta.Accessor(obj); err == nil {
+		summary.Metadata = ObjectSummaryMetadata{
+			Name:              accessor.GetName(),
+			Namespace:         accessor.GetNamespace(),
+			UID:               accessor.GetUID(),
+			ResourceVersion:   accessor.GetResourceVersion(),
+			CreationTimestamp: accessor.GetCreationTimestamp(),
+			DeletionTimestamp: accessor.GetDeletionTimestamp(),
+			Labels:            accessor.GetLabels(),
+		}
+		summary.Owners = accessor.GetOwnerReferences()
+	}
+	return summary
+}
+
+// ObjectStatus returns a one-word status of obj, like the STATUS column of
+// kubectl get: the reason a pod is not running, whether a node or workload
+// is ready, or the phase of other objects.
+func ObjectStatus(obj runtime.Object) string {
+	if accessor, err := meta.Accessor(obj); err == nil && accessor.GetDeletionTimestamp() != nil {
+		return "Terminating"
+	}
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		return podStatus(o)
+	case *corev1.Node:
+		status := "NotReady"
+		for _, condition := range o.Status.Conditions {
+			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+				status = "Ready"
+			}
+		}
+		if o.Spec.Unschedulable {
+			status += ",SchedulingDisabled"
+		}
+		return status
+	case *appsv1.Deployment, *appsv1.StatefulSet, *appsv1.ReplicaSet, *appsv1.DaemonSet:
+		ready, desired := workloadReplicas(o)
+		switch {
+		case desired == 0:
+			return "ScaledDown"
+		case ready >= desired:
+			return "Ready"
+		}
+		return "NotReady"
+	case *batchv1.Job:
+		if o.Spec.Suspend != nil && *o.Spec.Suspend {
+			return "Suspended"
+		}
+		for _, condition := range o.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				return "Complete"
+			case batchv1.JobFailed:
+				return "Failed"
+			}
+		}
+		return "Running"
+	case *corev1.PersistentVolumeClaim:
+		return string(o.Status.Phase)
+	case *corev1.PersistentVolume:
+		return string(o.Status.Phase)
+	case *corev1.Namespace:
+		return string(o.Status.Phase)
+	case *unstructured.Unstructured:
+		return unstructuredStatus(o)
+	}
+	return ""
+}
+
+// unstructuredStatus returns the phase of a custom resource, or whether its
+// Ready condition is true.
+func unstructuredStatus(obj *unstructured.Unstructured) string {
+	if phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase"); phase != "" {
+		return phase
+	}
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		if condition["status"] == "True" {
+			return "Ready"
+		}
+		return "NotReady"
+	}
+	return ""
+}
+
+func podStatus(pod *corev1.Pod) string {
+	reason := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		reason = pod.Status.Reason
+	}
+	for _, status := range pod.Status.InitContainerStatuses {
+		switch {
+		case status.State.Terminated != nil && status.State.Terminated.ExitCode != 0:
+			return "Init:" + nonEmpty(status.State.Terminated.Reason, "Error")
+		case status.State.Waiting != nil && status.State.Waiting.Reason != "" && status.State.Waiting.Reason != "PodInitializing":
+			return "Init:" + status.State.Waiting.Reason
+		}
+	}
+	for i := len(pod.Status.ContainerStatuses) - 1; i >= 0; i-- {
+		status := pod.Status.ContainerStatuses[i]
+		switch {
+		case status.State.Waiting != nil && status.State.Waiting.Reason != "":
+			reason = status.State.Waiting.Reason
+		case status.State.Terminated != nil && status.State.Terminated.Reason != "":
+			reason = status.State.Terminated.Reason
+		}
+	}
+	return reason
+}
+
+func nonEmpty(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// ObjectRestarts returns the container restarts of a pod, and 0 for other
+// objects.
+func ObjectRestarts(obj runtime.Object) int64 {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return 0
+	}
+	var restarts int64
+	for _, status := range pod.Status.InitContainerStatuses {
+		restarts += int64(status.RestartCount)
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += int64(status.RestartCount)
+	}
+	return restarts
+}
+
+// objectReady returns the ready containers of a pod or the ready replicas of
+// a workload, e.g. "2/3".
+func objectReady(obj runtime.Object) string {
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		ready := 0
+		for _, status := range o.Status.ContainerStatuses {
+			if status.Ready {
+				ready++
+			}
+		}
+		return fmt.Sprintf("%d/%d", ready, len(o.Spec.Containers))
+	case *appsv1.Deployment, *appsv1.StatefulSet, *appsv1.ReplicaSet, *appsv1.DaemonSet:
+		ready, desired := workloadReplicas(o)
+		return fmt.Sprintf("%d/%d", ready, desired)
+	}
+	return ""
+}
+
+func workloadReplicas(obj runtime.Object) (int32, int32) {
+	desired := func(replicas *int32) int32 {
+		if replicas == nil {
+			return 1
+		}
+		return *replicas
+	}
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return o.Status.ReadyReplicas, desired(o.Spec.Replicas)
+	case *appsv1.StatefulSet:
+		return o.Status.ReadyReplicas, desired(o.Spec.Replicas)
+	case *appsv1.ReplicaSet:
+		return o.Status.ReadyReplicas, desired(o.Spec.Replicas)
+	case *appsv1.DaemonSet:
+		return o.Status.NumberReady, o.Status.DesiredNumberScheduled
+	}
+	return 0, 0
+}
@@ -0,0 +1,207 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func newPod(name string, age time.Duration, restarts int32, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "shop",
+			Labels:            labels,
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", Ready: true, RestartCount: restarts}},
+		},
+	}
+}
+
+func names(items []runtime.Object) []string {
+	var result []string
+	for _, item := range items {
+		result = append(result, item.(metav1.Object).GetName())
+	}
+	return result
+}
+
+func TestListQueryPages(t *testing.T) {
+	var items []runtime.Object
+	for i := 0; i < 5; i++ {
+		items = append(items, newPod(fmt.Sprintf("pod-%d", i), time.Duration(i)*time.Hour, 0, nil))
+	}
+
+	query := &ListQuery{PageSize: 2}
+	require.NoError(t, query.Validate())
+	page, next, remaining, err := query.Apply(items)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pod-0", "pod-1"}, names(page), "newest first")
+	assert.EqualValues(t, 3, remaining)
+	require.NotEmpty(t, next)
+	first := next
+
+	// objects removed before the next page do not shift it
+	query.PageToken = next
+	page, next, remaining, err = query.Apply(items[2:])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pod-2", "pod-3"}, names(page))
+	assert.EqualValues(t, 1, remaining)
+
+	query.PageToken = next
+	page, next, remaining, err = query.Apply(items)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pod-4"}, names(page))
+	assert.Empty(t, next)
+	assert.Zero(t, remaining)
+
+	other := &ListQuery{SortBy: SortByName, PageToken: first}
+	assert.Error(t, other.Validate())
+	assert.Error(t, (&ListQuery{SortBy: "size"}).Validate())
+}
+
+func TestListQuerySortAndFilter(t *testing.T) {
+	crashing := newPod("worker", time.Hour, 7, map[string]string{"app": "worker"})
+	crashing.Status.ContainerStatuses[0].State.Waiting = &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}
+	items := []runtime.Object{
+		newPod("api", 2*time.Hour, 1, map[string]string{"app": "api", "tier": "backend"}),
+		crashing,
+		newPod("web", 3*time.Hour, 0, map[string]string{"app": "web", "tier": "frontend"}),
+	}
+
+	query := &ListQuery{SortBy: SortByRestarts, Desc: true}
+	require.NoError(t, query.Validate())
+	page, _, _, err := query.Apply(items)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"worker", "api", "web"}, names(page))
+
+	query = &ListQuery{SortBy: SortByStatus}
+	require.NoError(t, query.Validate())
+	page, _, _, err = query.Apply(items)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"worker", "api", "web"}, names(page))
+
+	query = &ListQuery{SortBy: SortByName, Filter: "Tier=B"}
+	require.NoError(t, query.Validate())
+	page, _, _, err = query.Apply(items)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api"}, names(page))
+}
+
+func TestObjectStatus(t *testing.T) {
+	pod := newPod("api", time.Hour, 0, nil)
+	assert.Equal(t, "Running", ObjectStatus(pod))
+	pod.Status.InitContainerStatuses = []corev1.ContainerStatus{{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}}}
+	assert.Equal(t, "Init:ImagePullBackOff", ObjectStatus(pod))
+	pod.DeletionTimestamp = ptr.To(metav1.Now())
+	assert.Equal(t, "Terminating", ObjectStatus(pod))
+
+	deployment := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: ptr.To[int32](3)}, Status: appsv1.DeploymentStatus{ReadyReplicas: 2}}
+	assert.Equal(t, "NotReady", ObjectStatus(deployment))
+	assert.Equal(t, "2/3", Summarize(deployment).Ready)
+
+	cr := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}}},
+	}}
+	assert.Equal(t, "Ready", ObjectStatus(cr))
+}
+
+func TestListAll(t *testing.T) {
+	var objs []runtime.Object
+	for i := 0; i < 3; i++ {
+		objs = append(objs, newPod(fmt.Sprintf("pod-%d", i), time.Hour, 0, nil))
+	}
+	c := &K8sClient{Client: fake.NewClientBuilder().WithScheme(runtimeScheme).WithRuntimeObjects(objs...).Build()}
+	var pods corev1.PodList
+	require.NoError(t, c.ListAll(context.Background(), &pods))
+	assert.Len(t, pods.Items, 3)
+	assert.Empty(t, pods.Continue)
+}
+
+func TestListForQuery(t *testing.T) {
+	var objs []runtime.Object
+	for i := 0; i < 5; i++ {
+		objs = append(objs, newPod(fmt.Sprintf("pod-%d", i), time.Duration(i)*time.Hour, 0, nil))
+	}
+	lists := 0
+	// The fake client does not page, so pages are cut here like the API
+	// server does, with the index of the next object as continue token.
+	fakeClient := fake.NewClientBuilder().WithScheme(runtimeScheme).WithRuntimeObjects(objs...).WithInterceptorFuncs(interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			lists++
+			if err := c.List(ctx, list, opts...); err != nil {
+				return err
+			}
+			listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+			pods := list.(*corev1.PodList)
+			start, _ := strconv.Atoi(listOpts.Continue)
+			end := len(pods.Items)
+			if listOpts.Limit > 0 && start+int(listOpts.Limit) < end {
+				end = start + int(listOpts.Limit)
+				pods.Continue = strconv.Itoa(end)
+			}
+			pods.Items = pods.Items[start:end]
+			pods.ResourceVersion = "42"
+			return nil
+		},
+	}).Build()
+	c := &K8sClient{Client: fakeClient}
+	ctx := context.Background()
+
+	query := &ListQuery{PageSize: 2}
+	require.NoError(t, query.Validate())
+	var pods corev1.PodList
+	paged, err := c.ListForQuery(ctx, &pods, query, true)
+	require.NoError(t, err)
+	assert.True(t, paged)
+	assert.Len(t, pods.Items, 2)
+	require.NotEmpty(t, pods.Continue)
+	query.PageToken = pods.Continue
+	pods = corev1.PodList{}
+	_, err = c.ListForQuery(ctx, &pods, query, true)
+	require.NoError(t, err)
+	assert.Equal(t, "pod-2", pods.Items[0].Name)
+	assert.Equal(t, 2, lists)
+
+	// the later pages of a query are cut from the list of its first page
+	lists = 0
+	query = &ListQuery{SortBy: SortByName, PageSize: 2}
+	require.NoError(t, query.Validate())
+	var seen []string
+	for {
+		var pods corev1.PodList
+		paged, err := c.ListForQuery(ctx, &pods, query, false)
+		require.NoError(t, err)
+		assert.False(t, paged)
+		items, err := meta.ExtractList(&pods)
+		require.NoError(t, err)
+		page, next, _, err := query.Apply(items)
+		require.NoError(t, err)
+		seen = append(seen, names(page)...)
+		if next == "" {
+			break
+		}
+		query.PageToken = next
+	}
+	assert.Equal(t, []string{"pod-0", "pod-1", "pod-2", "pod-3", "pod-4"}, seen)
+	assert.Equal(t, 1, lists)
+}
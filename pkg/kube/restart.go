@@ -0,0 +1,77 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RestartedAtAnnotation is stamped on the pod template of a workload to
+// trigger a rolling restart.
+const RestartedAtAnnotation = "kube-sentinel.kubernetes.io/restartedAt"
+
+// Restartable reports whether obj can be restarted through the pod template
+// at spec.template: Deployments, StatefulSets, DaemonSets and custom
+// resources following the same convention, such as Argo Rollouts.
+func Restartable(obj client.Object) bool {
+	switch o := obj.(type) {
+	case *appsv1.Deployment, *appsv1.StatefulSet, *appsv1.DaemonSet:
+		return true
+	case *unstructured.Unstructured:
+		if !RestartableKind(o.GroupVersionKind()) {
+			return false
+		}
+		_, found, err := unstructured.NestedMap(o.Object, "spec", "template")
+		return found && err == nil
+	}
+	return false
+}
+
+// RestartableKind reports whether objects of gvk may be restarted:
+// Deployments, StatefulSets, DaemonSets and custom resources. Other built-in
+// kinds with a pod template, such as ReplicaSets and Jobs, are not rolled out
+// again when it changes.
+func RestartableKind(gvk schema.GroupVersionKind) bool {
+	if gvk.Group != "" && !runtimeScheme.IsGroupRegistered(gvk.Group) {
+		return true
+	}
+	if gvk.Group != appsv1.GroupName {
+		return false
+	}
+	switch gvk.Kind {
+	case "Deployment", "StatefulSet", "DaemonSet":
+		return true
+	}
+	return false
+}
+
+// Restart triggers a rolling restart of obj by stamping RestartedAtAnnotation
+// on its pod template, like kubectl rollout restart. obj is updated with the
+// patched object.
+func Restart(ctx context.Context, c client.Client, obj client.Object) error {
+	if !Restartable(obj) {
+		return fmt.Errorf("%s has no pod template to restart", obj.GetName())
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						RestartedAtAnnotation: time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return c.Patch(ctx, obj, client.RawPatch(types.MergePatchType, patch), client.FieldOwner(FieldManager))
+}
@@ -0,0 +1,64 @@
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRestartable(t *testing.T) {
+	assert.True(t, Restartable(&appsv1.StatefulSet{}))
+	assert.False(t, Restartable(&corev1.ConfigMap{}))
+
+	rollout := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Rollout",
+		"spec":       map[string]interface{}{"template": map[string]interface{}{}},
+	}}
+	assert.True(t, Restartable(rollout))
+	unstructured.RemoveNestedField(rollout.Object, "spec", "template")
+	_ = unstructured.SetNestedField(rollout.Object, map[string]interface{}{"name": "api"}, "spec", "workloadRef")
+	assert.False(t, Restartable(rollout))
+
+	// built-in kinds other than deployments, statefulsets and daemonsets are
+	// not restarted even though they have a pod template
+	for _, gvk := range []schema.GroupVersionKind{appsv1.SchemeGroupVersion.WithKind("ReplicaSet"), batchv1.SchemeGroupVersion.WithKind("Job")} {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"template": map[string]interface{}{}},
+		}}
+		obj.SetGroupVersionKind(gvk)
+		assert.False(t, Restartable(obj), gvk.Kind)
+	}
+	deployment := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"template": map[string]interface{}{}},
+	}}
+	deployment.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	assert.True(t, Restartable(deployment))
+}
+
+func TestRestart(t *testing.T) {
+	ctx := context.Background()
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "kube-system"},
+		Spec:       appsv1.DaemonSetSpec{Template: testPodTemplate("agent:v1")},
+	}
+	c := fake.NewClientBuilder().WithObjects(ds).Build()
+	require.NoError(t, Restart(ctx, c, ds))
+
+	var updated appsv1.DaemonSet
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(ds), &updated))
+	assert.NotEmpty(t, updated.Spec.Template.Annotations[RestartedAtAnnotation])
+	assert.Equal(t, "api", updated.Spec.Template.Labels["app"])
+
+	assert.Error(t, Restart(ctx, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm"}}))
+}
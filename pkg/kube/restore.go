@@ -0,0 +1,79 @@
+package kube
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// serverManagedMetadataFields are set by the API server and are stripped
+// from an object before it is restored.
+var serverManagedMetadataFields = []string{
+	"uid", "resourceVersion", "generation", "creationTimestamp", "managedFields",
+	"selfLink", "deletionTimestamp", "deletionGracePeriodSeconds",
+}
+
+// DecodeAuditObject decodes an object recorded as YAML in the audit log, or
+// returns nil for an empty document.
+func DecodeAuditObject(data string) (*unstructured.Unstructured, error) {
+	if data == "" {
+		return nil, nil
+	}
+	content := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(data), &content); err != nil {
+		return nil, fmt.Errorf("failed to decode object: %w", err)
+	}
+	if len(content) == 0 {
+		return nil, nil
+	}
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+// RestoreObject returns the object that restores previous, the state of an
+// object recorded in the audit log, over live, which is nil when the object
+// no longer exists. Server-managed fields and the status are stripped, and
+// the resource version of live is kept so that the restore replaces it.
+func RestoreObject(previous, live *unstructured.Unstructured) *unstructured.Unstructured {
+	obj := previous.DeepCopy()
+	delete(obj.Object, "status")
+	for _, field := range serverManagedMetadataFields {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	if live != nil {
+		obj.SetResourceVersion(live.GetResourceVersion())
+		return obj
+	}
+	if obj.GetKind() == "Service" {
+		// The cluster IP may have been given to another service
+		if ip, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterIP"); ip != "None" {
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+		}
+	}
+	return obj
+}
+
+// RestoreConflicts compares recorded, the object right after the audited
+// change or nil for a deletion, with live, nil when the object no longer
+// exists. It returns warnings about what restoring the previous state would
+// overwrite, and the fields changed since the audited change.
+func RestoreConflicts(recorded, live *unstructured.Unstructured) ([]string, []FieldChange) {
+	switch {
+	case live == nil && recorded != nil:
+		return []string{"The object was deleted after this change and will be re-created."}, nil
+	case live == nil:
+		return nil, nil
+	case recorded == nil:
+		return []string{"An object with this name was created after the deletion and will be replaced."}, nil
+	case recorded.GetUID() != "" && recorded.GetUID() != live.GetUID():
+		return []string{"The object was deleted and re-created after this change, and the new object will be replaced."}, nil
+	case recorded.GetResourceVersion() == "" || recorded.GetResourceVersion() == live.GetResourceVersion():
+		return nil, nil
+	}
+	changes := FieldChanges(recorded, live)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("The object was changed after this change, and %d later field changes will be reverted.", len(changes))}, changes
+}
@@ -0,0 +1,83 @@
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const auditedService = `apiVersion: v1
+kind: Service
+metadata:
+  name: api
+  namespace: shop
+  uid: 1f0c
+  resourceVersion: "41"
+  generation: 3
+  creationTimestamp: "2026-01-02T03:04:05Z"
+  managedFields:
+  - manager: kubectl
+  labels:
+    app: api
+spec:
+  clusterIP: 10.0.0.12
+  clusterIPs: [10.0.0.12]
+  ports:
+  - port: 80
+status:
+  loadBalancer: {}
+`
+
+func TestRestoreObject(t *testing.T) {
+	previous, err := DecodeAuditObject(auditedService)
+	require.NoError(t, err)
+
+	obj := RestoreObject(previous, nil)
+	assert.Empty(t, obj.GetUID())
+	assert.Empty(t, obj.GetResourceVersion())
+	assert.Empty(t, obj.GetManagedFields())
+	assert.NotContains(t, obj.Object["metadata"], "creationTimestamp")
+	assert.NotContains(t, obj.Object, "status")
+	assert.NotContains(t, obj.Object["spec"], "clusterIP", "a re-created service gets a new cluster IP")
+	assert.Equal(t, map[string]string{"app": "api"}, obj.GetLabels())
+	assert.Equal(t, "41", previous.GetResourceVersion(), "previous is not modified")
+
+	live := previous.DeepCopy()
+	live.SetResourceVersion("57")
+	obj = RestoreObject(previous, live)
+	assert.Equal(t, "57", obj.GetResourceVersion())
+	assert.Contains(t, obj.Object["spec"], "clusterIP")
+
+	none, err := DecodeAuditObject("")
+	require.NoError(t, err)
+	assert.Nil(t, none)
+}
+
+func TestRestoreConflicts(t *testing.T) {
+	recorded, err := DecodeAuditObject(auditedService)
+	require.NoError(t, err)
+
+	warnings, changes := RestoreConflicts(recorded, recorded.DeepCopy())
+	assert.Empty(t, warnings, "the object did not change after the audited change")
+	assert.Empty(t, changes)
+
+	warnings, _ = RestoreConflicts(recorded, nil)
+	assert.Len(t, warnings, 1, "the object was deleted")
+	warnings, _ = RestoreConflicts(nil, recorded)
+	assert.Len(t, warnings, 1, "the deleted object was re-created")
+	warnings, _ = RestoreConflicts(nil, nil)
+	assert.Empty(t, warnings)
+
+	recreated := recorded.DeepCopy()
+	recreated.SetUID("9a7e")
+	warnings, _ = RestoreConflicts(recorded, recreated)
+	assert.Len(t, warnings, 1)
+
+	changed := recorded.DeepCopy()
+	changed.SetResourceVersion("57")
+	changed.SetLabels(map[string]string{"app": "api", "tier": "backend"})
+	warnings, changes = RestoreConflicts(recorded, changed)
+	assert.Len(t, warnings, 1)
+	assert.Equal(t, []FieldChange{{Path: "metadata.labels.tier", Op: FieldAdded, To: "backend"}}, changes)
+}
@@ -0,0 +1,236 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// RevisionAnnotation holds the revision of a Deployment and its ReplicaSets
+	RevisionAnnotation = "deployment.kubernetes.io/revision"
+	// ChangeCauseAnnotation records the cause of a rollout
+	ChangeCauseAnnotation = "kubernetes.io/change-cause"
+)
+
+// rollbackSkippedAnnotations are ReplicaSet annotations that are not copied
+// to the Deployment on rollback, like kubectl rollout undo.
+var rollbackSkippedAnnotations = map[string]bool{
+	"kubectl.kubernetes.io/last-applied-configuration": true,
+	RevisionAnnotation:                          true,
+	"deployment.kubernetes.io/revision-history": true,
+	"deployment.kubernetes.io/desired-replicas": true,
+	"deployment.kubernetes.io/max-replicas":     true,
+	"deprecated.deployment.rollback.to":         true,
+}
+
+// Revision is a revision of the pod template of a workload, kept in a
+// ReplicaSet for Deployments and in a ControllerRevision for StatefulSets and
+// DaemonSets.
+type Revision struct {
+	Revision    int64     `json:"revision"`
+	Name        string    `json:"name"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ChangeCause string    `json:"changeCause,omitempty"`
+	Images      []string  `json:"images"`
+	Current     bool      `json:"current"`
+
+	Template corev1.PodTemplateSpec `json:"-"`
+	// data is the patch of a ControllerRevision
+	data []byte
+}
+
+// ListRevisions returns the revisions of a Deployment, StatefulSet or
+// DaemonSet, newest first.
+func ListRevisions(ctx context.Context, c client.Client, obj client.Object) ([]Revision, error) {
+	var revisions []Revision
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		var replicaSets appsv1.ReplicaSetList
+		if err := c.List(ctx, &replicaSets, client.InNamespace(o.Namespace)); err != nil {
+			return nil, err
+		}
+		current := o.Annotations[RevisionAnnotation]
+		for _, rs := range replicaSets.Items {
+			if !isControlledBy(&rs, o) {
+				continue
+			}
+			number, err := strconv.ParseInt(rs.Annotations[RevisionAnnotation], 10, 64)
+			if err != nil {
+				continue
+			}
+			template := *rs.Spec.Template.DeepCopy()
+			delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+			revisions = append(revisions, Revision{
+				Revision:    number,
+				Name:        rs.Name,
+				CreatedAt:   rs.CreationTimestamp.Time,
+				ChangeCause: rs.Annotations[ChangeCauseAnnotation],
+				Current:     rs.Annotations[RevisionAnnotation] == current,
+				Template:    template,
+			})
+		}
+
+	case *appsv1.StatefulSet, *appsv1.DaemonSet:
+		var controllerRevisions appsv1.ControllerRevisionList
+		if err := c.List(ctx, &controllerRevisions, client.InNamespace(obj.GetNamespace())); err != nil {
+			return nil, err
+		}
+		for _, cr := range controllerRevisions.Items {
+			if !isControlledBy(&cr, obj) {
+				continue
+			}
+			template, err := controllerRevisionTemplate(&cr)
+			if err != nil {
+				return nil, fmt.Errorf("controller revision %s: %w", cr.Name, err)
+			}
+			revisions = append(revisions, Revision{
+				Revision:    cr.Revision,
+				Name:        cr.Name,
+				CreatedAt:   cr.CreationTimestamp.Time,
+				ChangeCause: cr.Annotations[ChangeCauseAnnotation],
+				Template:    template,
+				data:        cr.Data.Raw,
+			})
+		}
+		sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision > revisions[j].Revision })
+		if sts, ok := obj.(*appsv1.StatefulSet); ok && sts.Status.UpdateRevision != "" {
+			for i := range revisions {
+				revisions[i].Current = revisions[i].Name == sts.Status.UpdateRevision
+			}
+		} else if len(revisions) > 0 {
+			revisions[0].Current = true
+		}
+
+	default:
+		return nil, fmt.Errorf("%T has no revisions", obj)
+	}
+
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision > revisions[j].Revision })
+	for i := range revisions {
+		for _, container := range revisions[i].Template.Spec.Containers {
+			revisions[i].Images = append(revisions[i].Images, container.Image)
+		}
+	}
+	return revisions, nil
+}
+
+// FindRevision returns the revision with the given number. Revision 0 is the
+// revision before the current one.
+func FindRevision(revisions []Revision, revision int64) (*Revision, error) {
+	if revision == 0 {
+		for i, r := range revisions {
+			if r.Current {
+				if i+1 < len(revisions) {
+					return &revisions[i+1], nil
+				}
+				break
+			}
+		}
+		return nil, fmt.Errorf("no previous revision found")
+	}
+	for i := range revisions {
+		if revisions[i].Revision == revision {
+			return &revisions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("revision %d not found", revision)
+}
+
+// RevisionDiff returns the unified diff of the pod templates of two
+// revisions.
+func RevisionDiff(from, to *Revision) (string, error) {
+	fromYAML, err := yaml.Marshal(from.Template)
+	if err != nil {
+		return "", err
+	}
+	toYAML, err := yaml.Marshal(to.Template)
+	if err != nil {
+		return "", err
+	}
+	return UnifiedDiff(string(fromYAML), string(toYAML),
+		fmt.Sprintf("revision %d", from.Revision), fmt.Sprintf("revision %d", to.Revision))
+}
+
+// Rollback rolls obj back to the pod template of target, like kubectl rollout
+// undo. It returns false without changing obj when the template already
+// matches.
+func Rollback(ctx context.Context, c client.Client, obj client.Object, target *Revision) (bool, error) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		if o.Spec.Paused {
+			return false, fmt.Errorf("cannot roll back a paused deployment, resume it first")
+		}
+		current := o.Spec.Template.DeepCopy()
+		delete(current.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+		if apiequality.Semantic.DeepEqual(*current, target.Template) {
+			return false, nil
+		}
+		patched := o.DeepCopy()
+		patched.Spec.Template = target.Template
+		var rs appsv1.ReplicaSet
+		if err := c.Get(ctx, types.NamespacedName{Namespace: o.Namespace, Name: target.Name}, &rs); err != nil {
+			return false, err
+		}
+		for key, value := range rs.Annotations {
+			if rollbackSkippedAnnotations[key] {
+				continue
+			}
+			if patched.Annotations == nil {
+				patched.Annotations = map[string]string{}
+			}
+			patched.Annotations[key] = value
+		}
+		if err := c.Patch(ctx, patched, client.MergeFrom(o), client.FieldOwner(FieldManager)); err != nil {
+			return false, err
+		}
+		patched.DeepCopyInto(o)
+		return true, nil
+
+	case *appsv1.StatefulSet, *appsv1.DaemonSet:
+		var current corev1.PodTemplateSpec
+		switch w := o.(type) {
+		case *appsv1.StatefulSet:
+			current = w.Spec.Template
+		case *appsv1.DaemonSet:
+			current = w.Spec.Template
+		}
+		if apiequality.Semantic.DeepEqual(current, target.Template) {
+			return false, nil
+		}
+		// The data of a ControllerRevision is the patch that restores it
+		if err := c.Patch(ctx, obj, client.RawPatch(types.StrategicMergePatchType, target.data), client.FieldOwner(FieldManager)); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("%T cannot be rolled back", obj)
+}
+
+func controllerRevisionTemplate(cr *appsv1.ControllerRevision) (corev1.PodTemplateSpec, error) {
+	var data struct {
+		Spec struct {
+			Template corev1.PodTemplateSpec `json:"template"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(cr.Data.Raw, &data); err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+	return data.Spec.Template, nil
+}
+
+func isControlledBy(obj metav1.Object, owner metav1.Object) bool {
+	ref := metav1.GetControllerOf(obj)
+	return ref != nil && ref.UID == owner.GetUID()
+}
@@ -0,0 +1,141 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testPodTemplate(image string) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "api"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "api", Image: image}}},
+	}
+}
+
+func controllerRef(kind, name string, uid types.UID) []metav1.OwnerReference {
+	return []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: name, UID: uid, Controller: ptr.To(true)}}
+}
+
+func testReplicaSet(revision int, image string) *appsv1.ReplicaSet {
+	template := testPodTemplate(image)
+	template.Labels[appsv1.DefaultDeploymentUniqueLabelKey] = fmt.Sprintf("hash%d", revision)
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("api-%d", revision),
+			Namespace: "shop",
+			Annotations: map[string]string{
+				RevisionAnnotation:    fmt.Sprint(revision),
+				ChangeCauseAnnotation: "deploy " + image,
+			},
+			OwnerReferences: controllerRef("Deployment", "api", "deploy-uid"),
+		},
+		Spec: appsv1.ReplicaSetSpec{Template: template},
+	}
+}
+
+func TestDeploymentRollback(t *testing.T) {
+	ctx := context.Background()
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api",
+			Namespace:   "shop",
+			UID:         "deploy-uid",
+			Annotations: map[string]string{RevisionAnnotation: "2"},
+		},
+		Spec: appsv1.DeploymentSpec{Template: testPodTemplate("api:v2")},
+	}
+	other := testReplicaSet(7, "other:v1")
+	other.Name = "other"
+	other.OwnerReferences = controllerRef("Deployment", "other", "other-uid")
+	c := fake.NewClientBuilder().WithObjects(deployment, testReplicaSet(1, "api:v1"), testReplicaSet(2, "api:v2"), other).Build()
+
+	revisions, err := ListRevisions(ctx, c, deployment)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, int64(2), revisions[0].Revision)
+	assert.True(t, revisions[0].Current)
+	assert.Equal(t, []string{"api:v2"}, revisions[0].Images)
+	assert.Equal(t, "deploy api:v1", revisions[1].ChangeCause)
+	assert.NotContains(t, revisions[1].Template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+
+	target, err := FindRevision(revisions, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), target.Revision)
+	_, err = FindRevision(revisions, 5)
+	assert.Error(t, err)
+
+	diff, err := RevisionDiff(&revisions[0], target)
+	require.NoError(t, err)
+	assert.Contains(t, diff, "--- revision 2\n+++ revision 1\n")
+	assert.Contains(t, diff, "-  - image: api:v2")
+	assert.Contains(t, diff, "+  - image: api:v1")
+
+	changed, err := Rollback(ctx, c, deployment, target)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	var updated appsv1.Deployment
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(deployment), &updated))
+	assert.Equal(t, "api:v1", updated.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, "deploy api:v1", updated.Annotations[ChangeCauseAnnotation])
+	assert.Equal(t, "2", updated.Annotations[RevisionAnnotation], "the revision is maintained by the controller")
+
+	changed, err = Rollback(ctx, c, &updated, target)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	updated.Spec.Paused = true
+	_, err = Rollback(ctx, c, &updated, &revisions[0])
+	assert.ErrorContains(t, err, "paused")
+}
+
+func TestStatefulSetRollback(t *testing.T) {
+	ctx := context.Background()
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "shop", UID: "sts-uid"},
+		Spec:       appsv1.StatefulSetSpec{Template: testPodTemplate("db:v2")},
+		Status:     appsv1.StatefulSetStatus{UpdateRevision: "db-2"},
+	}
+	revision := func(number int64, image string) *appsv1.ControllerRevision {
+		template := testPodTemplate(image)
+		raw := fmt.Sprintf(`{"spec":{"template":{"$patch":"replace","metadata":{"labels":{"app":"api"}},"spec":{"containers":[{"name":"api","image":%q}]}}}}`, template.Spec.Containers[0].Image)
+		return &appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            fmt.Sprintf("db-%d", number),
+				Namespace:       "shop",
+				OwnerReferences: controllerRef("StatefulSet", "db", "sts-uid"),
+			},
+			Revision: number,
+			Data:     runtime.RawExtension{Raw: []byte(raw)},
+		}
+	}
+	c := fake.NewClientBuilder().WithObjects(sts, revision(1, "db:v1"), revision(2, "db:v2")).Build()
+
+	revisions, err := ListRevisions(ctx, c, sts)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "db-2", revisions[0].Name)
+	assert.True(t, revisions[0].Current)
+	assert.False(t, revisions[1].Current)
+	assert.Equal(t, []string{"db:v1"}, revisions[1].Images)
+
+	target, err := FindRevision(revisions, 0)
+	require.NoError(t, err)
+	changed, err := Rollback(ctx, c, sts, target)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	var updated appsv1.StatefulSet
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(sts), &updated))
+	assert.Equal(t, "db:v1", updated.Spec.Template.Spec.Containers[0].Image)
+}
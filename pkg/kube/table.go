@@ -0,0 +1,277 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metatable "k8s.io/apimachinery/pkg/api/meta/table"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// TableAccept is the Accept header that makes the API server return a list
+// as a Table with the columns of kubectl get.
+const TableAccept = "application/json;as=Table;g=meta.k8s.io;v=v1"
+
+var objectMetaDescriptions = metav1.ObjectMeta{}.SwaggerDoc()
+
+// ListTable lists gvr in namespace, or in all namespaces when empty, as a
+// Table from the API server, in chunks. The object of each row is its
+// PartialObjectMetadata.
+func (c *K8sClient) ListTable(ctx context.Context, gvr schema.GroupVersionResource, namespace string, opts metav1.ListOptions) (*metav1.Table, error) {
+	config := rest.CopyConfig(c.Configuration)
+	config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	restClient, err := rest.UnversionedRESTClientFor(config)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := "/apis/" + gvr.Group
+	if gvr.Group == "" {
+		prefix = "/api"
+	}
+	resourcePath := path.Join(prefix, gvr.Version, gvr.Resource)
+	if namespace != "" {
+		resourcePath = path.Join(prefix, gvr.Version, "namespaces", namespace, gvr.Resource)
+	}
+
+	var table *metav1.Table
+	continueToken := ""
+	for {
+		req := restClient.Get().AbsPath(resourcePath).
+			SetHeader("Accept", TableAccept).
+			Param("includeObject", string(metav1.IncludeMetadata)).
+			Param("limit", strconv.Itoa(listChunkSize))
+		if opts.LabelSelector != "" {
+			req = req.Param("labelSelector", opts.LabelSelector)
+		}
+		if opts.FieldSelector != "" {
+			req = req.Param("fieldSelector", opts.FieldSelector)
+		}
+		if continueToken != "" {
+			req = req.Param("continue", continueToken)
+		}
+		data, err := req.Do(ctx).Raw()
+		if err != nil {
+			return nil, err
+		}
+		var chunk metav1.Table
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode table: %w", err)
+		}
+		if table == nil {
+			table = &chunk
+		} else {
+			table.Rows = append(table.Rows, chunk.Rows...)
+		}
+		continueToken = chunk.Continue
+		if continueToken == "" {
+			break
+		}
+	}
+	table.Continue = ""
+	table.RemainingItemCount = nil
+	return table, nil
+}
+
+// CustomResourceTable returns the custom resources of a version of crd as a
+// Table with its additionalPrinterColumns, evaluated like the API server
+// does. Without printer columns it has the Name and Age columns.
+func CustomResourceTable(crd *apiextensionsv1.CustomResourceDefinition, version string, items []unstructured.Unstructured) (*metav1.Table, error) {
+	var columns []apiextensionsv1.CustomResourceColumnDefinition
+	for _, v := range crd.Spec.Versions {
+		if v.Name == version {
+			columns = v.AdditionalPrinterColumns
+		}
+	}
+	if len(columns) == 0 {
+		columns = []apiextensionsv1.CustomResourceColumnDefinition{{
+			Name:        "Age",
+			Type:        "date",
+			Description: objectMetaDescriptions["creationTimestamp"],
+			JSONPath:    ".metadata.creationTimestamp",
+		}}
+	}
+
+	table := &metav1.Table{
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "Name", Type: "string", Format: "name", Description: objectMetaDescriptions["name"]},
+		},
+	}
+	table.APIVersion, table.Kind = metav1.SchemeGroupVersion.String(), "Table"
+	paths := make([]*jsonpath.JSONPath, 0, len(columns))
+	for _, column := range columns {
+		p := jsonpath.New(column.Name)
+		if err := p.Parse(fmt.Sprintf("{%s}", column.JSONPath)); err != nil {
+			return nil, fmt.Errorf("unrecognized column definition %q", column.JSONPath)
+		}
+		p.AllowMissingKeys(true)
+		paths = append(paths, p)
+
+		description := column.Description
+		if description == "" {
+			description = fmt.Sprintf("Custom resource definition column (in JSONPath format): %s", column.JSONPath)
+		}
+		table.ColumnDefinitions = append(table.ColumnDefinitions, metav1.TableColumnDefinition{
+			Name:        column.Name,
+			Type:        column.Type,
+			Format:      column.Format,
+			Description: description,
+			Priority:    column.Priority,
+		})
+	}
+
+	var buf bytes.Buffer
+	for i := range items {
+		item := &items[i]
+		cells := make([]interface{}, 0, 1+len(paths))
+		cells = append(cells, item.GetName())
+		for j, p := range paths {
+			results, err := p.FindResults(item.Object)
+			if err != nil || len(results) == 0 || len(results[0]) == 0 {
+				cells = append(cells, nil)
+				continue
+			}
+			value := results[0][0].Interface()
+			if columns[j].Type == "string" {
+				if err := p.PrintResults(&buf, []reflect.Value{reflect.ValueOf(value)}); err != nil {
+					cells = append(cells, nil)
+				} else {
+					cells = append(cells, buf.String())
+				}
+				buf.Reset()
+				continue
+			}
+			cells = append(cells, tableCell(columns[j].Type, value))
+		}
+
+		object, err := json.Marshal(&metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: metav1.SchemeGroupVersion.String(), Kind: "PartialObjectMetadata"},
+			ObjectMeta: partialObjectMeta(item),
+		})
+		if err != nil {
+			return nil, err
+		}
+		table.Rows = append(table.Rows, metav1.TableRow{Cells: cells, Object: runtime.RawExtension{Raw: object}})
+	}
+	return table, nil
+}
+
+func partialObjectMeta(obj *unstructured.Unstructured) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:              obj.GetName(),
+		Namespace:         obj.GetNamespace(),
+		UID:               obj.GetUID(),
+		ResourceVersion:   obj.GetResourceVersion(),
+		CreationTimestamp: obj.GetCreationTimestamp(),
+		DeletionTimestamp: obj.GetDeletionTimestamp(),
+		Labels:            obj.GetLabels(),
+		Annotations:       obj.GetAnnotations(),
+		OwnerReferences:   obj.GetOwnerReferences(),
+	}
+}
+
+// tableCell converts a JSON value to the cell of a column type.
+func tableCell(columnType string, value interface{}) interface{} {
+	switch columnType {
+	case "integer":
+		switch v := value.(type) {
+		case int64:
+			return v
+		case float64:
+			return int64(v)
+		}
+	case "number":
+		switch v := value.(type) {
+		case int64:
+			return float64(v)
+		case float64:
+			return v
+		}
+	case "boolean":
+		if v, ok := value.(bool); ok {
+			return v
+		}
+	case "date":
+		if v, ok := value.(string); ok {
+			var timestamp metav1.Time
+			if err := timestamp.UnmarshalQueryParameter(v); err != nil {
+				return "<invalid>"
+			}
+			return metatable.ConvertToHumanReadableDateType(timestamp)
+		}
+	}
+	return nil
+}
+
+// TableRowObjects decodes the PartialObjectMetadata of each row of table.
+func TableRowObjects(table *metav1.Table) ([]*metav1.PartialObjectMetadata, error) {
+	objects := make([]*metav1.PartialObjectMetadata, 0, len(table.Rows))
+	for i, row := range table.Rows {
+		obj := &metav1.PartialObjectMetadata{}
+		if err := json.Unmarshal(row.Object.Raw, obj); err != nil {
+			return nil, fmt.Errorf("failed to decode the object of row %d: %w", i, err)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// SortByTable makes q sort the rows of table, whose objects are objects, by
+// their Status or Restarts cell, as the objects of rows do not carry their
+// status. It returns an error when the table has no such column.
+func (q *ListQuery) SortByTable(table *metav1.Table, objects []*metav1.PartialObjectMetadata) error {
+	var column string
+	switch q.SortBy {
+	case SortByStatus:
+		column = "Status"
+	case SortByRestarts:
+		column = "Restarts"
+	default:
+		return nil
+	}
+	index := slices.IndexFunc(table.ColumnDefinitions, func(c metav1.TableColumnDefinition) bool {
+		return strings.EqualFold(c.Name, column)
+	})
+	if index < 0 {
+		return fmt.Errorf("sortBy %s is not supported for this table, it has no %s column", q.SortBy, column)
+	}
+	cells := make(map[runtime.Object]interface{}, len(objects))
+	for i, obj := range objects {
+		if i < len(table.Rows) && index < len(table.Rows[i].Cells) {
+			cells[obj] = table.Rows[i].Cells[index]
+		}
+	}
+	q.cell = func(obj runtime.Object) interface{} { return cells[obj] }
+	return nil
+}
+
+// cellNumber returns the number of a table cell, or the number a text cell
+// starts with, like "3 (5m ago)" in the Restarts column of pods.
+func cellNumber(cell interface{}) int64 {
+	switch v := cell.(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case string:
+		if fields := strings.Fields(v); len(fields) > 0 {
+			n, _ := strconv.ParseInt(fields[0], 10, 64)
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+func TestCustomResourceTable(t *testing.T) {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1", AdditionalPrinterColumns: []apiextensionsv1.CustomResourceColumnDefinition{
+					{Name: "Ready", Type: "string", JSONPath: `.status.conditions[?(@.type=="Ready")].status`},
+					{Name: "Replicas", Type: "integer", JSONPath: ".spec.replicas"},
+					{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+				}},
+				{Name: "v1beta1"},
+			},
+		},
+	}
+	item := unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":              "api",
+			"namespace":         "shop",
+			"creationTimestamp": metav1.NewTime(time.Now().Add(-2 * time.Hour)).UTC().Format(time.RFC3339),
+		},
+		"spec":   map[string]interface{}{"replicas": int64(3)},
+		"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}}},
+	}}
+	missing := unstructured.Unstructured{Object: map[string]interface{}{"metadata": map[string]interface{}{"name": "web"}}}
+
+	table, err := CustomResourceTable(crd, "v1", []unstructured.Unstructured{item, missing})
+	require.NoError(t, err)
+	require.Len(t, table.ColumnDefinitions, 4)
+	assert.Equal(t, "Name", table.ColumnDefinitions[0].Name)
+	require.Len(t, table.Rows, 2)
+	assert.Equal(t, []interface{}{"api", "True", int64(3), "120m"}, table.Rows[0].Cells)
+	assert.Equal(t, []interface{}{"web", nil, nil, nil}, table.Rows[1].Cells)
+
+	objects, err := TableRowObjects(table)
+	require.NoError(t, err)
+	assert.Equal(t, "shop", objects[0].Namespace)
+
+	table, err = CustomResourceTable(crd, "v1beta1", []unstructured.Unstructured{item})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Name", "Age"}, []string{table.ColumnDefinitions[0].Name, table.ColumnDefinitions[1].Name})
+}
+
+func TestListTable(t *testing.T) {
+	var requests []*http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		table := metav1.Table{
+			TypeMeta:          metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "Table"},
+			ColumnDefinitions: []metav1.TableColumnDefinition{{Name: "Name", Type: "string"}},
+		}
+		name := "api"
+		if r.URL.Query().Get("continue") == "" {
+			table.Continue = "next"
+		} else {
+			name = "web"
+		}
+		object, _ := json.Marshal(metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"}})
+		table.Rows = []metav1.TableRow{{Cells: []interface{}{name}, Object: runtime.RawExtension{Raw: object}}}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(table)
+	}))
+	defer srv.Close()
+
+	c := &K8sClient{Configuration: &rest.Config{Host: srv.URL}}
+	table, err := c.ListTable(context.Background(), schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, "shop", metav1.ListOptions{LabelSelector: "app=api"})
+	require.NoError(t, err)
+	require.Len(t, table.Rows, 2)
+	assert.Empty(t, table.Continue)
+	assert.Equal(t, "web", table.Rows[1].Cells[0])
+
+	require.Len(t, requests, 2)
+	assert.Equal(t, "/apis/apps/v1/namespaces/shop/deployments", requests[0].URL.Path)
+	assert.Equal(t, TableAccept, requests[0].Header.Get("Accept"))
+	assert.Equal(t, "app=api", requests[0].URL.Query().Get("labelSelector"))
+	assert.Equal(t, "next", requests[1].URL.Query().Get("continue"))
+}
+
+func TestListQuerySortByTable(t *testing.T) {
+	table := &metav1.Table{
+		ColumnDefinitions: []metav1.TableColumnDefinition{{Name: "Name"}, {Name: "Status"}, {Name: "Restarts"}},
+		Rows: []metav1.TableRow{
+			{Cells: []interface{}{"api", "Running", "3 (5m ago)"}},
+			{Cells: []interface{}{"web", "CrashLoopBackOff", "12 (1m ago)"}},
+			{Cells: []interface{}{"db", "Running", int64(0)}},
+		},
+	}
+	var objects []*metav1.PartialObjectMetadata
+	var items []runtime.Object
+	for _, row := range table.Rows {
+		obj := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: row.Cells[0].(string), Namespace: "shop"}}
+		objects = append(objects, obj)
+		items = append(items, obj)
+	}
+
+	query := &ListQuery{SortBy: SortByRestarts, Desc: true}
+	require.NoError(t, query.Validate())
+	require.NoError(t, query.SortByTable(table, objects))
+	page, _, _, err := query.Apply(items)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web", "api", "db"}, names(page))
+
+	query = &ListQuery{SortBy: SortByStatus}
+	require.NoError(t, query.Validate())
+	require.NoError(t, query.SortByTable(table, objects))
+	page, _, _, err = query.Apply(items)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web", "api", "db"}, names(page))
+
+	table.ColumnDefinitions = table.ColumnDefinitions[:1]
+	assert.Error(t, query.SortByTable(table, objects))
+}
@@ -0,0 +1,464 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	metricsv1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// Watch event types
+const (
+	WatchAdded    = "added"
+	WatchModified = "modified"
+	WatchDeleted  = "deleted"
+	// WatchBookmark carries the resource version to resume from. One is sent
+	// after the initial events and periodically after that.
+	WatchBookmark = "bookmark"
+	// WatchReset is sent before a new snapshot when the resource version to
+	// resume from is too old. Clients drop the objects they have.
+	WatchReset = "reset"
+)
+
+const (
+	// watchHistorySize is the number of events kept to resume watches from.
+	watchHistorySize = 1024
+	// watchBufferSize is the number of events a subscriber may fall behind
+	// before it is dropped.
+	watchBufferSize = 256
+	// watchIdleTimeout is how long an informer is kept without subscribers.
+	watchIdleTimeout = time.Minute
+	// watchSyncTimeout is how long a subscriber waits for the initial list.
+	watchSyncTimeout = 30 * time.Second
+)
+
+// ErrWatchUnsupported is returned for resources that cannot be watched.
+var ErrWatchUnsupported = fmt.Errorf("resource does not support watch")
+
+// WatchEvent is a change to an object, or a bookmark or reset.
+type WatchEvent struct {
+	Type            string
+	Object          *unstructured.Unstructured
+	ResourceVersion string
+}
+
+// WatchFilter selects the events of a subscriber.
+type WatchFilter struct {
+	// Namespaces are the namespaces to watch, all when empty
+	Namespaces []string
+	Labels     labels.Selector
+	Fields     fields.Selector
+	// Allow, when not nil, reports whether the subscriber may see obj
+	Allow func(obj *unstructured.Unstructured) bool
+}
+
+func (f *WatchFilter) matches(obj *unstructured.Unstructured) bool {
+	if obj == nil {
+		return false
+	}
+	if len(f.Namespaces) > 0 {
+		found := false
+		for _, ns := range f.Namespaces {
+			if obj.GetNamespace() == ns {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Labels != nil && !f.Labels.Matches(labels.Set(obj.GetLabels())) {
+		return false
+	}
+	if f.Fields != nil && !f.Fields.Empty() && !f.Fields.Matches(objectFields(obj, f.Fields)) {
+		return false
+	}
+	return f.Allow == nil || f.Allow(obj)
+}
+
+// objectFields returns the fields of obj the selector refers to, e.g.
+// metadata.name or status.phase.
+func objectFields(obj *unstructured.Unstructured, selector fields.Selector) fields.Set {
+	set := fields.Set{}
+	for _, req := range selector.Requirements() {
+		value, found, err := unstructured.NestedFieldNoCopy(obj.Object, strings.Split(req.Field, ".")...)
+		if err != nil || !found {
+			continue
+		}
+		set[req.Field] = fmt.Sprint(value)
+	}
+	return set
+}
+
+// WatchHub shares one informer per resource and namespace between all the
+// subscribers of a cluster, so that browser tabs do not each open an API
+// watch. Informers are started on the first subscriber and stopped after they
+// are idle. Resources the cache policy keeps out of memory get an informer per
+// subscriber instead, which is stopped with it.
+type WatchHub struct {
+	ctx       context.Context
+	client    dynamic.Interface
+	cacheable func(gvr schema.GroupVersionResource) bool
+	mu        sync.Mutex
+	watches   map[watchKey]*sharedWatch
+}
+
+// watchKey identifies a shared watch. namespace is empty for a watch of all
+// namespaces or cluster-scoped resources.
+type watchKey struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+// NewWatchHub returns a WatchHub whose informers run until ctx is done.
+// cacheable reports whether the informer of a resource may be shared; nil
+// shares all of them.
+func NewWatchHub(ctx context.Context, client dynamic.Interface, cacheable func(gvr schema.GroupVersionResource) bool) *WatchHub {
+	return &WatchHub{
+		ctx:       ctx,
+		client:    client,
+		cacheable: cacheable,
+		watches:   map[watchKey]*sharedWatch{},
+	}
+}
+
+// watchEntry is an event in the history of a shared watch. old is the
+// previous object of a modification.
+type watchEntry struct {
+	typ string
+	obj *unstructured.Unstructured
+	old *unstructured.Unstructured
+	rv  string
+}
+
+type sharedWatch struct {
+	hub      *WatchHub
+	key      watchKey
+	informer toolscache.SharedIndexInformer
+	// private watches belong to a single subscriber and stop with it
+	private bool
+	cancel  context.CancelFunc
+	synced  chan struct{}
+
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	// pending counts the subscribers waiting for the initial list
+	pending int
+	history []watchEntry
+	// truncated is set once events were dropped from the history
+	truncated bool
+	// startRV is the resource version of the initial list
+	startRV string
+	lastRV  string
+	lastErr error
+	idle    *time.Timer
+}
+
+// Subscription receives the events of a watch. Initial holds the events to
+// send first: a replay from the requested resource version, or a snapshot
+// of the matching objects. Events is closed when the subscriber falls too
+// far behind, and it should reconnect with the last resource version.
+type Subscription struct {
+	Initial []WatchEvent
+	Events  <-chan WatchEvent
+
+	events chan WatchEvent
+	filter WatchFilter
+	watch  *sharedWatch
+	closed bool
+}
+
+// ResourceVersion returns the resource version to resume the subscription
+// from.
+func (s *Subscription) ResourceVersion() string {
+	s.watch.mu.Lock()
+	defer s.watch.mu.Unlock()
+	return s.watch.lastRV
+}
+
+// Close stops the subscription.
+func (s *Subscription) Close() {
+	s.watch.mu.Lock()
+	defer s.watch.mu.Unlock()
+	s.watch.remove(s)
+}
+
+// Subscribe starts receiving the events of gvr matching filter. With a
+// resourceVersion the events after it are replayed if they are still known,
+// otherwise the subscription starts with a snapshot. A filter with a single
+// namespace watches only that namespace.
+func (h *WatchHub) Subscribe(ctx context.Context, gvr schema.GroupVersionResource, resourceVersion string, filter WatchFilter) (*Subscription, error) {
+	if gvr.Group == metricsv1.SchemeGroupVersion.Group {
+		return nil, ErrWatchUnsupported
+	}
+	key := watchKey{gvr: gvr}
+	if len(filter.Namespaces) == 1 {
+		key.namespace = filter.Namespaces[0]
+	}
+	w := h.get(key, h.cacheable == nil || h.cacheable(gvr))
+
+	timer := time.NewTimer(watchSyncTimeout)
+	defer timer.Stop()
+	select {
+	case <-w.synced:
+	case <-ctx.Done():
+		h.release(w)
+		return nil, ctx.Err()
+	case <-timer.C:
+		w.mu.Lock()
+		err := w.lastErr
+		w.mu.Unlock()
+		h.release(w)
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch %s: %w", gvr.Resource, err)
+		}
+		return nil, fmt.Errorf("timed out waiting for the watch of %s", gvr.Resource)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	events := make(chan WatchEvent, watchBufferSize)
+	sub := &Subscription{Events: events, events: events, filter: filter, watch: w}
+	sub.Initial = w.initialEvents(resourceVersion, &sub.filter)
+	w.subscribers[sub] = struct{}{}
+	w.pending--
+	return sub, nil
+}
+
+// get returns the watch of key, starting it if needed. Watches that are not
+// shared are always started for the caller. The caller holds a reference on
+// it until release.
+func (h *WatchHub) get(key watchKey, shared bool) *sharedWatch {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if w, ok := h.watches[key]; ok && shared {
+		w.mu.Lock()
+		if w.idle != nil {
+			w.idle.Stop()
+			w.idle = nil
+		}
+		w.pending++
+		w.mu.Unlock()
+		return w
+	}
+
+	ctx, cancel := context.WithCancel(h.ctx)
+	w := &sharedWatch{
+		hub:         h,
+		key:         key,
+		private:     !shared,
+		cancel:      cancel,
+		synced:      make(chan struct{}),
+		subscribers: map[*Subscription]struct{}{},
+		pending:     1,
+	}
+	w.informer = dynamicinformer.NewFilteredDynamicInformer(h.client, key.gvr, key.namespace, 0, toolscache.Indexers{}, nil).Informer()
+	_ = w.informer.SetTransform(watchTransform)
+	_ = w.informer.SetWatchErrorHandlerWithContext(func(ctx context.Context, r *toolscache.Reflector, err error) {
+		w.mu.Lock()
+		w.lastErr = err
+		w.mu.Unlock()
+		toolscache.DefaultWatchErrorHandler(ctx, r, err)
+	})
+	_, _ = w.informer.AddEventHandler(toolscache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if !isInInitialList {
+				w.dispatch(WatchAdded, obj, nil)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			w.dispatch(WatchModified, newObj, oldObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			w.dispatch(WatchDeleted, obj, nil)
+		},
+	})
+	go w.informer.RunWithContext(ctx)
+	go func() {
+		if !toolscache.WaitForCacheSync(ctx.Done(), w.informer.HasSynced) {
+			return
+		}
+		w.mu.Lock()
+		if len(w.history) == 0 {
+			w.startRV = w.informer.LastSyncResourceVersion()
+			w.lastRV = w.startRV
+		}
+		w.mu.Unlock()
+		close(w.synced)
+	}()
+	if shared {
+		h.watches[key] = w
+	}
+	klog.V(2).Infof("Started watch of %s in namespace %q (shared: %t)", key.gvr.String(), key.namespace, shared)
+	return w
+}
+
+// release drops the reference taken by get for a subscriber that gave up.
+func (h *WatchHub) release(w *sharedWatch) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending--
+	w.idleCheck()
+}
+
+// remove removes a subscriber. The caller holds w.mu.
+func (w *sharedWatch) remove(sub *Subscription) {
+	if !sub.closed {
+		sub.closed = true
+		close(sub.events)
+	}
+	delete(w.subscribers, sub)
+	w.idleCheck()
+}
+
+// idleCheck stops the watch after watchIdleTimeout without subscribers, or at
+// once when it is private. The caller holds w.mu.
+func (w *sharedWatch) idleCheck() {
+	if len(w.subscribers) > 0 || w.pending > 0 || w.idle != nil {
+		return
+	}
+	if w.private {
+		w.cancel()
+		return
+	}
+	w.idle = time.AfterFunc(watchIdleTimeout, w.stopIfIdle)
+}
+
+func (w *sharedWatch) stopIfIdle() {
+	w.hub.mu.Lock()
+	defer w.hub.mu.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.subscribers) > 0 || w.pending > 0 || w.idle == nil {
+		return
+	}
+	w.idle = nil
+	w.cancel()
+	if w.hub.watches[w.key] == w {
+		delete(w.hub.watches, w.key)
+	}
+	klog.V(2).Infof("Stopped idle shared watch of %s in namespace %q", w.key.gvr.String(), w.key.namespace)
+}
+
+// initialEvents returns the events after resourceVersion, or a snapshot
+// ending with a bookmark. The caller holds w.mu.
+func (w *sharedWatch) initialEvents(resourceVersion string, filter *WatchFilter) []WatchEvent {
+	if resourceVersion != "" {
+		start := -1
+		if resourceVersion == w.startRV && !w.truncated {
+			start = 0
+		} else {
+			for i := len(w.history) - 1; i >= 0; i-- {
+				if w.history[i].rv == resourceVersion {
+					start = i + 1
+					break
+				}
+			}
+		}
+		if start >= 0 {
+			var events []WatchEvent
+			for _, entry := range w.history[start:] {
+				if event, ok := entry.event(filter); ok {
+					events = append(events, event)
+				}
+			}
+			return append(events, WatchEvent{Type: WatchBookmark, ResourceVersion: w.lastRV})
+		}
+	}
+
+	var events []WatchEvent
+	if resourceVersion != "" {
+		events = append(events, WatchEvent{Type: WatchReset, ResourceVersion: w.lastRV})
+	}
+	for _, item := range w.informer.GetStore().List() {
+		obj, ok := item.(*unstructured.Unstructured)
+		if ok && filter.matches(obj) {
+			events = append(events, WatchEvent{Type: WatchAdded, Object: obj, ResourceVersion: obj.GetResourceVersion()})
+		}
+	}
+	return append(events, WatchEvent{Type: WatchBookmark, ResourceVersion: w.lastRV})
+}
+
+// dispatch records an informer event and sends it to the subscribers it
+// matches.
+func (w *sharedWatch) dispatch(typ string, obj, old interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	entry := watchEntry{typ: typ, obj: u, rv: u.GetResourceVersion()}
+	entry.old, _ = old.(*unstructured.Unstructured)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.history = append(w.history, entry)
+	if len(w.history) > watchHistorySize {
+		w.history = w.history[len(w.history)-watchHistorySize:]
+		w.truncated = true
+	}
+	w.lastRV = entry.rv
+
+	for sub := range w.subscribers {
+		event, ok := entry.event(&sub.filter)
+		if !ok {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			w.remove(sub)
+		}
+	}
+}
+
+// event returns the event a subscriber with filter sees. An object that
+// stops or starts matching the filter is reported as deleted or added, like
+// the API server does for selector watches.
+func (e *watchEntry) event(filter *WatchFilter) (WatchEvent, bool) {
+	event := WatchEvent{Type: e.typ, Object: e.obj, ResourceVersion: e.rv}
+	if e.typ != WatchModified || e.old == nil {
+		return event, filter.matches(e.obj)
+	}
+	matches, matched := filter.matches(e.obj), filter.matches(e.old)
+	switch {
+	case matches && matched:
+	case matches:
+		event.Type = WatchAdded
+	case matched:
+		event.Type = WatchDeleted
+	default:
+		return event, false
+	}
+	return event, true
+}
+
+// watchTransform trims objects before they are stored, like the lists of the
+// resource handlers.
+func watchTransform(obj interface{}) (interface{}, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		u.SetManagedFields(nil)
+		if annotations := u.GetAnnotations(); annotations != nil {
+			if _, ok := annotations[common.KubectlAnnotation]; ok {
+				delete(annotations, common.KubectlAnnotation)
+				u.SetAnnotations(annotations)
+			}
+		}
+	}
+	return obj, nil
+}
@@ -0,0 +1,138 @@
+package kube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+var configMapsGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+func newConfigMap(namespace, name, rv string, labels map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetResourceVersion(rv)
+	obj.SetLabels(labels)
+	return obj
+}
+
+func nextEvent(t *testing.T, sub *Subscription) WatchEvent {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events:
+		require.True(t, ok, "subscription closed")
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a watch event")
+		return WatchEvent{}
+	}
+}
+
+func TestWatchHub(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configMapsGVR: "ConfigMapList"},
+		newConfigMap("shop", "api", "1", map[string]string{"app": "api"}),
+		newConfigMap("shop", "web", "2", map[string]string{"app": "web"}),
+		newConfigMap("other", "api", "3", map[string]string{"app": "api"}),
+	)
+	hub := NewWatchHub(ctx, client, nil)
+	cms := client.Resource(configMapsGVR)
+
+	sub, err := hub.Subscribe(ctx, configMapsGVR, "", WatchFilter{
+		Namespaces: []string{"shop"},
+		Labels:     labels.SelectorFromSet(labels.Set{"app": "api"}),
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+	require.Len(t, sub.Initial, 2)
+	assert.Equal(t, WatchAdded, sub.Initial[0].Type)
+	assert.Equal(t, "api", sub.Initial[0].Object.GetName())
+	assert.Equal(t, WatchBookmark, sub.Initial[1].Type)
+
+	assert.Contains(t, hub.watches, watchKey{gvr: configMapsGVR, namespace: "shop"}, "a single namespace is watched alone")
+
+	// subscribers share the informer of their namespaces
+	other, err := hub.Subscribe(ctx, configMapsGVR, "", WatchFilter{})
+	require.NoError(t, err)
+	assert.Len(t, other.Initial, 4)
+	again, err := hub.Subscribe(ctx, configMapsGVR, "", WatchFilter{Namespaces: []string{"shop", "other"}})
+	require.NoError(t, err)
+	assert.Len(t, hub.watches, 2)
+	assert.Same(t, other.watch, again.watch)
+	other.Close()
+	again.Close()
+
+	_, err = cms.Namespace("shop").Create(ctx, newConfigMap("shop", "api-2", "10", map[string]string{"app": "api"}), metav1.CreateOptions{})
+	require.NoError(t, err)
+	event := nextEvent(t, sub)
+	assert.Equal(t, WatchAdded, event.Type)
+	assert.Equal(t, "api-2", event.Object.GetName())
+
+	// relabeling out of the selector is a deletion for the subscriber
+	_, err = cms.Namespace("shop").Update(ctx, newConfigMap("shop", "api-2", "11", map[string]string{"app": "web"}), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	event = nextEvent(t, sub)
+	assert.Equal(t, WatchDeleted, event.Type)
+	assert.Equal(t, "11", event.ResourceVersion)
+
+	// resuming replays the events after the resource version
+	resumed, err := hub.Subscribe(ctx, configMapsGVR, "10", WatchFilter{})
+	require.NoError(t, err)
+	defer resumed.Close()
+	require.Len(t, resumed.Initial, 2)
+	assert.Equal(t, WatchModified, resumed.Initial[0].Type)
+	assert.Equal(t, "11", resumed.Initial[0].ResourceVersion)
+	assert.Equal(t, WatchBookmark, resumed.Initial[1].Type)
+	assert.Equal(t, "11", resumed.Initial[1].ResourceVersion)
+
+	// an unknown resource version gets a reset and a new snapshot
+	reset, err := hub.Subscribe(ctx, configMapsGVR, "5", WatchFilter{Fields: fields.OneTermEqualSelector("metadata.namespace", "other")})
+	require.NoError(t, err)
+	defer reset.Close()
+	require.Len(t, reset.Initial, 3)
+	assert.Equal(t, WatchReset, reset.Initial[0].Type)
+	assert.Equal(t, "other", reset.Initial[1].Object.GetNamespace())
+}
+
+func TestWatchHubUncached(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configMapsGVR: "ConfigMapList"},
+		newConfigMap("shop", "api", "1", nil),
+	)
+	hub := NewWatchHub(ctx, client, func(schema.GroupVersionResource) bool { return false })
+
+	filter := WatchFilter{Namespaces: []string{"shop"}}
+	first, err := hub.Subscribe(ctx, configMapsGVR, "", filter)
+	require.NoError(t, err)
+	second, err := hub.Subscribe(ctx, configMapsGVR, "", filter)
+	require.NoError(t, err)
+	assert.NotSame(t, first.watch, second.watch, "uncached resources get a watch per subscriber")
+	assert.Empty(t, hub.watches)
+
+	first.Close()
+	second.Close()
+	assert.Eventually(t, first.watch.informer.IsStopped, 5*time.Second, 10*time.Millisecond, "private watches stop with their subscriber")
+}
+
+func TestWatchHubUnsupported(t *testing.T) {
+	hub := NewWatchHub(context.Background(), nil, nil)
+	_, err := hub.Subscribe(context.Background(), schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}, "", WatchFilter{})
+	assert.ErrorIs(t, err, ErrWatchUnsupported)
+}
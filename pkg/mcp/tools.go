@@ -8,6 +8,7 @@ import (
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/pixelvide/kube-sentinel/pkg/analyzer"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -185,7 +186,10 @@ func (m *MCPServer) handleRunSecurityScan(ctx context.Context, request mcp.CallT
 		return mcp.NewToolResultText(fmt.Sprintf("Error fetching resource: %v", err)), nil
 	}
 
-	results := analyzer.Analyze(ctx, cs.K8sClient, obj)
+	if cs.PromClient != nil {
+		ctx = analyzer.WithVolumeForecaster(ctx, cs.PromClient)
+	}
+	results := analyzer.Analyze(ctx, cs.K8sClient, obj, model.GetDisabledAnalyzers(cs.Name)...)
 	data, err := json.MarshalIndent(results, "", "  ")
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal security results: %w", err)
@@ -18,6 +18,7 @@ type AIProviderProfile struct {
 	IsEnabled         bool         `json:"isEnabled" gorm:"default:true"` // If false, profile is hidden from users
 	AllowUserOverride bool         `json:"allowUserOverride"`             // If true, users can provide their own key
 	AllowedModels     SliceString  `json:"allowedModels"`                 // Comma-separated in DB, array in JSON
+	ManagedBy         string       `json:"managedBy,omitempty" gorm:"type:varchar(20)"`
 }
 
 func (AIProviderProfile) TableName() string {
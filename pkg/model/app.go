@@ -24,6 +24,8 @@ type AppConfig struct {
 	Key   string `gorm:"not null;uniqueIndex:idx_app_key" json:"key"`
 	Value string `json:"value"`
 
+	ManagedBy string `gorm:"type:varchar(20)" json:"managed_by,omitempty"`
+
 	// Relationships
 	App App `gorm:"foreignKey:AppID" json:"app,omitempty"`
 }
@@ -71,6 +73,12 @@ func StartAppConfigRefresher() {
 	}()
 }
 
+// RefreshAppConfigCache reloads the config cache after configs were written
+// without SetAppConfig.
+func RefreshAppConfigCache() {
+	refreshCache()
+}
+
 func refreshCache() {
 	if CurrentApp == nil {
 		return
@@ -147,6 +155,15 @@ func SetAppConfig(appID uint, key, value string) error {
 	return err
 }
 
+// IsAppConfigManaged reports whether the key is owned by the configuration file.
+func IsAppConfigManaged(appID uint, key string) bool {
+	var count int64
+	if err := DB.Model(&AppConfig{}).Where("app_id = ? AND key = ? AND managed_by = ?", appID, key, ManagedByConfig).Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
 func GetAppConfigs(appID uint) ([]AppConfig, error) {
 	var configs []AppConfig
 	if err := DB.Where("app_id = ?", appID).Find(&configs).Error; err != nil {
@@ -1,6 +1,9 @@
 package model
 
-import "github.com/pixelvide/kube-sentinel/pkg/common"
+import (
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"k8s.io/apimachinery/pkg/labels"
+)
 
 type Cluster struct {
 	Model
@@ -12,6 +15,28 @@ type Cluster struct {
 	IsDefault      bool         `json:"is_default" gorm:"type:boolean;default:false"`
 	Enable         bool         `json:"enable" gorm:"type:boolean;default:true"`
 	SkipSystemSync bool         `json:"skip_system_sync" gorm:"type:boolean;default:false"`
+
+	// Labels such as env=prod, region=eu or team=payments. They are used to
+	// filter clusters, to select clusters in RBAC roles and to attach
+	// cluster groups.
+	Labels MapString `json:"labels" gorm:"type:text"`
+
+	// CachePolicy selects the kinds kept in the informer cache. Nil means
+	// common.DefaultCachePolicy.
+	CachePolicy *common.CachePolicy `json:"cache_policy,omitempty" gorm:"type:text"`
+
+	// PrometheusConfig holds authentication, headers and TLS settings for
+	// PrometheusURL. It is stored encrypted.
+	PrometheusConfig *PrometheusConfig `json:"prometheus_config,omitempty" gorm:"type:text"`
+
+	// AlertmanagerURL is discovered in the cluster when empty. The
+	// PrometheusConfig settings also apply to it.
+	AlertmanagerURL string `json:"alertmanager_url,omitempty" gorm:"type:varchar(255)"`
+
+	// Pricing is used for cost allocation reports. Nil disables them.
+	Pricing *CostPricing `json:"pricing,omitempty" gorm:"type:text"`
+
+	ManagedBy string `json:"managed_by,omitempty" gorm:"type:varchar(20)"`
 }
 
 func (Cluster) TableName() string {
@@ -19,6 +44,7 @@ func (Cluster) TableName() string {
 }
 
 func AddCluster(cluster *Cluster) error {
+	defer InvalidateClusterGroupSettings()
 	return DB.Create(cluster).Error
 }
 
@@ -39,10 +65,12 @@ func GetClusterByID(id uint) (*Cluster, error) {
 }
 
 func UpdateCluster(cluster *Cluster, updates map[string]interface{}) error {
+	defer InvalidateClusterGroupSettings()
 	return DB.Model(cluster).Updates(updates).Error
 }
 
 func DeleteCluster(cluster *Cluster) error {
+	defer InvalidateClusterGroupSettings()
 	return DB.Delete(cluster).Error
 }
 
@@ -66,6 +94,21 @@ func ListClusters() ([]*Cluster, error) {
 	return clusters, nil
 }
 
+// ListClustersBySelector returns the clusters whose labels match selector.
+func ListClustersBySelector(selector labels.Selector) ([]*Cluster, error) {
+	clusters, err := ListClusters()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Cluster, 0, len(clusters))
+	for _, cluster := range clusters {
+		if selector.Matches(labels.Set(cluster.Labels)) {
+			result = append(result, cluster)
+		}
+	}
+	return result, nil
+}
+
 func CountClusters() (count int64, err error) {
 	return count, DB.Model(&Cluster{}).Count(&count).Error
 }
@@ -0,0 +1,159 @@
+package model
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+// ClusterGroup is a named set of clusters selected by cluster labels.
+// Settings stored on a group apply to every cluster it selects.
+type ClusterGroup struct {
+	Model
+	Name        string `json:"name" gorm:"type:varchar(100);uniqueIndex;not null"`
+	Description string `json:"description" gorm:"type:text"`
+	// Selector is a Kubernetes label selector, e.g. "env=prod,region in (eu,us)".
+	Selector string `json:"selector" gorm:"type:text"`
+
+	// DisabledAnalyzers lists analyzer names that are skipped for clusters in this group.
+	DisabledAnalyzers SliceString `json:"disabledAnalyzers" gorm:"type:text"`
+	// AlertFilters are Alertmanager matchers, e.g. `severity=~"critical|warning"`,
+	// added to every alert list of the clusters in this group.
+	AlertFilters SliceString `json:"alertFilters" gorm:"type:text"`
+}
+
+func (ClusterGroup) TableName() string {
+	return common.GetAppTableName("k8s_cluster_groups")
+}
+
+// Matches reports whether the group selects a cluster with the given labels.
+// A group with an empty selector matches every cluster.
+func (g *ClusterGroup) Matches(clusterLabels map[string]string) bool {
+	selector, err := labels.Parse(g.Selector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(clusterLabels))
+}
+
+func ListClusterGroups() ([]ClusterGroup, error) {
+	var groups []ClusterGroup
+	if err := DB.Order("name").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func GetClusterGroupByID(id uint) (*ClusterGroup, error) {
+	var group ClusterGroup
+	if err := DB.First(&group, id).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func AddClusterGroup(group *ClusterGroup) error {
+	defer InvalidateClusterGroupSettings()
+	return DB.Create(group).Error
+}
+
+func UpdateClusterGroup(group *ClusterGroup) error {
+	defer InvalidateClusterGroupSettings()
+	return DB.Save(group).Error
+}
+
+func DeleteClusterGroup(id uint) error {
+	defer InvalidateClusterGroupSettings()
+	return DB.Delete(&ClusterGroup{}, id).Error
+}
+
+// GetClusterGroupsForCluster returns the groups whose selector matches the named cluster.
+func GetClusterGroupsForCluster(clusterName string) ([]ClusterGroup, error) {
+	cluster, err := GetClusterByName(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := ListClusterGroups()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]ClusterGroup, 0, len(groups))
+	for _, g := range groups {
+		if g.Matches(cluster.Labels) {
+			result = append(result, g)
+		}
+	}
+	return result, nil
+}
+
+// ClusterGroupSettings are the settings of all groups a cluster belongs to.
+type ClusterGroupSettings struct {
+	DisabledAnalyzers []string
+	AlertFilters      []string
+}
+
+// clusterGroupSettingsTTL bounds how long cached settings miss changes made
+// by another replica.
+const clusterGroupSettingsTTL = time.Minute
+
+var clusterGroupSettings struct {
+	sync.RWMutex
+	byCluster map[string]ClusterGroupSettings
+	loadedAt  time.Time
+}
+
+// GetClusterGroupSettings returns the settings of the groups the cluster
+// belongs to. The settings of all clusters are loaded at once and cached
+// until groups or clusters change.
+func GetClusterGroupSettings(clusterName string) ClusterGroupSettings {
+	clusterGroupSettings.RLock()
+	byCluster, loadedAt := clusterGroupSettings.byCluster, clusterGroupSettings.loadedAt
+	clusterGroupSettings.RUnlock()
+	if byCluster != nil && time.Since(loadedAt) < clusterGroupSettingsTTL {
+		return byCluster[clusterName]
+	}
+
+	clusters, err := ListClusters()
+	if err != nil {
+		klog.Warningf("Failed to load clusters for cluster group settings: %v", err)
+		return ClusterGroupSettings{}
+	}
+	groups, err := ListClusterGroups()
+	if err != nil {
+		klog.Warningf("Failed to load cluster groups: %v", err)
+		return ClusterGroupSettings{}
+	}
+	byCluster = make(map[string]ClusterGroupSettings, len(clusters))
+	for _, cluster := range clusters {
+		var settings ClusterGroupSettings
+		for _, g := range groups {
+			if g.Matches(cluster.Labels) {
+				settings.DisabledAnalyzers = append(settings.DisabledAnalyzers, g.DisabledAnalyzers...)
+				settings.AlertFilters = append(settings.AlertFilters, g.AlertFilters...)
+			}
+		}
+		byCluster[cluster.Name] = settings
+	}
+
+	clusterGroupSettings.Lock()
+	clusterGroupSettings.byCluster, clusterGroupSettings.loadedAt = byCluster, time.Now()
+	clusterGroupSettings.Unlock()
+	return byCluster[clusterName]
+}
+
+// InvalidateClusterGroupSettings drops the cached group settings. Cluster and
+// group writes of this package call it; writes made in a transaction, such as
+// imports, call it once committed.
+func InvalidateClusterGroupSettings() {
+	clusterGroupSettings.Lock()
+	clusterGroupSettings.byCluster = nil
+	clusterGroupSettings.Unlock()
+}
+
+// GetDisabledAnalyzers returns the analyzers disabled by any group the cluster belongs to.
+func GetDisabledAnalyzers(clusterName string) []string {
+	return GetClusterGroupSettings(clusterName).DisabledAnalyzers
+}
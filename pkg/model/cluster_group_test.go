@@ -0,0 +1,41 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterGroupSettings(t *testing.T) {
+	common.DBType = "sqlite"
+	common.DBDSN = "file::memory:?cache=shared"
+	model.InitDB()
+
+	prod := &model.Cluster{Name: "groups-prod", Config: "c", Labels: model.MapString{"env": "prod"}}
+	require.NoError(t, model.AddCluster(prod))
+	require.NoError(t, model.AddCluster(&model.Cluster{Name: "groups-dev", Config: "c", Labels: model.MapString{"env": "dev"}}))
+	group := &model.ClusterGroup{
+		Name:              "groups-prod",
+		Selector:          "env=prod",
+		DisabledAnalyzers: []string{"VolumeUsage"},
+		AlertFilters:      []string{`severity="critical"`},
+	}
+	require.NoError(t, model.AddClusterGroup(group))
+
+	settings := model.GetClusterGroupSettings("groups-prod")
+	assert.Equal(t, []string{"VolumeUsage"}, settings.DisabledAnalyzers)
+	assert.Equal(t, []string{`severity="critical"`}, settings.AlertFilters)
+	assert.Empty(t, model.GetDisabledAnalyzers("groups-dev"))
+
+	// cached settings follow label and group changes
+	require.NoError(t, model.UpdateCluster(prod, map[string]interface{}{"labels": model.MapString{"env": "staging"}}))
+	assert.Empty(t, model.GetDisabledAnalyzers("groups-prod"))
+	group.Selector = "env in (staging,dev)"
+	require.NoError(t, model.UpdateClusterGroup(group))
+	assert.Equal(t, []string{"VolumeUsage"}, model.GetDisabledAnalyzers("groups-dev"))
+	require.NoError(t, model.DeleteClusterGroup(group.ID))
+	assert.Empty(t, model.GetDisabledAnalyzers("groups-dev"))
+}
@@ -0,0 +1,33 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterCachePolicy(t *testing.T) {
+	common.DBType = "sqlite"
+	common.DBDSN = "file::memory:?cache=shared"
+	model.InitDB()
+
+	withDefault := &model.Cluster{Name: "cache-default", Config: "c"}
+	assert.NoError(t, model.AddCluster(withDefault))
+	got, err := model.GetClusterByName("cache-default")
+	assert.NoError(t, err)
+	assert.Nil(t, got.CachePolicy)
+
+	policy := &common.CachePolicy{
+		Cached:             []string{"Pod", "Deployment.apps"},
+		Uncached:           []string{"Secret"},
+		StripManagedFields: true,
+		MaxAnnotationBytes: 4096,
+	}
+	withPolicy := &model.Cluster{Name: "cache-custom", Config: "c", CachePolicy: policy}
+	assert.NoError(t, model.AddCluster(withPolicy))
+	got, err = model.GetClusterByName("cache-custom")
+	assert.NoError(t, err)
+	assert.Equal(t, policy, got.CachePolicy)
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// CostPricing holds the prices used to allocate the cost of a cluster. CPU
+// and memory are priced per hour, storage and load balancers per month.
+type CostPricing struct {
+	// Currency is a display label such as "USD"
+	Currency          string  `json:"currency,omitempty"`
+	CPUCoreHour       float64 `json:"cpuCoreHour"`
+	MemoryGiBHour     float64 `json:"memoryGiBHour"`
+	StorageGiBMonth   float64 `json:"storageGiBMonth"`
+	LoadBalancerMonth float64 `json:"loadBalancerMonth"`
+}
+
+// IsZero reports whether no price is configured.
+func (p *CostPricing) IsZero() bool {
+	return p == nil || *p == CostPricing{}
+}
+
+// Validate rejects negative prices.
+func (p *CostPricing) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.CPUCoreHour < 0 || p.MemoryGiBHour < 0 || p.StorageGiBMonth < 0 || p.LoadBalancerMonth < 0 {
+		return fmt.Errorf("prices must not be negative")
+	}
+	return nil
+}
+
+func (p *CostPricing) Scan(value interface{}) error {
+	return scanJSON(value, p)
+}
+
+func (p CostPricing) Value() (driver.Value, error) {
+	return valueJSON(p)
+}
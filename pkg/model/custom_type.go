@@ -2,6 +2,7 @@ package model
 
 import (
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -112,3 +113,43 @@ func (s SliceString) Value() (driver.Value, error) {
 	}
 	return strings.Join(s, ","), nil
 }
+
+// MapString stores a string map (e.g. labels) as a JSON object.
+type MapString map[string]string
+
+func (m *MapString) Scan(value interface{}) error {
+	if value == nil {
+		*m = nil
+		return nil
+	}
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into MapString", value)
+	}
+	if len(data) == 0 {
+		*m = MapString{}
+		return nil
+	}
+	result := MapString{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("cannot unmarshal MapString: %w", err)
+	}
+	*m = result
+	return nil
+}
+
+func (m MapString) Value() (driver.Value, error) {
+	if m == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(map[string]string(m))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/pixelvide/kube-sentinel/pkg/utils"
@@ -186,6 +187,71 @@ func TestSecretString_Value(t *testing.T) {
 	}
 }
 
+func TestMapString_ScanValue(t *testing.T) {
+	var m MapString
+	if err := m.Scan(`{"env":"prod","team":"payments"}`); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if m["env"] != "prod" || m["team"] != "payments" {
+		t.Errorf("Scan() got = %v", m)
+	}
+
+	val, err := m.Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+	var roundTrip MapString
+	if err := roundTrip.Scan(val); err != nil {
+		t.Fatalf("Scan() round trip error = %v", err)
+	}
+	if len(roundTrip) != 2 || roundTrip["env"] != "prod" {
+		t.Errorf("round trip got = %v", roundTrip)
+	}
+
+	if err := m.Scan(""); err != nil || len(m) != 0 {
+		t.Errorf("Scan(\"\") got = %v, err = %v", m, err)
+	}
+	if err := m.Scan(123); err == nil {
+		t.Errorf("Scan(123) expected error")
+	}
+	if val, _ := MapString(nil).Value(); val != "" {
+		t.Errorf("nil Value() got = %v, want empty string", val)
+	}
+}
+
+func TestPrometheusConfig_ScanValue(t *testing.T) {
+	cfg := PrometheusConfig{
+		AuthType: PrometheusAuthBasic,
+		Username: "user",
+		Password: "s3cret",
+		Headers:  map[string]string{"X-Scope-OrgID": "tenant-a"},
+	}
+	val, err := cfg.Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+	if s, _ := val.(string); s == "" || strings.Contains(s, "s3cret") {
+		t.Errorf("Value() is not encrypted: %v", val)
+	}
+
+	var roundTrip PrometheusConfig
+	if err := roundTrip.Scan(val); err != nil {
+		t.Fatalf("Scan() round trip error = %v", err)
+	}
+	if roundTrip.Password != "s3cret" || roundTrip.Headers["X-Scope-OrgID"] != "tenant-a" {
+		t.Errorf("round trip got = %+v", roundTrip)
+	}
+
+	// Unencrypted values are read as is
+	var plain PrometheusConfig
+	if err := plain.Scan(`{"authType":"bearer","bearerToken":"t"}`); err != nil || plain.BearerToken != "t" {
+		t.Errorf("Scan(plaintext) got = %+v, err = %v", plain, err)
+	}
+	if !(&PrometheusConfig{}).IsZero() || cfg.IsZero() {
+		t.Errorf("IsZero() mismatch")
+	}
+}
+
 func equalSliceString(a, b SliceString) bool {
 	if len(a) != len(b) {
 		return false
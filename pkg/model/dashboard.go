@@ -0,0 +1,171 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+)
+
+// Panel visualization types
+const (
+	PanelLine  = "line"
+	PanelArea  = "area"
+	PanelBar   = "bar"
+	PanelStat  = "stat"
+	PanelGauge = "gauge"
+	PanelTable = "table"
+)
+
+// MaxDashboardPanels limits the number of queries one render runs.
+const MaxDashboardPanels = 50
+
+var (
+	panelTypes        = []string{PanelLine, PanelArea, PanelBar, PanelStat, PanelGauge, PanelTable}
+	variableNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Dashboard is a saved set of metric panels. It is visible to its owner, to
+// users with one of SharedRoles and to admins.
+type Dashboard struct {
+	Model
+	Name        string             `json:"name" gorm:"type:varchar(255);not null"`
+	Description string             `json:"description" gorm:"type:text"`
+	OwnerID     uint               `json:"ownerId" gorm:"index;not null"`
+	Owner       string             `json:"owner" gorm:"type:varchar(255)"`
+	SharedRoles SliceString        `json:"sharedRoles" gorm:"type:text"`
+	Variables   DashboardVariables `json:"variables" gorm:"type:text"`
+	Panels      DashboardPanels    `json:"panels" gorm:"type:text"`
+}
+
+func (Dashboard) TableName() string {
+	return common.GetAppTableName("dashboards")
+}
+
+// DashboardPanel is a chart of one PromQL query. The query may reference the
+// dashboard variables as $name or ${name}.
+type DashboardPanel struct {
+	Title string `json:"title"`
+	Query string `json:"query"`
+	Type  string `json:"type"`
+	// Unit is a display hint such as "bytes", "cores" or "percent".
+	Unit string `json:"unit,omitempty"`
+	// Legend is a template for series names, e.g. "{{pod}}".
+	Legend string `json:"legend,omitempty"`
+	Width  int    `json:"width,omitempty"`
+}
+
+// DashboardVariable is a value substituted into the panel queries, such as
+// $namespace or $pod.
+type DashboardVariable struct {
+	Name    string   `json:"name"`
+	Label   string   `json:"label,omitempty"`
+	Default string   `json:"default,omitempty"`
+	Options []string `json:"options,omitempty"`
+}
+
+type DashboardPanels []DashboardPanel
+
+func (p *DashboardPanels) Scan(value interface{}) error {
+	return scanJSON(value, p)
+}
+
+func (p DashboardPanels) Value() (driver.Value, error) {
+	return valueJSON(p)
+}
+
+type DashboardVariables []DashboardVariable
+
+func (v *DashboardVariables) Scan(value interface{}) error {
+	return scanJSON(value, v)
+}
+
+func (v DashboardVariables) Value() (driver.Value, error) {
+	return valueJSON(v)
+}
+
+func scanJSON(value interface{}, out interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into %T", value, out)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+func valueJSON(v interface{}) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Validate checks the name, panels and variables of the dashboard.
+func (d *Dashboard) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(d.Panels) > MaxDashboardPanels {
+		return fmt.Errorf("a dashboard can have at most %d panels", MaxDashboardPanels)
+	}
+	for i, panel := range d.Panels {
+		if panel.Query == "" {
+			return fmt.Errorf("panel %d: query is required", i)
+		}
+		if !slices.Contains(panelTypes, panel.Type) {
+			return fmt.Errorf("panel %d: type must be one of %v", i, panelTypes)
+		}
+	}
+	names := map[string]bool{}
+	for _, variable := range d.Variables {
+		if !variableNameRegex.MatchString(variable.Name) {
+			return fmt.Errorf("invalid variable name %q", variable.Name)
+		}
+		if names[variable.Name] {
+			return fmt.Errorf("duplicate variable %q", variable.Name)
+		}
+		names[variable.Name] = true
+	}
+	return nil
+}
+
+func ListDashboards() ([]Dashboard, error) {
+	var dashboards []Dashboard
+	if err := DB.Order("name").Find(&dashboards).Error; err != nil {
+		return nil, err
+	}
+	return dashboards, nil
+}
+
+func GetDashboardByID(id uint) (*Dashboard, error) {
+	var dashboard Dashboard
+	if err := DB.First(&dashboard, id).Error; err != nil {
+		return nil, err
+	}
+	return &dashboard, nil
+}
+
+func AddDashboard(dashboard *Dashboard) error {
+	return DB.Create(dashboard).Error
+}
+
+func UpdateDashboard(dashboard *Dashboard) error {
+	return DB.Save(dashboard).Error
+}
+
+func DeleteDashboard(id uint) error {
+	return DB.Delete(&Dashboard{}, id).Error
+}
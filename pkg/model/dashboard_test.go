@@ -0,0 +1,64 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboard(t *testing.T) {
+	common.DBType = "sqlite"
+	common.DBDSN = "file::memory:?cache=shared"
+	model.InitDB()
+
+	dashboard := &model.Dashboard{
+		Name:        "payments",
+		OwnerID:     1,
+		SharedRoles: []string{"payments-team"},
+		Variables:   model.DashboardVariables{{Name: "namespace", Default: "payments"}},
+		Panels: model.DashboardPanels{
+			{Title: "CPU", Query: `sum(rate(container_cpu_usage_seconds_total{namespace="$namespace"}[5m]))`, Type: model.PanelLine, Unit: "cores"},
+		},
+	}
+	require.NoError(t, dashboard.Validate())
+	require.NoError(t, model.AddDashboard(dashboard))
+
+	got, err := model.GetDashboardByID(dashboard.ID)
+	require.NoError(t, err)
+	assert.Equal(t, dashboard.Panels, got.Panels)
+	assert.Equal(t, dashboard.Variables, got.Variables)
+	assert.Equal(t, dashboard.SharedRoles, got.SharedRoles)
+
+	require.NoError(t, model.DeleteDashboard(dashboard.ID))
+	_, err = model.GetDashboardByID(dashboard.ID)
+	assert.Error(t, err)
+}
+
+func TestDashboardValidate(t *testing.T) {
+	panel := model.DashboardPanel{Query: "up", Type: model.PanelStat}
+	tests := []struct {
+		name      string
+		dashboard model.Dashboard
+		wantErr   bool
+	}{
+		{"valid", model.Dashboard{Name: "d", Panels: model.DashboardPanels{panel}}, false},
+		{"no name", model.Dashboard{Panels: model.DashboardPanels{panel}}, true},
+		{"no query", model.Dashboard{Name: "d", Panels: model.DashboardPanels{{Type: model.PanelLine}}}, true},
+		{"unknown type", model.Dashboard{Name: "d", Panels: model.DashboardPanels{{Query: "up", Type: "pie"}}}, true},
+		{"invalid variable", model.Dashboard{Name: "d", Variables: model.DashboardVariables{{Name: "name-space"}}}, true},
+		{"duplicate variable", model.Dashboard{Name: "d", Variables: model.DashboardVariables{{Name: "pod"}, {Name: "pod"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.dashboard.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"errors"
 	"log"
 	"os"
 	"strings"
@@ -24,6 +25,13 @@ var (
 	once sync.Once
 )
 
+// ManagedByConfig marks records owned by the declarative configuration file.
+// They are read-only in the admin APIs.
+const ManagedByConfig = "config"
+
+// ErrConfigManaged is returned when a config-managed record is modified.
+var ErrConfigManaged = errors.New("this record is managed by the configuration file and is read-only")
+
 type Model struct {
 	ID        uint      `json:"id" gorm:"primarykey"`
 	CreatedAt time.Time `json:"createdAt"`
@@ -116,14 +124,20 @@ func InitDB() {
 		AppUser{},
 		UserGitlabConfig{},
 		UserAWSConfig{},
+		UserGCPConfig{},
+		UserAzureConfig{},
+		UserOIDCConfig{},
 
 		Cluster{},
+		ClusterGroup{},
 		ClusterKnowledgeBase{},
 
 		OAuthProvider{},
 		Role{},
 		RoleAssignment{},
 		ResourceTemplate{},
+		Dashboard{},
+		SLO{},
 
 		AuditLog{},
 
@@ -18,6 +18,7 @@ type OAuthProvider struct {
 	Scopes       string          `json:"scopes" gorm:"type:varchar(255);default:'openid,profile,email'"`
 	Issuer       string          `json:"issuer" gorm:"type:varchar(255)"`
 	Enabled      bool            `json:"enabled" gorm:"type:boolean;default:true"`
+	ManagedBy    string          `json:"managedBy,omitempty" gorm:"type:varchar(20)"`
 
 	// Auto-generated redirect URL
 	RedirectURL string `json:"-" gorm:"-"`
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pixelvide/kube-sentinel/pkg/utils"
+)
+
+const (
+	PrometheusAuthBasic  = "basic"
+	PrometheusAuthBearer = "bearer"
+	PrometheusAuthOAuth2 = "oauth2"
+)
+
+// PrometheusConfig holds the connection settings of the Prometheus (or
+// Thanos, Mimir, Cortex) of a cluster. It carries credentials, so the whole
+// value is stored encrypted like SecretString.
+type PrometheusConfig struct {
+	// AuthType is empty, "basic", "bearer" or "oauth2"
+	AuthType    string            `json:"authType,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	Password    string            `json:"password,omitempty"`
+	BearerToken string            `json:"bearerToken,omitempty"`
+	OAuth2      *PrometheusOAuth2 `json:"oauth2,omitempty"`
+
+	// Headers are added to every request, e.g. X-Scope-OrgID for multi-tenant backends
+	Headers map[string]string `json:"headers,omitempty"`
+
+	TLS *PrometheusTLS `json:"tls,omitempty"`
+}
+
+// PrometheusOAuth2 configures the OAuth2 client credentials grant.
+type PrometheusOAuth2 struct {
+	ClientID       string            `json:"clientId"`
+	ClientSecret   string            `json:"clientSecret"`
+	TokenURL       string            `json:"tokenUrl"`
+	Scopes         []string          `json:"scopes,omitempty"`
+	EndpointParams map[string]string `json:"endpointParams,omitempty"`
+}
+
+// PrometheusTLS holds PEM encoded certificates for the Prometheus connection.
+type PrometheusTLS struct {
+	CAData             string `json:"caData,omitempty"`
+	CertData           string `json:"certData,omitempty"`
+	KeyData            string `json:"keyData,omitempty"`
+	ServerName         string `json:"serverName,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+// IsZero reports whether no setting is configured.
+func (p *PrometheusConfig) IsZero() bool {
+	return p == nil || (p.AuthType == "" && len(p.Headers) == 0 && p.TLS == nil)
+}
+
+func (p *PrometheusConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	var data string
+	switch v := value.(type) {
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into PrometheusConfig", value)
+	}
+	if data == "" {
+		return nil
+	}
+	if decrypted, err := utils.DecryptString(data); err == nil {
+		data = decrypted
+	}
+	return json.Unmarshal([]byte(data), p)
+}
+
+func (p PrometheusConfig) Value() (driver.Value, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	encrypted := utils.EncryptString(string(data))
+	if len(encrypted) > 17 && encrypted[:17] == "encryption_error:" {
+		return nil, fmt.Errorf("encryption failed: %s", encrypted[17:])
+	}
+	return encrypted, nil
+}
@@ -10,10 +10,13 @@ type Role struct {
 	IsSystem    bool   `json:"isSystem" gorm:"type:boolean;not null;default:false"`
 
 	// Rules
-	Clusters   SliceString `json:"clusters" gorm:"type:text"`
-	Resources  SliceString `json:"resources" gorm:"type:text"`
-	Namespaces SliceString `json:"namespaces" gorm:"type:text"`
-	Verbs      SliceString `json:"verbs" gorm:"type:text"`
+	Clusters        SliceString `json:"clusters" gorm:"type:text"`
+	ClusterSelector string      `json:"clusterSelector" gorm:"type:text"`
+	Resources       SliceString `json:"resources" gorm:"type:text"`
+	Namespaces      SliceString `json:"namespaces" gorm:"type:text"`
+	Verbs           SliceString `json:"verbs" gorm:"type:text"`
+
+	ManagedBy string `json:"managedBy,omitempty" gorm:"type:varchar(20)"`
 
 	Assignments []RoleAssignment `json:"assignments" gorm:"foreignKey:RoleID;constraint:OnDelete:CASCADE"`
 }
@@ -31,6 +34,8 @@ type RoleAssignment struct {
 
 	SubjectType string `json:"subjectType" gorm:"type:varchar(20);not null;index:idx_role_assignments_subject,priority:2"`
 	Subject     string `json:"subject" gorm:"type:varchar(255);not null;index:idx_role_assignments_subject,priority:1"`
+
+	ManagedBy string `json:"managedBy,omitempty" gorm:"type:varchar(20)"`
 }
 
 func (RoleAssignment) TableName() string {
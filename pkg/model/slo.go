@@ -0,0 +1,140 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+	prommodel "github.com/prometheus/common/model"
+)
+
+// SLO types
+const (
+	// SLOTypeRatio measures GoodQuery / TotalQuery.
+	SLOTypeRatio = "ratio"
+	// SLOTypeAvailability measures the share of time the target had at least
+	// the fraction Threshold of its desired replicas (workloads) or of its
+	// endpoints (services) ready.
+	SLOTypeAvailability = "availability"
+	// SLOTypeLatency measures the share of requests of the histogram Metric
+	// that completed within Threshold seconds.
+	SLOTypeLatency = "latency"
+)
+
+// SLO target kinds
+var sloTargetKinds = []string{"Service", "Deployment", "StatefulSet", "DaemonSet"}
+
+// SLO is a service level objective of a Service or workload.
+type SLO struct {
+	Model
+	Cluster     string `json:"cluster" gorm:"type:varchar(100);not null;uniqueIndex:idx_slo_name,priority:1"`
+	Namespace   string `json:"namespace" gorm:"type:varchar(255);not null;uniqueIndex:idx_slo_name,priority:2"`
+	Name        string `json:"name" gorm:"type:varchar(255);not null;uniqueIndex:idx_slo_name,priority:3"`
+	Description string `json:"description" gorm:"type:text"`
+
+	TargetKind string `json:"targetKind" gorm:"type:varchar(50)"`
+	TargetName string `json:"targetName" gorm:"type:varchar(255)"`
+
+	Type string `json:"type" gorm:"type:varchar(20);not null"`
+	// GoodQuery and TotalQuery are the PromQL of a ratio SLO. They use
+	// $__range as the range of their range selectors, e.g.
+	// sum(rate(http_requests_total{code!~"5.."}[$__range])).
+	GoodQuery  string `json:"goodQuery,omitempty" gorm:"type:text"`
+	TotalQuery string `json:"totalQuery,omitempty" gorm:"type:text"`
+	// Metric is the histogram of a latency SLO with an optional selector,
+	// e.g. http_request_duration_seconds{service="api"}.
+	Metric    string  `json:"metric,omitempty" gorm:"type:text"`
+	Threshold float64 `json:"threshold,omitempty"`
+
+	// Objective is the target in percent, e.g. 99.9
+	Objective float64 `json:"objective" gorm:"not null"`
+	// Window is the compliance period, e.g. "30d"
+	Window string `json:"window" gorm:"type:varchar(20);not null"`
+
+	CreatedBy string `json:"createdBy" gorm:"type:varchar(255)"`
+}
+
+func (SLO) TableName() string {
+	return common.GetAppTableName("slos")
+}
+
+// Validate checks the fields required by the type of the SLO.
+func (s *SLO) Validate() error {
+	if s.Name == "" || s.Namespace == "" {
+		return fmt.Errorf("name and namespace are required")
+	}
+	if s.Objective <= 0 || s.Objective >= 100 {
+		return fmt.Errorf("objective must be between 0 and 100 percent")
+	}
+	if d, err := prommodel.ParseDuration(s.Window); err != nil || d <= 0 {
+		return fmt.Errorf("invalid window %q, e.g. 30d", s.Window)
+	}
+	if s.TargetKind != "" && !slices.Contains(sloTargetKinds, s.TargetKind) {
+		return fmt.Errorf("targetKind must be one of %v", sloTargetKinds)
+	}
+	if (s.TargetKind == "") != (s.TargetName == "") {
+		return fmt.Errorf("targetKind and targetName must be set together")
+	}
+	switch s.Type {
+	case SLOTypeRatio:
+		if s.GoodQuery == "" || s.TotalQuery == "" {
+			return fmt.Errorf("goodQuery and totalQuery are required for a ratio SLO")
+		}
+	case SLOTypeAvailability:
+		if s.TargetKind == "" {
+			return fmt.Errorf("targetKind and targetName are required for an availability SLO")
+		}
+		if s.Threshold < 0 || s.Threshold > 1 {
+			return fmt.Errorf("threshold must be a fraction between 0 and 1 for an availability SLO")
+		}
+	case SLOTypeLatency:
+		if s.Metric == "" || s.Threshold <= 0 {
+			return fmt.Errorf("metric and a positive threshold in seconds are required for a latency SLO")
+		}
+	default:
+		return fmt.Errorf("type must be one of: ratio, availability, latency")
+	}
+	return nil
+}
+
+// ListSLOs returns the SLOs of a cluster, of one namespace when namespace is
+// not empty.
+func ListSLOs(cluster, namespace string) ([]SLO, error) {
+	var slos []SLO
+	query := DB.Where("cluster = ?", cluster)
+	if namespace != "" {
+		query = query.Where("namespace = ?", namespace)
+	}
+	if err := query.Order("namespace, name").Find(&slos).Error; err != nil {
+		return nil, err
+	}
+	return slos, nil
+}
+
+// ListTargetSLOs returns the SLOs of a Service or workload.
+func ListTargetSLOs(cluster, namespace, kind, name string) ([]SLO, error) {
+	var slos []SLO
+	err := DB.Where("cluster = ? AND namespace = ? AND target_kind = ? AND target_name = ?", cluster, namespace, kind, name).
+		Order("name").Find(&slos).Error
+	return slos, err
+}
+
+func GetSLOByID(id uint) (*SLO, error) {
+	var slo SLO
+	if err := DB.First(&slo, id).Error; err != nil {
+		return nil, err
+	}
+	return &slo, nil
+}
+
+func AddSLO(slo *SLO) error {
+	return DB.Create(slo).Error
+}
+
+func UpdateSLO(slo *SLO) error {
+	return DB.Save(slo).Error
+}
+
+func DeleteSLO(id uint) error {
+	return DB.Delete(&SLO{}, id).Error
+}
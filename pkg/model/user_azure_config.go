@@ -0,0 +1,47 @@
+package model
+
+import (
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+)
+
+const (
+	AzureLoginModeSPN        = "spn"
+	AzureLoginModeDeviceCode = "devicecode"
+)
+
+// UserAzureConfig stores a user's credentials for the Azure kubelogin exec plugin.
+// With LoginMode "spn" the service principal is passed to kubelogin; with
+// "devicecode" kubelogin reuses the token cached by a completed device login.
+type UserAzureConfig struct {
+	Model
+	UserID       uint         `json:"user_id" gorm:"uniqueIndex:idx_user_azure_config_user_id;not null"`
+	LoginMode    string       `json:"login_mode" gorm:"type:varchar(20);not null;default:'spn'"`
+	TenantID     string       `json:"tenant_id" gorm:"type:varchar(100)"`
+	ClientID     string       `json:"client_id" gorm:"type:varchar(100)"`
+	ClientSecret SecretString `json:"client_secret" gorm:"type:text"`
+
+	// Relationships
+	User User `json:"user" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (UserAzureConfig) TableName() string {
+	return common.GetAppTableName("user_azure_configs")
+}
+
+// GetUserAzureConfig retrieves the user Azure config for a given user ID.
+func GetUserAzureConfig(userID uint) (*UserAzureConfig, error) {
+	var config UserAzureConfig
+	if err := DB.Where("user_id = ?", userID).First(&config).Error; err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// GetUserAzureConfigs retrieves the user Azure configs of the given users.
+func GetUserAzureConfigs(userIDs []uint) ([]UserAzureConfig, error) {
+	var configs []UserAzureConfig
+	if err := DB.Where("user_id IN ?", userIDs).Find(&configs).Error; err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
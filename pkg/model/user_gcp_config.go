@@ -0,0 +1,29 @@
+package model
+
+import (
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+)
+
+// UserGCPConfig stores a user's Google service account key used by
+// gke-gcloud-auth-plugin as application default credentials.
+type UserGCPConfig struct {
+	Model
+	UserID             uint         `json:"user_id" gorm:"uniqueIndex:idx_user_gcp_config_user_id;not null"`
+	ServiceAccountJSON SecretString `json:"service_account_json" gorm:"type:text"`
+
+	// Relationships
+	User User `json:"user" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (UserGCPConfig) TableName() string {
+	return common.GetAppTableName("user_gcp_configs")
+}
+
+// GetUserGCPConfig retrieves the user GCP config for a given user ID.
+func GetUserGCPConfig(userID uint) (*UserGCPConfig, error) {
+	var config UserGCPConfig
+	if err := DB.Where("user_id = ?", userID).First(&config).Error; err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"github.com/pixelvide/kube-sentinel/pkg/common"
+)
+
+// UserOIDCConfig stores a user's credentials for clusters that use the kubectl
+// oidc-login exec plugin.
+// The issuer URL and client ID come from the cluster kubeconfig; the user
+// supplies the password-grant credentials and an optional client secret.
+type UserOIDCConfig struct {
+	Model
+	UserID       uint         `json:"user_id" gorm:"uniqueIndex:idx_user_oidc_config_user_id;not null"`
+	Username     string       `json:"username" gorm:"type:varchar(255)"`
+	Password     SecretString `json:"password" gorm:"type:text"`
+	ClientSecret SecretString `json:"client_secret" gorm:"type:text"`
+
+	// Relationships
+	User User `json:"user" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (UserOIDCConfig) TableName() string {
+	return common.GetAppTableName("user_oidc_configs")
+}
+
+// GetUserOIDCConfig retrieves the user OIDC config for a given user ID.
+func GetUserOIDCConfig(userID uint) (*UserOIDCConfig, error) {
+	var config UserOIDCConfig
+	if err := DB.Where("user_id = ?", userID).First(&config).Error; err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// GetUserOIDCConfigs retrieves the user OIDC configs of the given users.
+func GetUserOIDCConfigs(userIDs []uint) ([]UserOIDCConfig, error) {
+	var configs []UserOIDCConfig
+	if err := DB.Where("user_id IN ?", userIDs).Find(&configs).Error; err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
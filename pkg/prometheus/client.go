@@ -348,21 +348,9 @@ func FillMissingDataPoints(timeRange time.Duration, step time.Duration, existing
 
 // GetPodMetrics fetches metrics for a specific pod
 func (c *Client) GetPodMetrics(ctx context.Context, namespace, podName, container string, duration string) (*PodMetrics, error) {
-	var step time.Duration
-	var timeRange time.Duration
-
-	switch duration {
-	case "30m":
-		timeRange = 30 * time.Minute
-		step = 15 * time.Second
-	case "1h":
-		timeRange = 1 * time.Hour
-		step = 1 * time.Minute
-	case "24h":
-		timeRange = 24 * time.Hour
-		step = 5 * time.Minute
-	default:
-		return nil, fmt.Errorf("unsupported duration: %s", duration)
+	timeRange, step, err := metricsRange(duration)
+	if err != nil {
+		return nil, err
 	}
 
 	cpuData, err := c.GetCPUUsage(ctx, namespace, podName, container, timeRange, step)
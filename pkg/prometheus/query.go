@@ -0,0 +1,133 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// Limits of the generic query API
+const (
+	DefaultQueryTimeout = 30 * time.Second
+	MaxQueryTimeout     = 2 * time.Minute
+	// MaxQueryPoints is the maximum number of steps of a range query, the
+	// same limit Prometheus applies.
+	MaxQueryPoints = 11000
+	// MaxQuerySeries and MaxQuerySamples limit the size of a result.
+	MaxQuerySeries  = 10000
+	MaxQuerySamples = 1000000
+)
+
+// NamespaceLabel is the label that scopes series to a namespace.
+const NamespaceLabel = "namespace"
+
+var (
+	ErrInvalidQuery       = errors.New("invalid query")
+	ErrNamespaceForbidden = errors.New("namespace not allowed")
+	ErrResultTooLarge     = errors.New("query result too large")
+)
+
+// QueryResult is the data of a query response.
+type QueryResult struct {
+	ResultType model.ValueType `json:"resultType"`
+	Result     model.Value     `json:"result"`
+	Warnings   []string        `json:"warnings,omitempty"`
+}
+
+// Query runs an instant query.
+func (c *Client) Query(ctx context.Context, query string, ts time.Time, timeout time.Duration) (*QueryResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, warnings, err := c.client.Query(ctx, query, ts, v1.WithTimeout(timeout))
+	if err != nil {
+		return nil, err
+	}
+	return newQueryResult(result, warnings)
+}
+
+// QueryRange runs a range query.
+func (c *Client) QueryRange(ctx context.Context, query string, r v1.Range, timeout time.Duration) (*QueryResult, error) {
+	if points := r.End.Sub(r.Start) / r.Step; points > MaxQueryPoints {
+		return nil, fmt.Errorf("%w: %d points per series exceed the limit of %d, increase the step", ErrResultTooLarge, points, MaxQueryPoints)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, warnings, err := c.client.QueryRange(ctx, query, r, v1.WithTimeout(timeout))
+	if err != nil {
+		return nil, err
+	}
+	return newQueryResult(result, warnings)
+}
+
+func newQueryResult(result model.Value, warnings v1.Warnings) (*QueryResult, error) {
+	series, samples := 0, 0
+	switch v := result.(type) {
+	case model.Vector:
+		series, samples = len(v), len(v)
+	case model.Matrix:
+		series = len(v)
+		for _, s := range v {
+			samples += len(s.Values) + len(s.Histograms)
+		}
+	}
+	if series > MaxQuerySeries {
+		return nil, fmt.Errorf("%w: %d series exceed the limit of %d", ErrResultTooLarge, series, MaxQuerySeries)
+	}
+	if samples > MaxQuerySamples {
+		return nil, fmt.Errorf("%w: %d samples exceed the limit of %d", ErrResultTooLarge, samples, MaxQuerySamples)
+	}
+	return &QueryResult{ResultType: result.Type(), Result: result, Warnings: warnings}, nil
+}
+
+// EnforceNamespaces rewrites query so that every series selector only matches
+// series whose namespace label is one of namespaces. Selectors that ask for a
+// namespace outside of namespaces are rejected with ErrNamespaceForbidden.
+// Series without a namespace label, such as node metrics, never match.
+func EnforceNamespaces(query string, namespaces []string) (string, error) {
+	expr, err := parser.ParseExpr(query)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	if len(namespaces) == 0 {
+		return "", fmt.Errorf("%w: no namespace is accessible", ErrNamespaceForbidden)
+	}
+
+	quoted := make([]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		quoted = append(quoted, regexp.QuoteMeta(ns))
+	}
+	slices.Sort(quoted)
+	matcher, err := labels.NewMatcher(labels.MatchRegexp, NamespaceLabel, strings.Join(quoted, "|"))
+	if err != nil {
+		return "", err
+	}
+
+	var enforceErr error
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		vs, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+		for _, m := range vs.LabelMatchers {
+			if m.Name == NamespaceLabel && m.Type == labels.MatchEqual && m.Value != "" && !slices.Contains(namespaces, m.Value) {
+				enforceErr = fmt.Errorf("%w: %s", ErrNamespaceForbidden, m.Value)
+				return enforceErr
+			}
+		}
+		vs.LabelMatchers = append(vs.LabelMatchers, matcher)
+		return nil
+	})
+	if enforceErr != nil {
+		return "", enforceErr
+	}
+	return expr.String(), nil
+}
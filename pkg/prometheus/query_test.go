@@ -0,0 +1,104 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnforceNamespaces(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "metric name only",
+			query: "up",
+			want:  `up{namespace=~"dev|team\\.a"}`,
+		},
+		{
+			name:  "every selector is restricted",
+			query: `sum by (pod) (rate(container_cpu_usage_seconds_total{container!=""}[5m])) / on (pod) kube_pod_info`,
+			want:  `sum by (pod) (rate(container_cpu_usage_seconds_total{container!="",namespace=~"dev|team\\.a"}[5m])) / on (pod) kube_pod_info{namespace=~"dev|team\\.a"}`,
+		},
+		{
+			name:  "allowed namespace",
+			query: `kube_pod_info{namespace="dev"}`,
+			want:  `kube_pod_info{namespace="dev",namespace=~"dev|team\\.a"}`,
+		},
+		{
+			name:  "regex matcher is intersected",
+			query: `kube_pod_info{namespace=~".+"}`,
+			want:  `kube_pod_info{namespace=~".+",namespace=~"dev|team\\.a"}`,
+		},
+		{
+			name:    "forbidden namespace",
+			query:   `kube_pod_info{namespace="kube-system"}`,
+			wantErr: ErrNamespaceForbidden,
+		},
+		{
+			name:    "forbidden namespace in a subquery",
+			query:   `max_over_time(up{namespace="prod"}[1h:5m])`,
+			wantErr: ErrNamespaceForbidden,
+		},
+		{
+			name:    "invalid query",
+			query:   `sum(`,
+			wantErr: ErrInvalidQuery,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := EnforceNamespaces(tc.query, []string{"team.a", "dev"})
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err := EnforceNamespaces("up", nil)
+	assert.ErrorIs(t, err, ErrNamespaceForbidden)
+}
+
+func TestQueryLimits(t *testing.T) {
+	series := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		results := make([]string, series)
+		for i := range results {
+			results[i] = fmt.Sprintf(`{"metric":{"pod":"p%d"},"value":[1700000000,"1"]}`, i)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[%s]}}`, strings.Join(results, ","))
+	}))
+	defer srv.Close()
+
+	client, err := NewClientWithRoundTripper(srv.URL, http.DefaultTransport)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	series = 2
+	result, err := client.Query(ctx, "up", time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "vector", result.ResultType.String())
+
+	series = MaxQuerySeries + 1
+	_, err = client.Query(ctx, "up", time.Now(), time.Second)
+	assert.ErrorIs(t, err, ErrResultTooLarge)
+
+	now := time.Now()
+	_, err = client.QueryRange(ctx, "up", v1.Range{Start: now.Add(-24 * time.Hour), End: now, Step: time.Second}, time.Second)
+	assert.ErrorIs(t, err, ErrResultTooLarge)
+}
@@ -0,0 +1,230 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// usageStep is the resolution usage is averaged at.
+const usageStep = 5 * time.Minute
+
+// PodUsage is the average resource usage of a pod. CPU is in cores and
+// Memory in bytes.
+type PodUsage struct {
+	CPU    float64
+	Memory float64
+}
+
+// PeriodUsage is the average resources of the pods, claims and load
+// balancers of a cluster over a period. An object counts as zero while it
+// did not exist, so the averages of a month add up to what ran in it.
+type PeriodUsage struct {
+	// Pods is keyed by "namespace/pod".
+	Pods map[string]*PodPeriodUsage
+	// Claims is the requested storage in bytes, keyed by "namespace/claim".
+	Claims map[string]float64
+	// ClaimPods is the name of a pod that mounted a claim, keyed by
+	// "namespace/claim".
+	ClaimPods map[string]string
+	// LoadBalancers is the number of load balancers, keyed by
+	// "namespace/service".
+	LoadBalancers map[string]float64
+}
+
+// PodPeriodUsage is the average requests and usage of a pod over a period.
+type PodPeriodUsage struct {
+	Namespace string
+	// Workload is "Kind/name" of the controller of the pod, with ReplicaSets
+	// resolved to their Deployment, or "Pod/name".
+	Workload string
+	// Labels only holds the label a report is grouped by.
+	Labels   map[string]string
+	Requests PodUsage
+	Usage    PodUsage
+}
+
+// GetPodUsageAverages returns the average CPU and memory usage of the pods
+// over window, keyed by "namespace/pod".
+func (c *Client) GetPodUsageAverages(ctx context.Context, window time.Duration) (map[string]PodUsage, error) {
+	w := model.Duration(window).String()
+	queries := []struct {
+		query string
+		set   func(*PodUsage, float64)
+	}{
+		{
+			fmt.Sprintf(`avg_over_time(%s[%s:5m])`, cpuUsageQuery, w),
+			func(u *PodUsage, v float64) { u.CPU = v },
+		},
+		{
+			fmt.Sprintf(`avg_over_time(%s[%s:5m])`, memoryUsageQuery, w),
+			func(u *PodUsage, v float64) { u.Memory = v },
+		},
+	}
+
+	usage := map[string]PodUsage{}
+	for _, q := range queries {
+		vector, err := c.queryVector(ctx, q.query, time.Now(), DefaultQueryTimeout)
+		if err != nil {
+			return nil, err
+		}
+		for _, sample := range vector {
+			key := string(sample.Metric["namespace"]) + "/" + string(sample.Metric["pod"])
+			u := usage[key]
+			q.set(&u, float64(sample.Value))
+			usage[key] = u
+		}
+	}
+	return usage, nil
+}
+
+const (
+	cpuUsageQuery    = `sum by (namespace, pod) (rate(container_cpu_usage_seconds_total{container!="",container!="POD"}[5m]))`
+	memoryUsageQuery = `sum by (namespace, pod) (container_memory_working_set_bytes{container!="",container!="POD"})`
+)
+
+// GetPeriodUsage returns the average requests and usage of the pods and the
+// average claims and load balancers between start and end, from
+// kube-state-metrics and cAdvisor. label, when set, is the pod label to
+// return, which kube-state-metrics only exposes for allowlisted labels.
+func (c *Client) GetPeriodUsage(ctx context.Context, start, end time.Time, label string) (*PeriodUsage, error) {
+	period := end.Sub(start).Truncate(usageStep)
+	if period < usageStep {
+		return nil, fmt.Errorf("period must be at least %s", usageStep)
+	}
+	p := model.Duration(period).String()
+	// Summing the samples and dividing by the number of steps counts the
+	// steps an object did not exist as zero, unlike avg_over_time.
+	average := func(expr string) string {
+		return fmt.Sprintf(`sum_over_time(%s[%s:5m]) / %d`, expr, p, int(period/usageStep))
+	}
+
+	result := &PeriodUsage{
+		Pods:          map[string]*PodPeriodUsage{},
+		Claims:        map[string]float64{},
+		ClaimPods:     map[string]string{},
+		LoadBalancers: map[string]float64{},
+	}
+	pod := func(m model.Metric) *PodPeriodUsage {
+		ns, name := string(m["namespace"]), string(m["pod"])
+		u, ok := result.Pods[ns+"/"+name]
+		if !ok {
+			u = &PodPeriodUsage{Namespace: ns, Workload: "Pod/" + name, Labels: map[string]string{}}
+			result.Pods[ns+"/"+name] = u
+		}
+		return u
+	}
+	queries := []struct {
+		query string
+		add   func(model.Metric, float64)
+	}{
+		{
+			average(`sum by (namespace, pod) (kube_pod_container_resource_requests{resource="cpu"})`),
+			func(m model.Metric, v float64) { pod(m).Requests.CPU = v },
+		},
+		{
+			average(`sum by (namespace, pod) (kube_pod_container_resource_requests{resource="memory"})`),
+			func(m model.Metric, v float64) { pod(m).Requests.Memory = v },
+		},
+		{
+			average(cpuUsageQuery),
+			func(m model.Metric, v float64) { pod(m).Usage.CPU = v },
+		},
+		{
+			average(memoryUsageQuery),
+			func(m model.Metric, v float64) { pod(m).Usage.Memory = v },
+		},
+		{
+			average(`sum by (namespace, persistentvolumeclaim) (kube_persistentvolumeclaim_resource_requests_storage_bytes)`),
+			func(m model.Metric, v float64) {
+				result.Claims[string(m["namespace"])+"/"+string(m["persistentvolumeclaim"])] = v
+			},
+		},
+		{
+			average(`sum by (namespace, service) (kube_service_spec_type{type="LoadBalancer"})`),
+			func(m model.Metric, v float64) {
+				result.LoadBalancers[string(m["namespace"])+"/"+string(m["service"])] = v
+			},
+		},
+	}
+	for _, q := range queries {
+		vector, err := c.queryVector(ctx, q.query, end, MaxQueryTimeout)
+		if err != nil {
+			return nil, err
+		}
+		for _, sample := range vector {
+			q.add(sample.Metric, float64(sample.Value))
+		}
+	}
+
+	// Owners and labels only name pods that already have samples above.
+	deployments := map[string]string{}
+	vector, err := c.queryVector(ctx, fmt.Sprintf(`max by (namespace, replicaset, owner_name) (max_over_time(kube_replicaset_owner{owner_kind="Deployment"}[%s]))`, p), end, MaxQueryTimeout)
+	if err != nil {
+		return nil, err
+	}
+	for _, sample := range vector {
+		deployments[string(sample.Metric["namespace"])+"/"+string(sample.Metric["replicaset"])] = string(sample.Metric["owner_name"])
+	}
+	vector, err = c.queryVector(ctx, fmt.Sprintf(`max by (namespace, pod, owner_kind, owner_name) (max_over_time(kube_pod_owner{owner_is_controller="true"}[%s]))`, p), end, MaxQueryTimeout)
+	if err != nil {
+		return nil, err
+	}
+	for _, sample := range vector {
+		u, ok := result.Pods[string(sample.Metric["namespace"])+"/"+string(sample.Metric["pod"])]
+		if !ok {
+			continue
+		}
+		kind, name := string(sample.Metric["owner_kind"]), string(sample.Metric["owner_name"])
+		if deployment, ok := deployments[u.Namespace+"/"+name]; ok && kind == "ReplicaSet" {
+			kind, name = "Deployment", deployment
+		}
+		u.Workload = kind + "/" + name
+	}
+
+	vector, err = c.queryVector(ctx, fmt.Sprintf(`max by (namespace, pod, persistentvolumeclaim) (max_over_time(kube_pod_spec_volumes_persistentvolumeclaims_info[%s]))`, p), end, MaxQueryTimeout)
+	if err != nil {
+		return nil, err
+	}
+	for _, sample := range vector {
+		result.ClaimPods[string(sample.Metric["namespace"])+"/"+string(sample.Metric["persistentvolumeclaim"])] = string(sample.Metric["pod"])
+	}
+
+	if label != "" {
+		labelName := "label_" + invalidLabelChars.ReplaceAllString(label, "_")
+		vector, err = c.queryVector(ctx, fmt.Sprintf(`max by (namespace, pod, %s) (max_over_time(kube_pod_labels{%s!=""}[%s]))`, labelName, labelName, p), end, MaxQueryTimeout)
+		if err != nil {
+			return nil, err
+		}
+		for _, sample := range vector {
+			if u, ok := result.Pods[string(sample.Metric["namespace"])+"/"+string(sample.Metric["pod"])]; ok {
+				u.Labels[label] = string(sample.Metric[model.LabelName(labelName)])
+			}
+		}
+	}
+	return result, nil
+}
+
+// invalidLabelChars matches the characters kube-state-metrics replaces in
+// the names of label_* labels.
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// queryVector runs an instant query for internal aggregation without the
+// result limits of Query, which protect the raw query API. These queries
+// return a series per pod and must not fail on large clusters.
+func (c *Client) queryVector(ctx context.Context, query string, ts time.Time, timeout time.Duration) (model.Vector, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, _, err := c.client.Query(ctx, query, ts)
+	if err != nil {
+		return nil, fmt.Errorf("error querying Prometheus: %w", err)
+	}
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type: %s", result.Type())
+	}
+	return vector, nil
+}
@@ -0,0 +1,67 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPeriodUsage(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		query := r.Form.Get("query")
+		queries = append(queries, query)
+		var result string
+		switch {
+		case strings.Contains(query, `resource="cpu"`):
+			result = `{"metric":{"namespace":"shop","pod":"api-7d9f-abcde"},"value":[1700000000,"0.5"]}`
+		case strings.Contains(query, "container_memory_working_set_bytes"):
+			result = `{"metric":{"namespace":"shop","pod":"api-7d9f-abcde"},"value":[1700000000,"1024"]}`
+		case strings.Contains(query, "kube_persistentvolumeclaim_resource_requests_storage_bytes"):
+			result = `{"metric":{"namespace":"shop","persistentvolumeclaim":"data"},"value":[1700000000,"2048"]}`
+		case strings.Contains(query, "kube_service_spec_type"):
+			result = `{"metric":{"namespace":"shop","service":"api"},"value":[1700000000,"0.25"]}`
+		case strings.Contains(query, "kube_replicaset_owner"):
+			result = `{"metric":{"namespace":"shop","replicaset":"api-7d9f","owner_name":"api"},"value":[1700000000,"1"]}`
+		case strings.Contains(query, "kube_pod_owner"):
+			result = `{"metric":{"namespace":"shop","pod":"api-7d9f-abcde","owner_kind":"ReplicaSet","owner_name":"api-7d9f"},"value":[1700000000,"1"]},
+				{"metric":{"namespace":"shop","pod":"gone","owner_kind":"Job","owner_name":"gone"},"value":[1700000000,"1"]}`
+		case strings.Contains(query, "kube_pod_spec_volumes_persistentvolumeclaims_info"):
+			result = `{"metric":{"namespace":"shop","pod":"api-7d9f-abcde","persistentvolumeclaim":"data"},"value":[1700000000,"1"]}`
+		case strings.Contains(query, "kube_pod_labels"):
+			result = `{"metric":{"namespace":"shop","pod":"api-7d9f-abcde","label_app_kubernetes_io_team":"web"},"value":[1700000000,"1"]}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[%s]}}`, result)
+	}))
+	defer srv.Close()
+
+	client, err := NewClientWithRoundTripper(srv.URL, http.DefaultTransport)
+	require.NoError(t, err)
+
+	start := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	usage, err := client.GetPeriodUsage(context.Background(), start, start.AddDate(0, 1, 0), "app.kubernetes.io/team")
+	require.NoError(t, err)
+
+	require.Len(t, usage.Pods, 1)
+	pod := usage.Pods["shop/api-7d9f-abcde"]
+	require.NotNil(t, pod)
+	assert.Equal(t, "Deployment/api", pod.Workload)
+	assert.Equal(t, map[string]string{"app.kubernetes.io/team": "web"}, pod.Labels)
+	assert.InDelta(t, 0.5, pod.Requests.CPU, 1e-9)
+	assert.InDelta(t, 1024, pod.Usage.Memory, 1e-9)
+	assert.InDelta(t, 2048, usage.Claims["shop/data"], 1e-9)
+	assert.Equal(t, "api-7d9f-abcde", usage.ClaimPods["shop/data"])
+	assert.InDelta(t, 0.25, usage.LoadBalancers["shop/api"], 1e-9)
+
+	// 30 days of 5 minute steps
+	assert.Contains(t, queries[0], "[30d:5m]) / 8640")
+}
@@ -0,0 +1,29 @@
+package prometheus
+
+import (
+	"regexp"
+	"strings"
+)
+
+var variableRegex = regexp.MustCompile(`\$\{([a-zA-Z_][a-zA-Z0-9_]*)\}|\$([a-zA-Z_][a-zA-Z0-9_]*)`)
+
+// labelValueEscaper escapes a value for use inside a quoted PromQL string.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// ExpandVariables replaces $name and ${name} in query with the escaped value
+// of the variable. Variables are meant to be used inside label matcher
+// values, e.g. {namespace="$namespace"}. Unknown variables are left as is.
+func ExpandVariables(query string, vars map[string]string) string {
+	return variableRegex.ReplaceAllStringFunc(query, func(match string) string {
+		groups := variableRegex.FindStringSubmatch(match)
+		name := groups[1]
+		if name == "" {
+			name = groups[2]
+		}
+		value, ok := vars[name]
+		if !ok {
+			return match
+		}
+		return labelValueEscaper.Replace(value)
+	})
+}
@@ -0,0 +1,18 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandVariables(t *testing.T) {
+	vars := map[string]string{"namespace": "shop", "pod": `api"} or up{a="`, "__range": "1h"}
+
+	assert.Equal(t, `up{namespace="shop"}`, ExpandVariables(`up{namespace="$namespace"}`, vars))
+	assert.Equal(t, `up{namespace="shop-x"}`, ExpandVariables(`up{namespace="${namespace}-x"}`, vars))
+	assert.Equal(t, `rate(x[1h])`, ExpandVariables(`rate(x[$__range])`, vars))
+	assert.Equal(t, `up{pod="$unknown"}`, ExpandVariables(`up{pod="$unknown"}`, vars))
+	// values cannot break out of the quoted label value
+	assert.Equal(t, `up{pod="api\"} or up{a=\""}`, ExpandVariables(`up{pod="$pod"}`, vars))
+}
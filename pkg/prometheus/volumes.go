@@ -0,0 +1,191 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// DefaultForecastWindow is the usage history a volume forecast is fitted to.
+const DefaultForecastWindow = 7 * 24 * time.Hour
+
+// VolumeUsage is the usage of a persistent volume claim and a linear forecast
+// of when it fills up.
+type VolumeUsage struct {
+	Namespace     string  `json:"namespace"`
+	Name          string  `json:"name"`
+	UsedBytes     float64 `json:"usedBytes"`
+	CapacityBytes float64 `json:"capacityBytes"`
+	UsedPercent   float64 `json:"usedPercent"`
+	// GrowthBytesPerDay is the slope of the usage over the forecast window
+	GrowthBytesPerDay float64 `json:"growthBytesPerDay"`
+	// FullAt is nil when the usage does not grow
+	FullAt        *time.Time `json:"fullAt,omitempty"`
+	DaysUntilFull *float64   `json:"daysUntilFull,omitempty"`
+}
+
+// VolumeMetrics is the usage history of a persistent volume claim in bytes.
+type VolumeMetrics struct {
+	Used     []UsageDataPoint `json:"used"`
+	Capacity []UsageDataPoint `json:"capacity"`
+	Forecast *VolumeUsage     `json:"forecast,omitempty"`
+}
+
+func volumeSelector(namespace, name string) string {
+	selector := `{persistentvolumeclaim!=""`
+	if namespace != "" {
+		selector += fmt.Sprintf(",namespace=%q", namespace)
+	}
+	if name != "" {
+		selector += fmt.Sprintf(",persistentvolumeclaim=%q", name)
+	}
+	return selector + "}"
+}
+
+// GetVolumeUsages returns the usage and forecast of the persistent volume
+// claims of namespace, or of all namespaces when it is empty, fitted to the
+// usage over window.
+func (c *Client) GetVolumeUsages(ctx context.Context, namespace string, window time.Duration) ([]VolumeUsage, error) {
+	return c.volumeUsages(ctx, namespace, "", window)
+}
+
+// GetVolumeMetrics returns the usage history of a persistent volume claim
+// over duration ("30m", "1h" or "24h") and a forecast fitted to the usage
+// over DefaultForecastWindow.
+func (c *Client) GetVolumeMetrics(ctx context.Context, namespace, name, duration string) (*VolumeMetrics, error) {
+	timeRange, step, err := metricsRange(duration)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	selector := volumeSelector(namespace, name)
+	used, err := c.queryRange(ctx, "max(kubelet_volume_stats_used_bytes"+selector+")", now.Add(-timeRange), now, step)
+	if err != nil {
+		return nil, fmt.Errorf("error querying volume usage: %w", err)
+	}
+	capacity, err := c.queryRange(ctx, "max(kubelet_volume_stats_capacity_bytes"+selector+")", now.Add(-timeRange), now, step)
+	if err != nil {
+		return nil, fmt.Errorf("error querying volume capacity: %w", err)
+	}
+	if len(used) == 0 {
+		return nil, fmt.Errorf("no volume stats found for %s/%s, kubelet volume metrics may not be scraped", namespace, name)
+	}
+
+	metrics := &VolumeMetrics{
+		Used:     FillMissingDataPoints(timeRange, step, used),
+		Capacity: FillMissingDataPoints(timeRange, step, capacity),
+	}
+	usages, err := c.volumeUsages(ctx, namespace, name, DefaultForecastWindow)
+	if err != nil {
+		return nil, err
+	}
+	if len(usages) > 0 {
+		metrics.Forecast = &usages[0]
+	}
+	return metrics, nil
+}
+
+func (c *Client) volumeUsages(ctx context.Context, namespace, name string, window time.Duration) ([]VolumeUsage, error) {
+	selector := volumeSelector(namespace, name)
+	now := time.Now()
+	step := max(window/200, time.Minute).Truncate(time.Minute)
+	r := v1.Range{Start: now.Add(-window), End: now, Step: step}
+
+	used, _, err := c.client.QueryRange(ctx, "max by (namespace, persistentvolumeclaim) (kubelet_volume_stats_used_bytes"+selector+")", r)
+	if err != nil {
+		return nil, fmt.Errorf("error querying volume usage: %w", err)
+	}
+	capacity, _, err := c.client.Query(ctx, "max by (namespace, persistentvolumeclaim) (kubelet_volume_stats_capacity_bytes"+selector+")", now)
+	if err != nil {
+		return nil, fmt.Errorf("error querying volume capacity: %w", err)
+	}
+	matrix, ok := used.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type: %s", used.Type())
+	}
+	vector, ok := capacity.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type: %s", capacity.Type())
+	}
+
+	capacities := make(map[string]float64, len(vector))
+	for _, sample := range vector {
+		capacities[string(sample.Metric["namespace"])+"/"+string(sample.Metric["persistentvolumeclaim"])] = float64(sample.Value)
+	}
+
+	usages := make([]VolumeUsage, 0, len(matrix))
+	for _, series := range matrix {
+		if len(series.Values) == 0 {
+			continue
+		}
+		points := make([]UsageDataPoint, 0, len(series.Values))
+		for _, v := range series.Values {
+			points = append(points, UsageDataPoint{Timestamp: v.Timestamp.Time(), Value: float64(v.Value)})
+		}
+		ns, pvc := string(series.Metric["namespace"]), string(series.Metric["persistentvolumeclaim"])
+		usages = append(usages, ForecastVolume(ns, pvc, points, capacities[ns+"/"+pvc], now))
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		di, dj := usages[i].DaysUntilFull, usages[j].DaysUntilFull
+		if (di == nil) != (dj == nil) {
+			return di != nil
+		}
+		if di != nil && *di != *dj {
+			return *di < *dj
+		}
+		return usages[i].UsedPercent > usages[j].UsedPercent
+	})
+	return usages, nil
+}
+
+// ForecastVolume fits a line to the usage points with least squares and
+// extrapolates when the usage reaches capacity.
+func ForecastVolume(namespace, name string, points []UsageDataPoint, capacity float64, now time.Time) VolumeUsage {
+	usage := VolumeUsage{Namespace: namespace, Name: name, CapacityBytes: capacity}
+	if len(points) == 0 {
+		return usage
+	}
+	usage.UsedBytes = points[len(points)-1].Value
+	if capacity > 0 {
+		usage.UsedPercent = usage.UsedBytes / capacity * 100
+	}
+
+	slope := linearSlope(points)
+	usage.GrowthBytesPerDay = slope * 24 * 60 * 60
+	if slope <= 0 || capacity <= 0 {
+		return usage
+	}
+	seconds := math.Max(capacity-usage.UsedBytes, 0) / slope
+	fullAt := now.Add(time.Duration(seconds * float64(time.Second)))
+	days := seconds / (24 * 60 * 60)
+	usage.FullAt = &fullAt
+	usage.DaysUntilFull = &days
+	return usage
+}
+
+// linearSlope returns the least squares slope of points in units per second.
+func linearSlope(points []UsageDataPoint) float64 {
+	if len(points) < 2 {
+		return 0
+	}
+	t0 := points[0].Timestamp
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		x := p.Timestamp.Sub(t0).Seconds()
+		sumX += x
+		sumY += p.Value
+		sumXY += x * p.Value
+		sumXX += x * x
+	}
+	n := float64(len(points))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
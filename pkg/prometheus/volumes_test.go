@@ -0,0 +1,73 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForecastVolume(t *testing.T) {
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	const gib = 1 << 30
+	// 1 GiB per day, 5 of 10 GiB used
+	var growing []UsageDataPoint
+	for i := 0; i <= 24; i++ {
+		growing = append(growing, UsageDataPoint{Timestamp: now.Add(time.Duration(i-24) * time.Hour), Value: 4*gib + float64(i)*gib/24})
+	}
+
+	usage := ForecastVolume("db", "data", growing, 10*gib, now)
+	assert.InDelta(t, 50, usage.UsedPercent, 1e-6)
+	assert.InDelta(t, gib, usage.GrowthBytesPerDay, 1)
+	require.NotNil(t, usage.DaysUntilFull)
+	assert.InDelta(t, 5, *usage.DaysUntilFull, 1e-6)
+	assert.WithinDuration(t, now.Add(5*24*time.Hour), *usage.FullAt, time.Second)
+
+	flat := []UsageDataPoint{{Timestamp: now.Add(-time.Hour), Value: gib}, {Timestamp: now, Value: gib}}
+	usage = ForecastVolume("db", "data", flat, 10*gib, now)
+	assert.Nil(t, usage.FullAt)
+	assert.Nil(t, usage.DaysUntilFull)
+
+	usage = ForecastVolume("db", "data", nil, 10*gib, now)
+	assert.Zero(t, usage.UsedBytes)
+}
+
+func TestGetVolumeUsages(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		query := r.Form.Get("query")
+		queries = append(queries, query)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(query, "capacity") {
+			_, _ = fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"namespace":"db","persistentvolumeclaim":"flat"},"value":[1700000000,"100"]},
+				{"metric":{"namespace":"db","persistentvolumeclaim":"growing"},"value":[1700000000,"100"]}]}}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"namespace":"db","persistentvolumeclaim":"flat"},"values":[[1700000000,"10"],[1700003600,"10"]]},
+			{"metric":{"namespace":"db","persistentvolumeclaim":"growing"},"values":[[1700000000,"10"],[1700003600,"20"]]}]}}`)
+	}))
+	defer srv.Close()
+
+	client, err := NewClientWithRoundTripper(srv.URL, http.DefaultTransport)
+	require.NoError(t, err)
+
+	usages, err := client.GetVolumeUsages(context.Background(), "db", DefaultForecastWindow)
+	require.NoError(t, err)
+	require.Len(t, usages, 2)
+	assert.Equal(t, "growing", usages[0].Name)
+	require.NotNil(t, usages[0].DaysUntilFull)
+	assert.Equal(t, "flat", usages[1].Name)
+	assert.Nil(t, usages[1].DaysUntilFull)
+	for _, q := range queries {
+		assert.Contains(t, q, `namespace="db"`)
+	}
+}
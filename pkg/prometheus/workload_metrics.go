@@ -0,0 +1,119 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Workload kinds supported by GetWorkloadMetrics
+const (
+	WorkloadDeployment  = "Deployment"
+	WorkloadStatefulSet = "StatefulSet"
+	WorkloadDaemonSet   = "DaemonSet"
+	WorkloadNamespace   = "Namespace"
+)
+
+// WorkloadMetrics contains metrics aggregated over the pods of a workload or
+// a namespace. CPU is in cores, memory in MB, network in bytes per second and
+// CPUThrottling in percent of CFS periods.
+type WorkloadMetrics struct {
+	CPU           []UsageDataPoint `json:"cpu"`
+	CPURequest    []UsageDataPoint `json:"cpuRequest"`
+	CPULimit      []UsageDataPoint `json:"cpuLimit"`
+	Memory        []UsageDataPoint `json:"memory"`
+	MemoryRequest []UsageDataPoint `json:"memoryRequest"`
+	MemoryLimit   []UsageDataPoint `json:"memoryLimit"`
+	NetworkIn     []UsageDataPoint `json:"networkIn"`
+	NetworkOut    []UsageDataPoint `json:"networkOut"`
+	Restarts      []UsageDataPoint `json:"restarts"`
+	CPUThrottling []UsageDataPoint `json:"cpuThrottling"`
+	Fallback      bool             `json:"fallback"`
+}
+
+// metricsRange returns the time range and step of a pod or workload metrics
+// duration.
+func metricsRange(duration string) (time.Duration, time.Duration, error) {
+	switch duration {
+	case "30m":
+		return 30 * time.Minute, 15 * time.Second, nil
+	case "1h":
+		return 1 * time.Hour, 1 * time.Minute, nil
+	case "24h":
+		return 24 * time.Hour, 5 * time.Minute, nil
+	default:
+		return 0, 0, fmt.Errorf("unsupported duration: %s", duration)
+	}
+}
+
+// workloadPods returns a PromQL expression with the value 1 for every pod of
+// the workload, built from the kube-state-metrics owner series. It is empty
+// for a namespace, which needs no join.
+func workloadPods(namespace, kind, name string) (string, error) {
+	switch kind {
+	case WorkloadNamespace:
+		return "", nil
+	case WorkloadStatefulSet, WorkloadDaemonSet:
+		return fmt.Sprintf(`max by (namespace, pod) (kube_pod_owner{namespace=%q,owner_kind=%q,owner_name=%q})`, namespace, kind, name), nil
+	case WorkloadDeployment:
+		// Pods are owned by the ReplicaSets of the Deployment
+		return fmt.Sprintf(`max by (namespace, pod) (kube_pod_owner{namespace=%q,owner_kind="ReplicaSet"} * on (namespace, owner_name) group_left () max by (namespace, owner_name) (label_replace(kube_replicaset_owner{namespace=%q,owner_kind="Deployment",owner_name=%q}, "owner_name", "$1", "replicaset", "(.*)")))`, namespace, namespace, name), nil
+	default:
+		return "", fmt.Errorf("unsupported workload kind: %s", kind)
+	}
+}
+
+// GetWorkloadMetrics fetches the metrics of a Deployment, StatefulSet,
+// DaemonSet or, with kind Namespace, of a whole namespace.
+func (c *Client) GetWorkloadMetrics(ctx context.Context, namespace, kind, name, duration string) (*WorkloadMetrics, error) {
+	timeRange, step, err := metricsRange(duration)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := workloadPods(namespace, kind, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// sum aggregates expr over the pods of the workload
+	sum := func(expr string) string {
+		if pods == "" {
+			return fmt.Sprintf("sum(%s)", expr)
+		}
+		return fmt.Sprintf("sum(%s * on (namespace, pod) group_left () %s)", expr, pods)
+	}
+	selector := func(conditions ...string) string {
+		return "{" + strings.Join(append([]string{fmt.Sprintf("namespace=%q", namespace)}, conditions...), ",") + "}"
+	}
+	containers := selector(`container!="POD"`, `container!=""`)
+
+	metrics := &WorkloadMetrics{}
+	queries := []struct {
+		name   string
+		target *[]UsageDataPoint
+		query  string
+	}{
+		{"CPU usage", &metrics.CPU, sum("rate(container_cpu_usage_seconds_total" + containers + "[1m])")},
+		{"CPU requests", &metrics.CPURequest, sum("kube_pod_container_resource_requests" + selector(`resource="cpu"`))},
+		{"CPU limits", &metrics.CPULimit, sum("kube_pod_container_resource_limits" + selector(`resource="cpu"`))},
+		{"memory usage", &metrics.Memory, sum("container_memory_working_set_bytes"+containers) + " / 1024 / 1024"},
+		{"memory requests", &metrics.MemoryRequest, sum("kube_pod_container_resource_requests"+selector(`resource="memory"`)) + " / 1024 / 1024"},
+		{"memory limits", &metrics.MemoryLimit, sum("kube_pod_container_resource_limits"+selector(`resource="memory"`)) + " / 1024 / 1024"},
+		{"network incoming", &metrics.NetworkIn, sum("rate(container_network_receive_bytes_total" + selector() + "[1m])")},
+		{"network outgoing", &metrics.NetworkOut, sum("rate(container_network_transmit_bytes_total" + selector() + "[1m])")},
+		{"restarts", &metrics.Restarts, sum("increase(kube_pod_container_status_restarts_total" + selector() + "[5m])")},
+		{"CPU throttling", &metrics.CPUThrottling, "100 * " + sum("increase(container_cpu_cfs_throttled_periods_total"+containers+"[5m])") + " / " + sum("increase(container_cpu_cfs_periods_total"+containers+"[5m])")},
+	}
+
+	now := time.Now()
+	start := now.Add(-timeRange)
+	for _, q := range queries {
+		data, err := c.queryRange(ctx, q.query, start, now, step)
+		if err != nil {
+			return nil, fmt.Errorf("error querying %s %s: %w", strings.ToLower(kind), q.name, err)
+		}
+		*q.target = FillMissingDataPoints(timeRange, step, data)
+	}
+	return metrics, nil
+}
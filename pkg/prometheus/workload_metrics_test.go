@@ -0,0 +1,51 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetWorkloadMetrics(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		queries = append(queries, r.Form.Get("query"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[%s,"2"]]}]}}`, r.Form.Get("end"))
+	}))
+	defer srv.Close()
+
+	client, err := NewClientWithRoundTripper(srv.URL, http.DefaultTransport)
+	require.NoError(t, err)
+
+	for _, kind := range []string{WorkloadDeployment, WorkloadStatefulSet, WorkloadDaemonSet, WorkloadNamespace} {
+		t.Run(kind, func(t *testing.T) {
+			queries = nil
+			metrics, err := client.GetWorkloadMetrics(context.Background(), "shop", kind, "api", "30m")
+			require.NoError(t, err)
+			assert.Len(t, queries, 10)
+			for _, q := range queries {
+				_, err := parser.ParseExpr(q)
+				assert.NoError(t, err, q)
+				assert.Contains(t, q, `namespace="shop"`)
+				if kind != WorkloadNamespace {
+					assert.Contains(t, q, `owner_name="api"`)
+				}
+			}
+			assert.Len(t, metrics.CPU, 120)
+			assert.Equal(t, 2.0, metrics.CPUThrottling[len(metrics.CPUThrottling)-1].Value)
+		})
+	}
+
+	_, err = client.GetWorkloadMetrics(context.Background(), "shop", "CronJob", "api", "1h")
+	assert.Error(t, err)
+	_, err = client.GetWorkloadMetrics(context.Background(), "shop", WorkloadDeployment, "api", "7d")
+	assert.Error(t, err)
+}
@@ -41,10 +41,15 @@ func CreateRole(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	role.ManagedBy = ""
 	if role.Name == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "role name is required"})
 		return
 	}
+	if err := ValidateClusterSelector(role.ClusterSelector); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err := model.DB.Create(&role).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create role: " + err.Error()})
 		return
@@ -70,15 +75,24 @@ func UpdateRole(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := ValidateClusterSelector(req.ClusterSelector); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	var role model.Role
 	if err := model.DB.First(&role, uint(dbID)).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
 		return
 	}
+	if role.ManagedBy == model.ManagedByConfig {
+		c.JSON(http.StatusForbidden, gin.H{"error": model.ErrConfigManaged.Error()})
+		return
+	}
 	// update fields
 	role.Name = req.Name
 	role.Description = req.Description
 	role.Clusters = req.Clusters
+	role.ClusterSelector = req.ClusterSelector
 	role.Namespaces = req.Namespaces
 	role.Resources = req.Resources
 	role.Verbs = req.Verbs
@@ -102,7 +116,16 @@ func DeleteRole(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role id"})
 		return
 	}
-	if err := model.DB.Delete(&model.Role{}, uint(dbID)).Error; err != nil {
+	var role model.Role
+	if err := model.DB.First(&role, uint(dbID)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+		return
+	}
+	if role.ManagedBy == model.ManagedByConfig {
+		c.JSON(http.StatusForbidden, gin.H{"error": model.ErrConfigManaged.Error()})
+		return
+	}
+	if err := model.DB.Delete(&role).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete role: " + err.Error()})
 		return
 	}
@@ -143,6 +166,10 @@ func AssignRole(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
 		return
 	}
+	if role.ManagedBy == model.ManagedByConfig {
+		c.JSON(http.StatusForbidden, gin.H{"error": model.ErrConfigManaged.Error()})
+		return
+	}
 
 	// check exists
 	var existing model.RoleAssignment
@@ -181,6 +208,15 @@ func UnassignRole(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "subjectType and subject query params are required"})
 		return
 	}
+	var managed int64
+	if err := model.DB.Model(&model.RoleAssignment{}).Where("role_id = ? AND subject_type = ? AND subject = ? AND managed_by = ?", uint(dbID), subjectType, subject, model.ManagedByConfig).Count(&managed).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove assignment: " + err.Error()})
+		return
+	}
+	if managed > 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": model.ErrConfigManaged.Error()})
+		return
+	}
 	if err := model.DB.Where("role_id = ? AND subject_type = ? AND subject = ?", uint(dbID), subjectType, subject).Delete(&model.RoleAssignment{}).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove assignment: " + err.Error()})
 		return
@@ -12,8 +12,10 @@ import (
 
 var (
 	RBACConfig *common.RolesConfig
-	once       sync.Once
-	rwlock     sync.RWMutex
+	// ClusterLabels maps cluster names to their labels for ClusterSelector matching
+	ClusterLabels map[string]map[string]string
+	once          sync.Once
+	rwlock        sync.RWMutex
 )
 
 func InitRBAC() {
@@ -39,12 +41,13 @@ func loadRolesFromDB() error {
 
 	for _, r := range roles {
 		cr := common.Role{
-			Name:        r.Name,
-			Description: r.Description,
-			Clusters:    r.Clusters,
-			Namespaces:  r.Namespaces,
-			Resources:   r.Resources,
-			Verbs:       r.Verbs,
+			Name:            r.Name,
+			Description:     r.Description,
+			Clusters:        r.Clusters,
+			ClusterSelector: r.ClusterSelector,
+			Namespaces:      r.Namespaces,
+			Resources:       r.Resources,
+			Verbs:           r.Verbs,
 		}
 		cfg.Roles = append(cfg.Roles, cr)
 
@@ -60,12 +63,31 @@ func loadRolesFromDB() error {
 			cfg.RoleMapping = append(cfg.RoleMapping, rm)
 		}
 	}
+	labels, err := loadClusterLabels()
+	if err != nil {
+		return err
+	}
+
 	rwlock.Lock()
 	RBACConfig = cfg
+	ClusterLabels = labels
 	rwlock.Unlock()
 	return nil
 }
 
+// loadClusterLabels returns the labels of every cluster keyed by cluster name
+func loadClusterLabels() (map[string]map[string]string, error) {
+	clusters, err := model.ListClusters()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]map[string]string, len(clusters))
+	for _, cluster := range clusters {
+		result[cluster.Name] = cluster.Labels
+	}
+	return result, nil
+}
+
 var (
 	SyncNow = make(chan struct{}, 1)
 )
@@ -87,3 +109,11 @@ func SyncRolesConfig() {
 		}
 	}
 }
+
+// TriggerSync requests an asynchronous reload of roles and cluster labels.
+func TriggerSync() {
+	select {
+	case SyncNow <- struct{}{}:
+	default:
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/pixelvide/kube-sentinel/pkg/common"
 	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog/v2"
 )
 
@@ -15,7 +16,7 @@ import (
 func CanAccess(user model.User, resource, verb, cluster, namespace string) bool {
 	roles := GetUserRoles(user)
 	for _, role := range roles {
-		if match(role.Clusters, cluster) &&
+		if matchCluster(role, cluster) &&
 			match(role.Namespaces, namespace) &&
 			match(role.Resources, resource) &&
 			match(role.Verbs, verb) {
@@ -32,7 +33,7 @@ func CanAccess(user model.User, resource, verb, cluster, namespace string) bool
 func CanAccessCluster(user model.User, name string) bool {
 	roles := GetUserRoles(user)
 	for _, role := range roles {
-		if match(role.Clusters, name) {
+		if matchCluster(role, name) {
 			return true
 		}
 	}
@@ -42,7 +43,19 @@ func CanAccessCluster(user model.User, name string) bool {
 func CanAccessNamespace(user model.User, cluster, name string) bool {
 	roles := GetUserRoles(user)
 	for _, role := range roles {
-		if match(role.Clusters, cluster) && match(role.Namespaces, name) {
+		if matchCluster(role, cluster) && match(role.Namespaces, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessAllNamespaces reports whether a role of user grants every
+// namespace of cluster, so that its lists need no namespace filtering.
+func CanAccessAllNamespaces(user model.User, cluster string) bool {
+	for _, role := range GetUserRoles(user) {
+		if matchCluster(role, cluster) && contains(role.Namespaces, "*") &&
+			!slices.ContainsFunc(role.Namespaces, func(ns string) bool { return strings.HasPrefix(ns, "!") }) {
 			return true
 		}
 	}
@@ -89,6 +102,35 @@ func findRole(name string) *common.Role {
 	return nil
 }
 
+// ValidateClusterSelector checks that a role's cluster selector is a valid
+// label selector. An empty selector is valid.
+func ValidateClusterSelector(selector string) error {
+	if _, err := labels.Parse(selector); err != nil {
+		return fmt.Errorf("invalid cluster selector: %w", err)
+	}
+	return nil
+}
+
+// matchCluster checks the cluster name against the role's cluster patterns
+// and its cluster label selector.
+func matchCluster(role common.Role, cluster string) bool {
+	if match(role.Clusters, cluster) {
+		return true
+	}
+	if role.ClusterSelector == "" {
+		return false
+	}
+	selector, err := labels.Parse(role.ClusterSelector)
+	if err != nil {
+		klog.Error(err)
+		return false
+	}
+	rwlock.RLock()
+	clusterLabels := ClusterLabels[cluster]
+	rwlock.RUnlock()
+	return selector.Matches(labels.Set(clusterLabels))
+}
+
 func match(list []string, val string) bool {
 	for _, v := range list {
 		if len(v) > 1 && strings.HasPrefix(v, "!") {
@@ -275,3 +275,81 @@ func TestCanAccess(t *testing.T) {
 		})
 	}
 }
+
+func TestCanAccessClusterSelector(t *testing.T) {
+	prodRole := common.Role{
+		Name:            "prod-eu",
+		Clusters:        []string{},
+		ClusterSelector: "env=prod,region=eu",
+		Resources:       []string{"*"},
+		Namespaces:      []string{"*"},
+		Verbs:           []string{"get"},
+	}
+	RBACConfig = &common.RolesConfig{
+		Roles:       []common.Role{prodRole},
+		RoleMapping: []common.RoleMapping{{Name: "prod-eu", Users: []string{"*"}}},
+	}
+	ClusterLabels = map[string]map[string]string{
+		"prod-eu-1": {"env": "prod", "region": "eu"},
+		"prod-us-1": {"env": "prod", "region": "us"},
+		"dev-eu-1":  {"env": "dev", "region": "eu"},
+	}
+	defer func() { ClusterLabels = nil }()
+
+	user := model.User{Username: "alice"}
+	tests := []struct {
+		cluster  string
+		expected bool
+	}{
+		{"prod-eu-1", true},
+		{"prod-us-1", false},
+		{"dev-eu-1", false},
+		{"unknown", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.cluster, func(t *testing.T) {
+			if got := CanAccessCluster(user, tc.cluster); got != tc.expected {
+				t.Errorf("CanAccessCluster(%s) = %v, want %v", tc.cluster, got, tc.expected)
+			}
+			if got := CanAccess(user, "pods", "get", tc.cluster, "default"); got != tc.expected {
+				t.Errorf("CanAccess(%s) = %v, want %v", tc.cluster, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestCanAccessAllNamespaces(t *testing.T) {
+	tests := []struct {
+		name       string
+		namespaces []string
+		expected   bool
+	}{
+		{"all", []string{"*"}, true},
+		{"some", []string{"dev", "test"}, false},
+		{"all but one", []string{"!kube-system", "*"}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			user := model.User{Username: "alice", Roles: []common.Role{{Name: "r", Clusters: []string{"prod"}, Namespaces: tc.namespaces}}}
+			if got := CanAccessAllNamespaces(user, "prod"); got != tc.expected {
+				t.Errorf("CanAccessAllNamespaces() = %v, want %v", got, tc.expected)
+			}
+			if CanAccessAllNamespaces(user, "dev") {
+				t.Errorf("CanAccessAllNamespaces(dev) = true for a role of prod")
+			}
+		})
+	}
+}
+
+func TestValidateClusterSelector(t *testing.T) {
+	for _, selector := range []string{"", "env=prod", "env in (prod,staging),!legacy"} {
+		if err := ValidateClusterSelector(selector); err != nil {
+			t.Errorf("ValidateClusterSelector(%q) = %v, want nil", selector, err)
+		}
+	}
+	for _, selector := range []string{"env in prod", "=prod", "env=prod,"} {
+		if err := ValidateClusterSelector(selector); err == nil {
+			t.Errorf("ValidateClusterSelector(%q) = nil, want an error", selector)
+		}
+	}
+}
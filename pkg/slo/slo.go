@@ -0,0 +1,233 @@
+// Package slo computes the service level indicators, error budgets and burn
+// rates of SLO definitions with Prometheus.
+package slo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pixelvide/kube-sentinel/pkg/cluster"
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/prometheus"
+	"github.com/pixelvide/kube-sentinel/pkg/rbac"
+	prommodel "github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"k8s.io/utils/ptr"
+)
+
+// BurnRateWindows are the windows burn rates are reported for, matching the
+// usual multi-window burn rate alerts.
+var BurnRateWindows = []string{"5m", "1h", "6h", "1d", "3d"}
+
+// BurnRate is the rate the error budget is spent at over Window. A rate of
+// 1 spends exactly the budget over the SLO window.
+type BurnRate struct {
+	Window string   `json:"window"`
+	Rate   *float64 `json:"rate"`
+}
+
+// Status is the current state of an SLO. SLI and the error budget are in
+// percent and nil when there is no data.
+type Status struct {
+	SLI                  *float64   `json:"sli"`
+	Objective            float64    `json:"objective"`
+	Window               string     `json:"window"`
+	ErrorBudget          float64    `json:"errorBudget"`
+	ErrorBudgetRemaining *float64   `json:"errorBudgetRemaining"`
+	BurnRates            []BurnRate `json:"burnRates"`
+	EvaluatedAt          time.Time  `json:"evaluatedAt"`
+}
+
+// availabilitySeries are the kube-state-metrics series of the available and
+// desired replicas of a workload, and the label holding its name.
+var availabilitySeries = map[string][3]string{
+	"Deployment":  {"kube_deployment_status_replicas_available", "kube_deployment_spec_replicas", "deployment"},
+	"StatefulSet": {"kube_statefulset_status_replicas_ready", "kube_statefulset_replicas", "statefulset"},
+	"DaemonSet":   {"kube_daemonset_status_number_available", "kube_daemonset_status_desired_number_scheduled", "daemonset"},
+}
+
+// SLIQuery returns the PromQL of the SLI of s over window as a ratio between
+// 0 and 1.
+func SLIQuery(s model.SLO, window string) (string, error) {
+	switch s.Type {
+	case model.SLOTypeRatio:
+		vars := map[string]string{"__range": window}
+		return fmt.Sprintf("(%s) / (%s)", prometheus.ExpandVariables(s.GoodQuery, vars), prometheus.ExpandVariables(s.TotalQuery, vars)), nil
+
+	case model.SLOTypeLatency:
+		name, matchers, err := histogramSelector(s.Metric)
+		if err != nil {
+			return "", err
+		}
+		le := strconv.FormatFloat(s.Threshold, 'f', -1, 64)
+		bucket := fmt.Sprintf("%s_bucket{%s}", name, strings.Join(append(matchers, fmt.Sprintf("le=%q", le)), ","))
+		count := fmt.Sprintf("%s_count{%s}", name, strings.Join(matchers, ","))
+		return fmt.Sprintf("sum(rate(%s[%s])) / sum(rate(%s[%s]))", bucket, window, count, window), nil
+
+	case model.SLOTypeAvailability:
+		threshold := s.Threshold
+		if threshold == 0 {
+			threshold = 1
+		}
+		var available string
+		if s.TargetKind == "Service" {
+			// kube_endpoint_address replaced kube_endpoint_address_available and
+			// kube_endpoint_address_not_ready in kube-state-metrics 2.x
+			selector := fmt.Sprintf("{namespace=%q,endpoint=%q}", s.Namespace, s.TargetName)
+			readySelector := fmt.Sprintf(`{namespace=%q,endpoint=%q,ready="true"}`, s.Namespace, s.TargetName)
+			ready := fmt.Sprintf("(sum(kube_endpoint_address_available%s) or count(kube_endpoint_address%s) or vector(0))", selector, readySelector)
+			total := fmt.Sprintf("((sum(kube_endpoint_address_available%s) + sum(kube_endpoint_address_not_ready%s)) or count(kube_endpoint_address%s))", selector, selector, selector)
+			available = fmt.Sprintf("(%s / %s)", ready, total)
+		} else {
+			series, ok := availabilitySeries[s.TargetKind]
+			if !ok {
+				return "", fmt.Errorf("unsupported target kind %q", s.TargetKind)
+			}
+			selector := fmt.Sprintf("{namespace=%q,%s=%q}", s.Namespace, series[2], s.TargetName)
+			available = fmt.Sprintf("(sum(%s%s) / sum(%s%s))", series[0], selector, series[1], selector)
+		}
+		return fmt.Sprintf("avg_over_time((%s >= bool %s)[%s:1m])", available, strconv.FormatFloat(threshold, 'f', -1, 64), window), nil
+	}
+	return "", fmt.Errorf("unsupported SLO type %q", s.Type)
+}
+
+// histogramSelector parses the metric of a latency SLO into the histogram
+// name and its label matchers.
+func histogramSelector(metric string) (string, []string, error) {
+	matchers, err := parser.ParseMetricSelector(metric)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid metric: %w", err)
+	}
+	var name string
+	var rest []string
+	for _, m := range matchers {
+		if m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+			name = m.Value
+			continue
+		}
+		rest = append(rest, m.String())
+	}
+	if name == "" {
+		return "", nil, fmt.Errorf("metric must name a histogram")
+	}
+	for _, suffix := range []string{"_bucket", "_count", "_sum"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	return name, rest, nil
+}
+
+// Evaluate computes the status of s. rewrite, when not nil, is applied to
+// every query, e.g. to restrict it to the namespaces of the user.
+func Evaluate(ctx context.Context, client *prometheus.Client, s model.SLO, rewrite func(string) (string, error)) (*Status, error) {
+	now := time.Now()
+	allowed := 1 - s.Objective/100
+	status := &Status{
+		Objective:   s.Objective,
+		Window:      s.Window,
+		ErrorBudget: allowed * 100,
+		EvaluatedAt: now,
+	}
+
+	sli := func(window string) (*float64, error) {
+		query, err := SLIQuery(s, window)
+		if err != nil {
+			return nil, err
+		}
+		if rewrite != nil {
+			if query, err = rewrite(query); err != nil {
+				return nil, err
+			}
+		}
+		result, err := client.Query(ctx, query, now, prometheus.DefaultQueryTimeout)
+		if err != nil {
+			return nil, err
+		}
+		vector, ok := result.Result.(prommodel.Vector)
+		if !ok || len(vector) == 0 {
+			return nil, nil
+		}
+		v := float64(vector[0].Value)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, nil
+		}
+		return &v, nil
+	}
+
+	ratio, err := sli(s.Window)
+	if err != nil {
+		return nil, err
+	}
+	if ratio != nil {
+		status.SLI = ptr.To(*ratio * 100)
+		status.ErrorBudgetRemaining = ptr.To((1 - (1-*ratio)/allowed) * 100)
+	}
+	for _, window := range BurnRateWindows {
+		ratio, err := sli(window)
+		if err != nil {
+			return nil, err
+		}
+		burnRate := BurnRate{Window: window}
+		if ratio != nil {
+			burnRate.Rate = ptr.To((1 - *ratio) / allowed)
+		}
+		status.BurnRates = append(status.BurnRates, burnRate)
+	}
+	return status, nil
+}
+
+// ValidateQueries checks that the SLI query of s parses.
+func ValidateQueries(s model.SLO) error {
+	query, err := SLIQuery(s, "5m")
+	if err != nil {
+		return err
+	}
+	if _, err := parser.ParseExpr(query); err != nil {
+		return fmt.Errorf("invalid query: %w", err)
+	}
+	return nil
+}
+
+// Result is an SLO with its status, or the error computing it.
+type Result struct {
+	model.SLO
+	Status      *Status `json:"status,omitempty"`
+	StatusError string  `json:"statusError,omitempty"`
+}
+
+// EvaluateForUser computes the status of each SLO. For users without the
+// admin role the queries are restricted to the namespaces they can access,
+// like the query API does.
+func EvaluateForUser(ctx context.Context, cs *cluster.ClientSet, user model.User, slos []model.SLO) []Result {
+	var rewrite func(string) (string, error)
+	var err error
+	if cs.PromClient == nil {
+		err = errors.New("Prometheus client not available")
+	} else if !rbac.UserHasRole(user, model.DefaultAdminRole.Name) {
+		var namespaces []string
+		if namespaces, err = cs.AccessibleNamespaces(ctx, user); err == nil {
+			rewrite = func(query string) (string, error) {
+				return prometheus.EnforceNamespaces(query, namespaces)
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(slos))
+	for _, s := range slos {
+		result := Result{SLO: s}
+		if err != nil {
+			result.StatusError = err.Error()
+		} else if status, evalErr := Evaluate(ctx, cs.PromClient, s, rewrite); evalErr != nil {
+			result.StatusError = evalErr.Error()
+		} else {
+			result.Status = status
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,100 @@
+package slo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pixelvide/kube-sentinel/pkg/model"
+	"github.com/pixelvide/kube-sentinel/pkg/prometheus"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLIQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		slo      model.SLO
+		contains []string
+	}{
+		{
+			name: "ratio",
+			slo: model.SLO{Type: model.SLOTypeRatio,
+				GoodQuery:  `sum(rate(http_requests_total{code!~"5.."}[$__range]))`,
+				TotalQuery: `sum(rate(http_requests_total[$__range]))`},
+			contains: []string{`[1h]`},
+		},
+		{
+			name:     "latency",
+			slo:      model.SLO{Type: model.SLOTypeLatency, Metric: `http_request_duration_seconds_bucket{service="api"}`, Threshold: 0.25},
+			contains: []string{`http_request_duration_seconds_bucket{service="api",le="0.25"}`, `http_request_duration_seconds_count{service="api"}`},
+		},
+		{
+			name:     "deployment availability",
+			slo:      model.SLO{Type: model.SLOTypeAvailability, Namespace: "shop", TargetKind: "Deployment", TargetName: "api"},
+			contains: []string{`kube_deployment_status_replicas_available{namespace="shop",deployment="api"}`, `>= bool 1`, `[1h:1m]`},
+		},
+		{
+			name:     "service availability",
+			slo:      model.SLO{Type: model.SLOTypeAvailability, Namespace: "shop", TargetKind: "Service", TargetName: "api", Threshold: 0.5},
+			contains: []string{`kube_endpoint_address_not_ready{namespace="shop",endpoint="api"}`, `ready="true"`, `>= bool 0.5`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := SLIQuery(tt.slo, "1h")
+			require.NoError(t, err)
+			_, err = parser.ParseExpr(query)
+			require.NoError(t, err, query)
+			for _, s := range tt.contains {
+				assert.Contains(t, query, s)
+			}
+		})
+	}
+
+	_, err := SLIQuery(model.SLO{Type: model.SLOTypeLatency, Metric: `{service="api"}`, Threshold: 1}, "1h")
+	assert.Error(t, err)
+}
+
+func TestEvaluate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		value := "0.9995"
+		if strings.Contains(r.Form.Get("query"), "[5m]") {
+			value = "0.99"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000,"%s"]}]}}`, value)
+	}))
+	defer srv.Close()
+	client, err := prometheus.NewClientWithRoundTripper(srv.URL, http.DefaultTransport)
+	require.NoError(t, err)
+
+	s := model.SLO{
+		Type:       model.SLOTypeRatio,
+		GoodQuery:  `sum(rate(good[$__range]))`,
+		TotalQuery: `sum(rate(total[$__range]))`,
+		Objective:  99.9,
+		Window:     "30d",
+	}
+	status, err := Evaluate(context.Background(), client, s, nil)
+	require.NoError(t, err)
+	require.NotNil(t, status.SLI)
+	assert.InDelta(t, 99.95, *status.SLI, 1e-9)
+	assert.InDelta(t, 0.1, status.ErrorBudget, 1e-9)
+	require.NotNil(t, status.ErrorBudgetRemaining)
+	assert.InDelta(t, 50, *status.ErrorBudgetRemaining, 1e-6)
+	require.Len(t, status.BurnRates, len(BurnRateWindows))
+	assert.Equal(t, "5m", status.BurnRates[0].Window)
+	assert.InDelta(t, 10, *status.BurnRates[0].Rate, 1e-6)
+	assert.InDelta(t, 0.5, *status.BurnRates[1].Rate, 1e-6)
+
+	_, err = Evaluate(context.Background(), client, s, func(string) (string, error) {
+		return "", prometheus.ErrNamespaceForbidden
+	})
+	assert.ErrorIs(t, err, prometheus.ErrNamespaceForbidden)
+}
@@ -23,7 +23,12 @@ func CheckPasswordHash(password, hash string) bool {
 }
 
 func EncryptString(input string) string {
-	keyHash := sha256.Sum256([]byte(common.KubeSentinelEncryptKey))
+	return EncryptStringWithKey(input, common.KubeSentinelEncryptKey)
+}
+
+// EncryptStringWithKey encrypts input with key instead of KUBE_SENTINEL_ENCRYPT_KEY.
+func EncryptStringWithKey(input, key string) string {
+	keyHash := sha256.Sum256([]byte(key))
 	block, err := aes.NewCipher(keyHash[:])
 	if err != nil {
 		return fmt.Sprintf("encryption_error: %v", err)
@@ -42,7 +47,12 @@ func EncryptString(input string) string {
 }
 
 func DecryptString(encrypted string) (string, error) {
-	keyHash := sha256.Sum256([]byte(common.KubeSentinelEncryptKey))
+	return DecryptStringWithKey(encrypted, common.KubeSentinelEncryptKey)
+}
+
+// DecryptStringWithKey decrypts a string encrypted with EncryptStringWithKey.
+func DecryptStringWithKey(encrypted, key string) (string, error) {
+	keyHash := sha256.Sum256([]byte(key))
 	ciphertext, err := base64.StdEncoding.DecodeString(encrypted)
 	if err != nil {
 		return "", fmt.Errorf("failed to decode base64: %w", err)
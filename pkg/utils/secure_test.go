@@ -15,3 +15,13 @@ func TestEncryptDecryptString(t *testing.T) {
 		t.Errorf("DecryptString() = %q, want %q", decrypted, original)
 	}
 }
+
+func TestEncryptDecryptStringWithKey(t *testing.T) {
+	encrypted := EncryptStringWithKey("secret", "key-a")
+	if decrypted, err := DecryptStringWithKey(encrypted, "key-a"); err != nil || decrypted != "secret" {
+		t.Fatalf("DecryptStringWithKey() = %q, %v", decrypted, err)
+	}
+	if _, err := DecryptStringWithKey(encrypted, "key-b"); err == nil {
+		t.Error("DecryptStringWithKey() with a different key should fail")
+	}
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"net"
@@ -9,6 +11,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/rand"
 )
@@ -130,6 +133,98 @@ func WriteUserAWSCredentials(storageNamespace string, content string) error {
 	return nil
 }
 
+// GetUserGCPCredentialsPath returns the path to the user's Google application default credentials file.
+func GetUserGCPCredentialsPath(storageNamespace string) string {
+	return filepath.Join(DataDir, storageNamespace, ".config", "gcloud", "application_default_credentials.json")
+}
+
+// WriteUserGCPCredentials writes the service account key to the user's specific path.
+func WriteUserGCPCredentials(storageNamespace string, content string) error {
+	return writeUserFile(GetUserGCPCredentialsPath(storageNamespace), content)
+}
+
+// GetUserKubeloginCacheDir returns the token cache directory for the user's Azure kubelogin.
+// It ensures the directory exists and is only accessible by the server.
+func GetUserKubeloginCacheDir(storageNamespace string) (string, error) {
+	return ensureUserDir(filepath.Join(DataDir, storageNamespace, ".kube", "cache", "kubelogin"))
+}
+
+// ensureUserDir creates a directory for the credentials of a user that only
+// the server can read.
+func ensureUserDir(path string) (string, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return "", fmt.Errorf("failed to create directory %s: %w", path, err)
+	}
+	// Explicitly chmod to tighten directories created before
+	if err := os.Chmod(path, 0700); err != nil {
+		return "", fmt.Errorf("failed to chmod directory %s: %w", path, err)
+	}
+	return path, nil
+}
+
+func writeUserFile(path string, content string) error {
+	if _, err := ensureUserDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("failed to chmod %s: %w", path, err)
+	}
+	return nil
+}
+
+// KubeloginDeviceCodeLogin starts "kubelogin get-token --login devicecode" with the
+// given arguments and returns the sign-in instructions printed by kubelogin.
+// The process keeps running in the background until the user completes the
+// login or the timeout expires; the resulting token lands in the cache dir.
+// The returned channel is closed when the process exits.
+func KubeloginDeviceCodeLogin(args []string, env []string, timeout time.Duration) (string, <-chan struct{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	cmd := exec.CommandContext(ctx, "kubelogin", args...)
+	cmd.Env = append(os.Environ(), env...)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		cancel()
+		return "", nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return "", nil, fmt.Errorf("failed to start kubelogin: %w", err)
+	}
+
+	messages := make(chan string, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer cancel()
+		scanner := bufio.NewScanner(stderr)
+		sent := false
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !sent && strings.Contains(line, "devicelogin") {
+				messages <- line
+				sent = true
+			}
+		}
+		if !sent {
+			close(messages)
+		}
+		_ = cmd.Wait()
+	}()
+
+	select {
+	case msg, ok := <-messages:
+		if !ok {
+			return "", done, fmt.Errorf("kubelogin exited without a device code prompt")
+		}
+		return msg, done, nil
+	case <-time.After(30 * time.Second):
+		return "", done, fmt.Errorf("timed out waiting for kubelogin device code prompt")
+	}
+}
+
 func ContainsString(slice []string, val string) bool {
 	for _, s := range slice {
 		if strings.ToLower(s) == val {
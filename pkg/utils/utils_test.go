@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -80,3 +82,23 @@ func TestGetUserGlabConfigDir(t *testing.T) {
 	// Skip execution as it requires root permissions to write to /data
 	t.Skip("Skipping execution of GetUserGlabConfigDir as it requires root permissions to write to /data")
 }
+
+func TestWriteUserGCPCredentials(t *testing.T) {
+	dataDir := DataDir
+	DataDir = t.TempDir()
+	defer func() { DataDir = dataDir }()
+
+	if err := WriteUserGCPCredentials("user-1", "{}"); err != nil {
+		t.Fatalf("WriteUserGCPCredentials() error = %v", err)
+	}
+	path := GetUserGCPCredentialsPath("user-1")
+	for p, want := range map[string]os.FileMode{path: 0600, filepath.Dir(path): 0700} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("Stat(%s) error = %v", p, err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("mode of %s = %v, want %v", p, got, want)
+		}
+	}
+}